@@ -8,51 +8,94 @@ import (
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
 	dbCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	mailCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport"
 	transportCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker"
+	breakerCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/cache"
+	cacheCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/cache/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
+	fetchersCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server"
 	serverCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications/message"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/token"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/pkg/settings"
-	"github.com/robfig/cron/v3"
 )
 
-const defaultCronSpec = "0 0 12 * * *"
+const (
+	unsubscribePurpose  = "unsubscribe"
+	confirmationPurpose = "confirm"
+	alertsPurpose       = "alerts"
+)
 
-func StartCron(spec string, f func()) *cron.Cron {
-	c := cron.New()
-	_, err := c.AddFunc(spec, f)
+func InitMigrator() (*database.DB, *database.Migrator, error) {
+	db, err := database.New(dbCfg.NewFromEnv())
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := database.NewMigrator(db, models.Migrations())
 	if err != nil {
-		slog.Error("failed to add cron job", slog.Any("error", err))
-		return nil
+		return nil, nil, err
 	}
-	slog.Info("cron job added", slog.Any("spec", spec))
-	c.Start()
-	return c
+	return db, migrator, nil
 }
 
 func InitDatabase() (*database.DB, error) {
-	db, err := database.New(dbCfg.NewFromEnv())
+	db, migrator, err := InitMigrator()
 	if err != nil {
 		return nil, err
 	}
-	if err := db.Migrate(&models.User{}, &models.Rate{}); err != nil {
+	// Apply pending migrations on start, so that deployments keep working without
+	// a separate migration step
+	if _, err := migrator.Up(); err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
-func InitFetchers() fetchers.RateFetcher {
-	// Initialize rate fetcher chain of responsibilities
-	nbuFetcher := fetchers.NewNBURateFetcher()
-	currencyBeaconFetcher := fetchers.NewCurrencyBeaconFetcher(
-		os.Getenv("CURRENCY_BEACON_API_KEY"),
+// InitFetchers wraps the providers in cross rate fetchers and circuit breakers,
+// and combines them by the configured strategy.
+func InitFetchers() (fetchers.RateFetcher, breaker.Monitor) {
+	config := fetchersCfg.NewFromEnv()
+	breakerConfig := breakerCfg.NewFromEnv()
+	nbuFetcher := breaker.NewFetcher(
+		"nbu",
+		fetchers.NewCrossFetcher(
+			fetchers.NewNBURateFetcher(fetchers.WithRefreshInterval(config.NBURefreshInterval)),
+			fetchers.NBUPivot,
+		),
+		breakerConfig,
+	)
+	currencyBeaconFetcher := breaker.NewFetcher(
+		"currencybeacon",
+		fetchers.NewCrossFetcher(
+			fetchers.NewCurrencyBeaconFetcher(os.Getenv("CURRENCY_BEACON_API_KEY")),
+			config.Pivot,
+		),
+		breakerConfig,
 	)
+	monitor := breaker.Monitor{currencyBeaconFetcher, nbuFetcher}
+	if config.Strategy != fetchersCfg.StrategyChain {
+		// Query the providers concurrently
+		return fetchers.NewParallelFetcher(config, currencyBeaconFetcher, nbuFetcher), monitor
+	}
+	// Initialize rate fetcher chain of responsibilities
 	currencyBeaconFetcher.SetNext(nbuFetcher)
-	return currencyBeaconFetcher
+	return currencyBeaconFetcher, monitor
+}
+
+// StartRelay starts publishing email commands from the outbox to the broker,
+// which the producer connects to in the background. While the broker is unavailable,
+// commands are kept in the outbox.
+func StartRelay(ctx context.Context, outbox mail.OutboxStore) *transport.Producer {
+	producer := transport.NewProducer(transportCfg.NewFromEnv())
+	go mail.NewRelay(outbox, producer, mailCfg.NewFromEnv()).Run(ctx)
+	return producer
 }
 
 func main() {
@@ -61,48 +104,92 @@ func main() {
 		// panic(err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == migrateCommand {
+		if err := RunMigrate(os.Args[2:], os.Stdout); err != nil {
+			slog.Error("migration failed", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	config := serverCfg.NewFromEnv()
+	if err := config.Validate(); err != nil {
+		slog.Error("invalid server config", slog.Any("error", err))
+		panic(err)
+	}
+
 	db, err := InitDatabase()
 	if err != nil {
 		slog.Error("failed to initialize database", slog.Any("error", err))
 		panic(err)
 	}
 
-	// Initialize rate fetcher chain of responsibilities
-	rateFetcher := InitFetchers()
+	// Initialize rate fetcher chain of responsibilities behind a cache
+	providers, providerMonitor := InitFetchers()
+	rateFetcher := cache.NewFetcher(providers, cacheCfg.NewFromEnv())
+
+	outboxRepo := models.NewOutboxRepository(db)
+	mailerFacade := mail.NewMailerFacade(outboxRepo)
+	producer := StartRelay(context.Background(), outboxRepo)
+	defer producer.Close()
 
 	userRepo := models.NewUserRepository(db)
-	apiClient := server.Client{
-		Config:      serverCfg.NewFromEnv(),
-		RateService: service.NewRateService(models.NewRateRepository(db), rateFetcher),
-		UserRepo:    userRepo,
+	rateRepo := models.NewRateRepository(db)
+	alertRepo := models.NewAlertRepository(db)
+	unsubscribeSigner := token.NewSigner(config.SecretKey, unsubscribePurpose)
+	confirmationSigner := token.NewSigner(config.SecretKey, confirmationPurpose)
+	alertsSigner := token.NewSigner(config.SecretKey, alertsPurpose)
+	links := server.UserLinks{
+		UnsubscribeLinks: server.UnsubscribeLinks{
+			BaseURL: config.BaseURL, Signer: unsubscribeSigner,
+		},
+		AlertLinks: server.AlertLinks{BaseURL: config.BaseURL, Signer: alertsSigner},
 	}
 
-	// Start cron job for notifications
-	cronSpec := os.Getenv("CRON_SPEC")
-	if cronSpec == "" {
-		slog.Warn(
-			"CRON_SPEC is not set, using default value",
-			slog.Any("default", defaultCronSpec),
-		)
-		cronSpec = defaultCronSpec
+	rateService := service.NewRateService(rateRepo, rateFetcher)
+	rateService.AddObserver(
+		notifications.NewAlertEvaluator(mailerFacade, alertRepo, rateRepo, links),
+	)
+	go rateService.Run(context.Background())
+
+	apiClient := server.Client{
+		Config:             config,
+		RateService:        rateService,
+		HistoryService:     service.NewHistoryService(rateRepo),
+		UserRepo:           userRepo,
+		AlertRepo:          alertRepo,
+		OutboxRepo:         outboxRepo,
+		UnsubscribeTokens:  unsubscribeSigner,
+		ConfirmationTokens: confirmationSigner,
+		AlertTokens:        alertsSigner,
+		ProviderMonitor:    providerMonitor,
+		Confirmer: notifications.NewConfirmationNotifier(
+			mailerFacade,
+			server.ConfirmationLinks{
+				BaseURL: config.BaseURL,
+				Signer:  confirmationSigner,
+				TTL:     config.ConfirmationTTL,
+			},
+		),
 	}
 
-	mailerFacade, err := mail.NewMailerFacade(transportCfg.NewFromEnv())
+	rateMessage, err := message.NewTemplateRate(os.Getenv("EMAIL_TEMPLATES_DIR"))
 	if err != nil {
-		slog.Error("failed to initialize mailer facade", slog.Any("error", err))
+		slog.Error("failed to parse email templates", slog.Any("error", err))
+		panic(err)
 	}
-	defer mailerFacade.Close()
 	notifier := notifications.NewUsersNotifier(
 		mailerFacade,
-		apiClient.RateService,
 		userRepo,
-		&message.PlainRate{},
+		apiClient.RateService,
+		rateMessage,
+		links,
+	)
+	// Start notifying subscribers by their delivery schedules
+	scheduler := notifications.NewScheduler(
+		userRepo, notifier, config.SchedulerInterval, server.RateTimeout,
 	)
-	StartCron(cronSpec, func() {
-		ctx, cancel := context.WithTimeout(context.Background(), server.RateTimeout)
-		defer cancel()
-		notifier.Notify(ctx)
-	})
+	go scheduler.Run(context.Background())
 
 	// Start HTTP server
 	s := server.NewServer(apiClient.Config, server.NewEngine(apiClient))
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+)
+
+const (
+	migrateCommand = "migrate"
+	migrateUsage   = "usage: migrate up|down [steps]|status"
+)
+
+// RunMigrate runs the migrate subcommand with the arguments following it:
+// "up" applies pending migrations, "down [steps]" rolls back the latest
+// applied ones (one by default) and "status" lists the migrations.
+func RunMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	db, migrator, err := InitMigrator()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up()
+		fmt.Fprintf(out, "applied %d migration(s)\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		count, err := migrator.Down(steps)
+		fmt.Fprintf(out, "rolled back %d migration(s)\n", count)
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		printStatus(out, statuses)
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+func printStatus(out io.Writer, statuses []database.MigrationStatus) {
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = "applied at " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(out, "%4d  %-24s %s\n", status.Version, status.Name, appliedAt)
+	}
+}
@@ -74,6 +74,8 @@ func (d *DB) Init() error {
 	return nil
 }
 
+// Migrate auto-migrates tables of the models. It is meant for tests,
+// the service schema is versioned by the Migrator.
 func (d *DB) Migrate(models ...any) error {
 	conn := d.Connection()
 	if conn == nil {
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned schema change. Up and Down are run in a transaction
+// along with recording the version in the migrations table.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationRecord is a row of the table of applied migrations.
+type MigrationRecord struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (MigrationRecord) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus tells whether the migration was applied and when.
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back versioned migrations,
+// keeping track of the applied ones in the schema_migrations table.
+//
+// Example usage:
+//
+//	migrator, err := database.NewMigrator(db, migrations)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	applied, err := migrator.Up()
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+func (m *Migrator) connection() (*gorm.DB, error) {
+	conn := m.db.Connection()
+	if conn == nil {
+		return nil, errors.New("failed to connect to database")
+	}
+	if err := conn.AutoMigrate(&MigrationRecord{}); err != nil {
+		return nil, fmt.Errorf("creating migrations table: %w", err)
+	}
+	return conn, nil
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[uint]MigrationRecord, error) {
+	var records []MigrationRecord
+	if err := conn.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("reading migrations table: %w", err)
+	}
+	applied := make(map[uint]MigrationRecord, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version uint) (Migration, bool) {
+	i := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+		return migration.Version == version
+	})
+	if i < 0 {
+		return Migration{}, false
+	}
+	return m.migrations[i], true
+}
+
+// Up applies all pending migrations in the version order and returns their number.
+func (m *Migrator) Up() (int, error) {
+	conn, err := m.connection()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(conn)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&MigrationRecord{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("applying migration %d: %w", migration.Version, err)
+		}
+		slog.Info(
+			"migration applied",
+			slog.Any("version", migration.Version), slog.Any("name", migration.Name),
+		)
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back up to the given number of the latest applied migrations
+// and returns the number of rolled back ones.
+func (m *Migrator) Down(steps int) (int, error) {
+	conn, err := m.connection()
+	if err != nil {
+		return 0, err
+	}
+	var records []MigrationRecord
+	err = conn.Order("version DESC").Limit(steps).Find(&records).Error
+	if err != nil {
+		return 0, fmt.Errorf("reading migrations table: %w", err)
+	}
+	for i, record := range records {
+		migration, ok := m.find(record.Version)
+		if !ok {
+			return i, fmt.Errorf("unknown migration version %d", record.Version)
+		}
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&record).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("rolling back migration %d: %w", migration.Version, err)
+		}
+		slog.Info(
+			"migration rolled back",
+			slog.Any("version", migration.Version), slog.Any("name", migration.Name),
+		)
+	}
+	return len(records), nil
+}
+
+// Status returns statuses of all known migrations in the version order.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	conn, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// NewMigrator creates a migrator of the migrations, which must have unique versions.
+func NewMigrator(db *DB, migrations []Migration) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+	return &Migrator{db: db, migrations: sorted}, nil
+}
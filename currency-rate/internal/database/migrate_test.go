@@ -0,0 +1,117 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newIsolatedDB opens a named in-memory database not shared with other tests.
+func newIsolatedDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(config.Config{
+		DatabaseService: "sqlite",
+		DatabaseDSN:     "file:" + t.Name() + "?mode=memory&cache=shared",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func execMigration(version uint, up, down string) database.Migration {
+	return database.Migration{
+		Version: version,
+		Name:    "migration",
+		Up:      func(tx *gorm.DB) error { return tx.Exec(up).Error },
+		Down:    func(tx *gorm.DB) error { return tx.Exec(down).Error },
+	}
+}
+
+func testMigrations() []database.Migration {
+	// Listed out of order to check the migrator sorts them by version
+	return []database.Migration{
+		execMigration(2, "ALTER TABLE items ADD COLUMN name TEXT", "ALTER TABLE items DROP COLUMN name"),
+		execMigration(1, "CREATE TABLE items (id INTEGER PRIMARY KEY)", "DROP TABLE items"),
+	}
+}
+
+func TestMigratorUp(t *testing.T) {
+	// Arrange
+	db := newIsolatedDB(t)
+	migrator, err := database.NewMigrator(db, testMigrations())
+	require.NoError(t, err)
+	// Act
+	applied, err := migrator.Up()
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, db.Connection().Migrator().HasColumn("items", "name"))
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+}
+
+func TestMigratorDown(t *testing.T) {
+	// Arrange
+	db := newIsolatedDB(t)
+	migrator, err := database.NewMigrator(db, testMigrations())
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	// Act
+	rolledBack, err := migrator.Down(1)
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+	conn := db.Connection()
+	assert.True(t, conn.Migrator().HasTable("items"))
+	assert.False(t, conn.Migrator().HasColumn("items", "name"))
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, uint(1), statuses[0].Version)
+	assert.True(t, statuses[0].Applied)
+	assert.NotZero(t, statuses[0].AppliedAt)
+	assert.False(t, statuses[1].Applied)
+	rolledBack, err = migrator.Down(5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+	assert.False(t, conn.Migrator().HasTable("items"))
+}
+
+func TestMigratorUpFailure(t *testing.T) {
+	// Arrange
+	db := newIsolatedDB(t)
+	migrations := append(testMigrations(), database.Migration{
+		Version: 3,
+		Name:    "failing",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE items ADD COLUMN price INTEGER").Error; err != nil {
+				return err
+			}
+			return errors.New("failed")
+		},
+	})
+	migrator, err := database.NewMigrator(db, migrations)
+	require.NoError(t, err)
+	// Act
+	applied, err := migrator.Up()
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, 2, applied)
+	assert.False(t, db.Connection().Migrator().HasColumn("items", "price"))
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	assert.False(t, statuses[2].Applied)
+}
+
+func TestNewMigratorDuplicateVersion(t *testing.T) {
+	db := newIsolatedDB(t)
+	_, err := database.NewMigrator(db, append(testMigrations(), execMigration(1, "", "")))
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRelayInterval = 5 * time.Second
+	defaultBatchSize     = 100
+	defaultRetryDelay    = 10 * time.Second
+	defaultMaxRetryDelay = 10 * time.Minute
+	defaultSentRetention = 7 * 24 * time.Hour
+	defaultPurgeInterval = time.Hour
+)
+
+type Config struct {
+	// RelayInterval is how often the outbox is polled for pending messages.
+	RelayInterval time.Duration
+	// BatchSize limits the number of messages published per poll.
+	BatchSize int
+	// RetryDelay is the delay after the first failed publishing attempt,
+	// doubled after every next failure up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// SentRetention is how long published messages are kept before being purged.
+	SentRetention time.Duration
+	// PurgeInterval is how often published messages past the retention are purged.
+	PurgeInterval time.Duration
+}
+
+// RetryDelayFor returns the delay before the next publishing attempt
+// after the given number of failed attempts.
+func (c Config) RetryDelayFor(attempts int) time.Duration {
+	delay := c.RetryDelay
+	for i := 1; i < attempts && delay < c.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxRetryDelay)
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func intOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func NewFromEnv() Config {
+	return Config{
+		RelayInterval: durationOrDefault("OUTBOX_RELAY_INTERVAL", defaultRelayInterval),
+		BatchSize:     intOrDefault("OUTBOX_BATCH_SIZE", defaultBatchSize),
+		RetryDelay:    durationOrDefault("OUTBOX_RETRY_DELAY", defaultRetryDelay),
+		MaxRetryDelay: durationOrDefault("OUTBOX_MAX_RETRY_DELAY", defaultMaxRetryDelay),
+		SentRetention: durationOrDefault("OUTBOX_SENT_RETENTION", defaultSentRetention),
+		PurgeInterval: durationOrDefault("OUTBOX_PURGE_INTERVAL", defaultPurgeInterval),
+	}
+}
@@ -5,15 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"strconv"
 	"time"
 
-	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport"
-	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport/config"
+	"github.com/google/uuid"
 )
 
-var lastCommandID int
-
 const eventType = "SendEmail"
 
 type Command struct {
@@ -27,16 +23,43 @@ type mailData struct {
 	Emails  []string `json:"emails"`
 	Subject string   `json:"subject"`
 	Body    string   `json:"body"`
+	// TextBody is a plain-text alternative of the HTML body.
+	TextBody string            `json:"textBody,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	// PerRecipient requests a separate message rendered for every recipient.
+	PerRecipient bool `json:"perRecipient,omitempty"`
+}
+
+// Email is a message to be sent by the email service.
+// Body is HTML, TextBody is its optional plain-text alternative.
+// Headers are optional and are set on the message as is, e.g. List-Unsubscribe.
+// If PerRecipient is set, every recipient gets a separate message with
+// {{.Recipient}} in the subject and the body replaced by their address.
+// This service renders notifications per user itself and never sets it,
+// the field mirrors the command of the email service for other producers.
+type Email struct {
+	Recipients   []string
+	Subject      string
+	Body         string
+	TextBody     string
+	Headers      map[string]string
+	PerRecipient bool
+}
+
+// Outbox stores commands until they are published to the broker.
+type Outbox interface {
+	Add(payload []byte) error
 }
 
 type MailerFacade struct {
-	producer *transport.Producer
+	outbox Outbox
 }
 
+// createCommand creates a command with a random UUID, which stays the same
+// on republishing, so that the email service can drop redelivered commands.
 func (m *MailerFacade) createCommand(data any) Command {
-	lastCommandID++
 	return Command{
-		ID:        strconv.Itoa(lastCommandID),
+		ID:        uuid.NewString(),
 		Type:      eventType,
 		Timestamp: time.Now().Format(time.RFC3339),
 		Data:      data,
@@ -51,34 +74,34 @@ func (m *MailerFacade) marshal(data Command) ([]byte, error) {
 	return bytes, nil
 }
 
-func (m *MailerFacade) SendEmail(
-	ctx context.Context,
-	emails []string, subject string, message string,
-) error {
-	data := m.createCommand(mailData{
-		Emails:  emails,
-		Subject: subject,
-		Body:    message,
-	})
-	slog.Info("sending email", slog.Any("userCount", len(emails)))
-	msgBytes, err := m.marshal(data)
+// Encode creates the command of the email as stored in the outbox,
+// for callers storing it along with their own changes.
+func (m *MailerFacade) Encode(email Email) ([]byte, error) {
+	return m.marshal(m.createCommand(mailData{
+		Emails:       email.Recipients,
+		Subject:      email.Subject,
+		Body:         email.Body,
+		TextBody:     email.TextBody,
+		Headers:      email.Headers,
+		PerRecipient: email.PerRecipient,
+	}))
+}
+
+// SendEmail stores the email command in the outbox, from which the relay
+// publishes it to the email service, so that no command is lost while the broker
+// is unavailable.
+func (m *MailerFacade) SendEmail(ctx context.Context, email Email) error {
+	slog.Info("sending email", slog.Any("userCount", len(email.Recipients)))
+	msgBytes, err := m.Encode(email)
 	if err != nil {
 		return err
 	}
-	if err := m.producer.Produce(ctx, msgBytes); err != nil {
-		return fmt.Errorf("producing email message: %w", err)
+	if err := m.outbox.Add(msgBytes); err != nil {
+		return fmt.Errorf("storing email message: %w", err)
 	}
 	return nil
 }
 
-func (m *MailerFacade) Close() error {
-	return m.producer.Close()
-}
-
-func NewMailerFacade(config config.Config) (*MailerFacade, error) {
-	producer, err := transport.NewProducer(config)
-	if err != nil {
-		return nil, fmt.Errorf("creating producer: %w", err)
-	}
-	return &MailerFacade{producer}, nil
+func NewMailerFacade(outbox Outbox) *MailerFacade {
+	return &MailerFacade{outbox: outbox}
 }
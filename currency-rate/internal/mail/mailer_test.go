@@ -0,0 +1,54 @@
+package mail_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutbox struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+func (o *fakeOutbox) Add(payload []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.payloads = append(o.payloads, payload)
+	return nil
+}
+
+func TestSendEmailUniqueCommandIDs(t *testing.T) {
+	// Arrange
+	const commandCount = 50
+	outbox := &fakeOutbox{}
+	facade := mail.NewMailerFacade(outbox)
+	email := mail.Email{Recipients: []string{"example@gmail.com"}, Subject: "Subject"}
+	// Act
+	var wg sync.WaitGroup
+	for range commandCount {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, facade.SendEmail(context.Background(), email))
+		}()
+	}
+	wg.Wait()
+	// Assert
+	require.Len(t, outbox.payloads, commandCount)
+	ids := make(map[string]struct{}, commandCount)
+	for _, payload := range outbox.payloads {
+		var command mail.Command
+		require.NoError(t, json.Unmarshal(payload, &command))
+		_, err := uuid.Parse(command.ID)
+		require.NoError(t, err)
+		ids[command.ID] = struct{}{}
+	}
+	assert.Len(t, ids, commandCount)
+}
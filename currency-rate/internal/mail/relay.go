@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+)
+
+// OutboxStore keeps messages until the relay publishes them.
+type OutboxStore interface {
+	FindPending(now time.Time, limit int) ([]models.OutboxMessage, error)
+	MarkSent(message *models.OutboxMessage) error
+	MarkFailed(message *models.OutboxMessage, reason string, nextAttempt time.Time) error
+	CountPending() (int64, error)
+	DeleteSentBefore(before time.Time) (int64, error)
+}
+
+type Producer interface {
+	Produce(ctx context.Context, msg []byte) error
+}
+
+// Relay publishes pending outbox messages to the broker, retrying failed
+// messages with exponential backoff, and purges published messages past the retention.
+type Relay struct {
+	store    OutboxStore
+	producer Producer
+	config   config.Config
+}
+
+// Run publishes pending messages every relay interval and purges published ones
+// every purge interval until the context is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.RelayInterval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(r.config.PurgeInterval)
+	defer purgeTicker.Stop()
+	r.Publish(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Publish(ctx)
+		case <-purgeTicker.C:
+			r.Purge()
+		}
+	}
+}
+
+// Publish publishes a batch of pending messages due by now and returns
+// the number of published messages.
+func (r *Relay) Publish(ctx context.Context) int {
+	messages, err := r.store.FindPending(time.Now(), r.config.BatchSize)
+	if err != nil {
+		slog.Error("finding pending outbox messages", slog.Any("error", err))
+		return 0
+	}
+	published := 0
+	for i := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.publishMessage(ctx, &messages[i]) {
+			published++
+		}
+	}
+	if len(messages) > 0 {
+		slog.Info(
+			"relayed outbox messages",
+			slog.Any("published", published),
+			slog.Any("pending", len(messages)),
+		)
+	}
+	return published
+}
+
+func (r *Relay) publishMessage(ctx context.Context, message *models.OutboxMessage) bool {
+	if err := r.producer.Produce(ctx, []byte(message.Payload)); err != nil {
+		nextAttempt := time.Now().Add(r.config.RetryDelayFor(message.Attempts + 1))
+		slog.Error(
+			"publishing outbox message",
+			slog.Any("message", message),
+			slog.Any("nextAttempt", nextAttempt),
+			slog.Any("error", err),
+		)
+		if err := r.store.MarkFailed(message, err.Error(), nextAttempt); err != nil {
+			slog.Error("marking outbox message failed", slog.Any("error", err))
+		}
+		return false
+	}
+	// A message published but not marked is published again, hence the email
+	// service may receive duplicates
+	if err := r.store.MarkSent(message); err != nil {
+		slog.Error("marking outbox message sent", slog.Any("error", err))
+	}
+	return true
+}
+
+// Purge deletes messages published longer than the retention ago
+// and returns the number of deleted messages.
+func (r *Relay) Purge() int64 {
+	purged, err := r.store.DeleteSentBefore(time.Now().Add(-r.config.SentRetention))
+	if err != nil {
+		slog.Error("purging sent outbox messages", slog.Any("error", err))
+		return 0
+	}
+	if purged > 0 {
+		slog.Info("purged sent outbox messages", slog.Any("purged", purged))
+	}
+	return purged
+}
+
+// Backlog returns the number of messages not published yet.
+func (r *Relay) Backlog() (int64, error) {
+	return r.store.CountPending()
+}
+
+func NewRelay(store OutboxStore, producer Producer, config config.Config) *Relay {
+	return &Relay{store: store, producer: producer, config: config}
+}
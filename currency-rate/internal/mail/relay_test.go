@@ -0,0 +1,154 @@
+package mail_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type (
+	mockOutboxStore struct {
+		mock.Mock
+	}
+
+	mockProducer struct {
+		mock.Mock
+	}
+)
+
+func (m *mockOutboxStore) FindPending(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]models.OutboxMessage), args.Error(1)
+}
+
+func (m *mockOutboxStore) MarkSent(message *models.OutboxMessage) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *mockOutboxStore) MarkFailed(
+	message *models.OutboxMessage, reason string, nextAttempt time.Time,
+) error {
+	args := m.Called(message, reason, nextAttempt)
+	return args.Error(0)
+}
+
+func (m *mockOutboxStore) CountPending() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockOutboxStore) DeleteSentBefore(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockProducer) Produce(ctx context.Context, msg []byte) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+var relayConfig = config.Config{
+	RelayInterval: time.Second,
+	BatchSize:     10,
+	RetryDelay:    time.Second,
+	MaxRetryDelay: time.Minute,
+	SentRetention: time.Hour,
+	PurgeInterval: time.Hour,
+}
+
+func TestRelayPublish(t *testing.T) {
+	// Arrange
+	store := new(mockOutboxStore)
+	producer := new(mockProducer)
+	messages := []models.OutboxMessage{
+		{ID: 1, Payload: "first"},
+		{ID: 2, Payload: "second", Attempts: 2},
+	}
+	store.On("FindPending", mock.Anything, relayConfig.BatchSize).Return(messages, nil)
+	producer.On("Produce", mock.Anything, []byte("first")).Return(nil)
+	producer.On("Produce", mock.Anything, []byte("second")).Return(assert.AnError)
+	store.On("MarkSent", mock.MatchedBy(func(m *models.OutboxMessage) bool {
+		return m.ID == 1
+	})).Return(nil)
+	var nextAttempt time.Time
+	store.On(
+		"MarkFailed", mock.Anything, assert.AnError.Error(), mock.Anything,
+	).Return(nil).Run(func(args mock.Arguments) {
+		nextAttempt = args.Get(2).(time.Time)
+	})
+	relay := mail.NewRelay(store, producer, relayConfig)
+	// Act
+	published := relay.Publish(context.Background())
+	// Assert
+	assert.Equal(t, 1, published)
+	store.AssertExpectations(t)
+	producer.AssertExpectations(t)
+	// Third attempt is delayed by four retry delays
+	assert.WithinDuration(t, time.Now().Add(4*time.Second), nextAttempt, time.Second)
+}
+
+func TestRelayPublishFindError(t *testing.T) {
+	// Arrange
+	store := new(mockOutboxStore)
+	producer := new(mockProducer)
+	store.On("FindPending", mock.Anything, mock.Anything).Return(
+		[]models.OutboxMessage(nil), assert.AnError,
+	)
+	relay := mail.NewRelay(store, producer, relayConfig)
+	// Act
+	published := relay.Publish(context.Background())
+	// Assert
+	assert.Zero(t, published)
+	producer.AssertNotCalled(t, "Produce", mock.Anything, mock.Anything)
+}
+
+func TestRelayBacklog(t *testing.T) {
+	// Arrange
+	store := new(mockOutboxStore)
+	store.On("CountPending").Return(int64(5), nil)
+	relay := mail.NewRelay(store, new(mockProducer), relayConfig)
+	// Act
+	backlog, err := relay.Backlog()
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), backlog)
+}
+
+func TestRelayPurge(t *testing.T) {
+	// Arrange
+	store := new(mockOutboxStore)
+	var before time.Time
+	store.On("DeleteSentBefore", mock.Anything).Return(int64(3), nil).Run(
+		func(args mock.Arguments) {
+			before = args.Get(0).(time.Time)
+		},
+	)
+	relay := mail.NewRelay(store, new(mockProducer), relayConfig)
+	// Act
+	purged := relay.Purge()
+	// Assert
+	assert.Equal(t, int64(3), purged)
+	assert.WithinDuration(t, time.Now().Add(-relayConfig.SentRetention), before, time.Second)
+}
+
+func TestConfigRetryDelayFor(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 10, expected: time.Minute},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, relayConfig.RetryDelayFor(tc.attempts))
+	}
+}
@@ -3,11 +3,33 @@ package config
 import (
 	"log/slog"
 	"os"
+	"time"
+)
+
+const (
+	defaultConfirmTimeout    = 5 * time.Second
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
 )
 
 type Config struct {
 	BrokerURI string
 	QueueName string
+	// ConfirmTimeout limits waiting for the broker to confirm a published message.
+	ConfirmTimeout time.Duration
+	// ReconnectDelay is a delay of the first reconnection attempt once the connection
+	// is lost or fails to be established, doubled for every next one up to MaxReconnectDelay.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+}
+
+// ReconnectDelayFor returns the delay before the reconnection attempt, starting from 1.
+func (c Config) ReconnectDelayFor(attempt int) time.Duration {
+	delay := c.ReconnectDelay
+	for i := 1; i < attempt && delay < c.MaxReconnectDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxReconnectDelay)
 }
 
 func getOrError(key string) string {
@@ -18,9 +40,32 @@ func getOrError(key string) string {
 	return value
 }
 
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
 func NewFromEnv() Config {
 	return Config{
-		BrokerURI: getOrError("BROKER_URI"),
-		QueueName: getOrError("QUEUE_NAME"),
+		BrokerURI:      getOrError("BROKER_URI"),
+		QueueName:      getOrError("QUEUE_NAME"),
+		ConfirmTimeout: durationOrDefault("BROKER_CONFIRM_TIMEOUT", defaultConfirmTimeout),
+		ReconnectDelay: durationOrDefault("BROKER_RECONNECT_DELAY", defaultReconnectDelay),
+		MaxReconnectDelay: durationOrDefault(
+			"BROKER_MAX_RECONNECT_DELAY", defaultMaxReconnectDelay,
+		),
 	}
 }
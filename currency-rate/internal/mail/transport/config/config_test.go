@@ -0,0 +1,39 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnectDelayFor(t *testing.T) {
+	cfg := config.Config{ReconnectDelay: time.Second, MaxReconnectDelay: 10 * time.Second}
+	testCases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: time.Second},
+		{attempt: 2, expected: 2 * time.Second},
+		{attempt: 4, expected: 8 * time.Second},
+		{attempt: 5, expected: 10 * time.Second},
+		{attempt: 60, expected: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, cfg.ReconnectDelayFor(tc.attempt), "attempt %d", tc.attempt)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	// Arrange
+	t.Setenv("BROKER_RECONNECT_DELAY", "2s")
+	t.Setenv("BROKER_MAX_RECONNECT_DELAY", "")
+	t.Setenv("BROKER_CONFIRM_TIMEOUT", "")
+	// Act
+	cfg := config.NewFromEnv()
+	// Assert
+	assert.Equal(t, 2*time.Second, cfg.ReconnectDelay)
+	assert.Equal(t, 30*time.Second, cfg.MaxReconnectDelay)
+	assert.Equal(t, 5*time.Second, cfg.ConfirmTimeout)
+}
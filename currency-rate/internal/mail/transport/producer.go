@@ -2,8 +2,11 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport/config"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -14,31 +17,85 @@ func logAndWrap(msg string, err error) error {
 	return fmt.Errorf("%s: %w", msg, err)
 }
 
+var (
+	// ErrNotConfirmed is returned when the broker negatively acknowledges a message.
+	ErrNotConfirmed = errors.New("message is not confirmed by the broker")
+	// ErrDisconnected is returned when publishing while the connection is being restored.
+	ErrDisconnected = errors.New("producer is disconnected from the broker")
+	errClosed       = errors.New("producer is closed")
+)
+
+// Producer publishes messages to the queue. Once the connection is lost,
+// it reconnects with backoff and declares the queue again.
 type Producer struct {
-	config  config.Config
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	config config.Config
+
+	connAccess sync.RWMutex
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+
+	done    chan struct{}
+	closing sync.Once
 }
 
 func (p *Producer) Close() error {
-	if err := p.channel.Close(); err != nil {
+	var err error
+	p.closing.Do(func() {
+		close(p.done)
+		p.connAccess.Lock()
+		defer p.connAccess.Unlock()
+		err = p.closeConnection()
+	})
+	return err
+}
+
+func (p *Producer) isClosed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeConnection closes the current connection, it must be called with the lock held.
+func (p *Producer) closeConnection() error {
+	if p.conn == nil {
+		return nil
+	}
+	conn, ch := p.conn, p.channel
+	p.conn, p.channel = nil, nil
+	if err := ch.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 		return logAndWrap("closing channel", err)
 	}
-	if err := p.conn.Close(); err != nil {
+	if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 		return logAndWrap("closing connection", err)
 	}
 	return nil
 }
 
+// Produce publishes a persistent message to the queue and waits for the broker
+// to confirm it, so that a returned nil means the message survives a broker restart.
+// Waiting is limited by the context and the confirm timeout of the config.
+// While the connection is being restored, it fails with ErrDisconnected.
 func (p *Producer) Produce(ctx context.Context, msg []byte) error {
-	err := p.channel.PublishWithContext(ctx,
+	p.connAccess.RLock()
+	channel := p.channel
+	p.connAccess.RUnlock()
+	if channel == nil {
+		return ErrDisconnected
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.config.ConfirmTimeout)
+	defer cancel()
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx,
 		"",                 // exchange
 		p.config.QueueName, // routing key
 		false,              // mandatory
 		false,              // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        []byte(msg),
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msg,
 		})
 	slog.Info(
 		"publishing message",
@@ -48,22 +105,99 @@ func (p *Producer) Produce(ctx context.Context, msg []byte) error {
 	if err != nil {
 		return fmt.Errorf("publishing message: %w", err)
 	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("waiting for publish confirmation: %w", err)
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
 	return nil
 }
 
-func NewProducer(config config.Config) (*Producer, error) {
-	slog.Info("creating producer", slog.Any("config", config))
-	conn, err := amqp.Dial(config.BrokerURI)
+// connect dials the broker, declares the queue and starts supervising the connection.
+func (p *Producer) connect() error {
+	conn, err := amqp.Dial(p.config.BrokerURI)
+	if err != nil {
+		return logAndWrap("dialing broker", err)
+	}
+	ch, err := openChannel(conn, p.config)
 	if err != nil {
-		return nil, logAndWrap("dialing broker", err)
+		conn.Close()
+		return err
+	}
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	p.connAccess.Lock()
+	defer p.connAccess.Unlock()
+	if p.isClosed() {
+		conn.Close()
+		return errClosed
+	}
+	p.conn, p.channel = conn, ch
+	go p.supervise(connClosed, channelClosed)
+	return nil
+}
+
+// supervise waits for the connection or the channel to close,
+// and reconnects unless the producer is closed.
+func (p *Producer) supervise(connClosed, channelClosed <-chan *amqp.Error) {
+	var reason *amqp.Error
+	select {
+	case <-p.done:
+		return
+	case reason = <-connClosed:
+	case reason = <-channelClosed:
+	}
+	if p.isClosed() {
+		return
 	}
+	slog.Error("broker connection lost", slog.Any("reason", reason))
+	p.connAccess.Lock()
+	_ = p.closeConnection()
+	p.connAccess.Unlock()
+	p.connectWithBackoff(1)
+}
+
+// connectWithBackoff connects until it succeeds or the producer is closed.
+// Attempts are delayed with exponential backoff, except the initial attempt 0.
+func (p *Producer) connectWithBackoff(attempt int) {
+	for ; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-p.done:
+				return
+			case <-time.After(p.config.ReconnectDelayFor(attempt)):
+			}
+		}
+		err := p.connect()
+		if err == nil {
+			slog.Info("connected to broker", slog.Any("attempt", attempt))
+			return
+		}
+		if errors.Is(err, errClosed) {
+			return
+		}
+		slog.Error(
+			"connecting to broker",
+			slog.Any("attempt", attempt),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func openChannel(conn *amqp.Connection, config config.Config) (*amqp.Channel, error) {
 	ch, err := conn.Channel()
 	if err != nil {
 		return nil, logAndWrap("creating channel", err)
 	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, logAndWrap("enabling publisher confirms", err)
+	}
 	_, err = ch.QueueDeclare(
 		config.QueueName, // name
-		false,            // durable
+		true,             // durable
 		false,            // delete when unused
 		false,            // exclusive
 		false,            // no-wait
@@ -72,10 +206,15 @@ func NewProducer(config config.Config) (*Producer, error) {
 	if err != nil {
 		return nil, logAndWrap("declaring queue", err)
 	}
+	return ch, nil
+}
 
-	return &Producer{
-		config:  config,
-		conn:    conn,
-		channel: ch,
-	}, nil
+// NewProducer creates a producer connecting to the broker in the background,
+// with the same backoff as reconnections. Until it is connected,
+// Produce fails with ErrDisconnected.
+func NewProducer(config config.Config) *Producer {
+	slog.Info("creating producer", slog.Any("config", config))
+	p := &Producer{config: config, done: make(chan struct{})}
+	go p.connectWithBackoff(0)
+	return p
 }
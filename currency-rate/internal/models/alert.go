@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+type AlertCondition string
+
+const (
+	// AlertAbove fires when the rate goes above the threshold.
+	AlertAbove AlertCondition = "above"
+	// AlertBelow fires when the rate goes below the threshold.
+	AlertBelow AlertCondition = "below"
+	// AlertChange fires when the rate moves by more than threshold percent within a day.
+	AlertChange AlertCondition = "change"
+)
+
+func (c AlertCondition) IsValid() bool {
+	switch c {
+	case AlertAbove, AlertBelow, AlertChange:
+		return true
+	}
+	return false
+}
+
+type Alert struct {
+	ID           uint `gorm:"primaryKey"`
+	UserID       uint `gorm:"index"`
+	User         User
+	CurrencyFrom string `gorm:"column:cc_from;index:idx_alert_pair"`
+	CurrencyTo   string `gorm:"column:cc_to;index:idx_alert_pair"`
+	Condition    AlertCondition
+	// Threshold is a rate for above/below conditions and percents for change one.
+	Threshold decimal.Decimal `gorm:"type:varchar(64)"`
+	// Triggered is set while the condition holds, so that the alert
+	// fires once per crossing and is re-armed when the condition stops holding.
+	Triggered bool
+	LastFired int64 // Use unix seconds as firing time
+	Created   int64 `gorm:"autoCreateTime"` // Use unix seconds as creating time
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf(
+		"Alert<%d, %s-%s %s %s>",
+		a.ID, a.CurrencyFrom, a.CurrencyTo, a.Condition, a.Threshold,
+	)
+}
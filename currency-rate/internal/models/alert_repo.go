@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlertRepository struct {
+	db DB
+}
+
+func (r *AlertRepository) Create(alert *Alert) error {
+	return r.db.Connection().Create(alert).Error
+}
+
+// FindByPair returns alerts of the currency pair along with their users.
+func (r *AlertRepository) FindByPair(ccFrom, ccTo string) ([]Alert, error) {
+	var alerts []Alert
+	err := r.db.Connection().Preload("User").Where(
+		"cc_from = ? AND cc_to = ?", ccFrom, ccTo,
+	).Find(&alerts).Error
+	return alerts, err
+}
+
+// FindByUser returns alerts of the user, oldest first.
+func (r *AlertRepository) FindByUser(userID uint) ([]Alert, error) {
+	var alerts []Alert
+	err := r.db.Connection().Where("user_id = ?", userID).Order("id").Find(&alerts).Error
+	return alerts, err
+}
+
+// Delete deletes the alert of the user. Returns false if the user has no such alert.
+func (r *AlertRepository) Delete(userID, alertID uint) (bool, error) {
+	result := r.db.Connection().Where(
+		"id = ? AND user_id = ?", alertID, userID,
+	).Delete(&Alert{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// claimTriggered switches the triggered state of the alert unless it is already switched,
+// so that only one of concurrent evaluations of the same crossing succeeds.
+func claimTriggered(
+	tx *gorm.DB, alert *Alert, triggered bool, updates map[string]any,
+) (bool, error) {
+	updates["triggered"] = triggered
+	result := tx.Model(&Alert{}).Where(
+		"id = ? AND triggered = ?", alert.ID, !triggered,
+	).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Fire marks the alert triggered and stores the notification payload in the outbox
+// within one transaction. Returns false and stores nothing if the alert
+// has already been triggered.
+func (r *AlertRepository) Fire(alert *Alert, payload []byte) (bool, error) {
+	firedAt := time.Now().Unix()
+	var claimed bool
+	err := r.db.Connection().Transaction(func(tx *gorm.DB) error {
+		var err error
+		claimed, err = claimTriggered(tx, alert, true, map[string]any{"last_fired": firedAt})
+		if err != nil || !claimed {
+			return err
+		}
+		return tx.Create(newOutboxMessage(payload)).Error
+	})
+	if err != nil || !claimed {
+		return false, err
+	}
+	alert.Triggered = true
+	alert.LastFired = firedAt
+	return true, nil
+}
+
+// Rearm resets the triggered state of the alert, so that it fires on the next crossing.
+// Returns false if the alert has already been re-armed.
+func (r *AlertRepository) Rearm(alert *Alert) (bool, error) {
+	claimed, err := claimTriggered(r.db.Connection(), alert, false, map[string]any{})
+	if err != nil || !claimed {
+		return false, err
+	}
+	alert.Triggered = false
+	return true, nil
+}
+
+func NewAlertRepository(db DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
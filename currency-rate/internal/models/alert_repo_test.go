@@ -0,0 +1,131 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRepositoryFindByPair(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.User{}, &models.Alert{})
+	user := &models.User{Email: "example@gmail.com"}
+	require.NoError(t, db.Connection().Create(user).Error)
+	repo := models.NewAlertRepository(db)
+	usdAlert := &models.Alert{
+		UserID: user.ID, CurrencyFrom: "USD", CurrencyTo: "UAH",
+		Condition: models.AlertAbove, Threshold: decimal.RequireFromString("42"),
+	}
+	eurAlert := &models.Alert{
+		UserID: user.ID, CurrencyFrom: "EUR", CurrencyTo: "UAH",
+		Condition: models.AlertChange, Threshold: decimal.RequireFromString("1"),
+	}
+	require.NoError(t, repo.Create(usdAlert))
+	require.NoError(t, repo.Create(eurAlert))
+	// Act
+	alerts, err := repo.FindByPair("USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, usdAlert.ID, alerts[0].ID)
+	assert.Equal(t, user.Email, alerts[0].User.Email)
+}
+
+func TestAlertRepositoryFire(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Alert{}, &models.OutboxMessage{})
+	repo := models.NewAlertRepository(db)
+	outbox := models.NewOutboxRepository(db)
+	alert := &models.Alert{CurrencyFrom: "USD", CurrencyTo: "UAH", Condition: models.AlertBelow}
+	require.NoError(t, repo.Create(alert))
+	stale := *alert
+	// Act
+	fired, err := repo.Fire(alert, []byte("alert"))
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, fired)
+	alerts, err := repo.FindByPair("USD", "UAH")
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.True(t, alerts[0].Triggered)
+	assert.NotZero(t, alerts[0].LastFired)
+	messages, err := outbox.FindPending(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alert", messages[0].Payload)
+	// Concurrent evaluation of the same crossing
+	fired, err = repo.Fire(&stale, []byte("duplicate"))
+	require.NoError(t, err)
+	assert.False(t, fired)
+	count, err := outbox.CountPending()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAlertRepositoryRearm(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Alert{})
+	repo := models.NewAlertRepository(db)
+	alert := &models.Alert{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Condition: models.AlertBelow, Triggered: true,
+	}
+	require.NoError(t, repo.Create(alert))
+	// Act
+	rearmed, err := repo.Rearm(alert)
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, rearmed)
+	alerts, err := repo.FindByPair("USD", "UAH")
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.False(t, alerts[0].Triggered)
+	rearmed, err = repo.Rearm(alert)
+	require.NoError(t, err)
+	assert.False(t, rearmed)
+}
+
+func TestAlertRepositoryFindByUser(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Alert{})
+	repo := models.NewAlertRepository(db)
+	first := &models.Alert{UserID: 1, CurrencyFrom: "USD", CurrencyTo: "UAH"}
+	other := &models.Alert{UserID: 2, CurrencyFrom: "USD", CurrencyTo: "UAH"}
+	second := &models.Alert{UserID: 1, CurrencyFrom: "EUR", CurrencyTo: "UAH"}
+	for _, alert := range []*models.Alert{first, other, second} {
+		require.NoError(t, repo.Create(alert))
+	}
+	// Act
+	alerts, err := repo.FindByUser(1)
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, first.ID, alerts[0].ID)
+	assert.Equal(t, second.ID, alerts[1].ID)
+}
+
+func TestAlertRepositoryDelete(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Alert{})
+	repo := models.NewAlertRepository(db)
+	alert := &models.Alert{UserID: 1, CurrencyFrom: "USD", CurrencyTo: "UAH"}
+	require.NoError(t, repo.Create(alert))
+	// Act
+	foreign, foreignErr := repo.Delete(2, alert.ID)
+	deleted, deletedErr := repo.Delete(1, alert.ID)
+	again, againErr := repo.Delete(1, alert.ID)
+	// Assert
+	require.NoError(t, foreignErr)
+	require.NoError(t, deletedErr)
+	require.NoError(t, againErr)
+	assert.False(t, foreign)
+	assert.True(t, deleted)
+	assert.False(t, again)
+	alerts, err := repo.FindByUser(1)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
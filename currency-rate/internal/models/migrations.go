@@ -0,0 +1,337 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const decimalColumnType = "VARCHAR(64)"
+
+// Table snapshots of the first schema version. They are frozen copies of
+// the models, so that later changes of the models don't change the migration.
+type (
+	v1User struct {
+		gorm.Model
+		Email         string
+		Status        string           `gorm:"default:confirmed"`
+		Subscriptions []v1Subscription `gorm:"foreignKey:UserID"`
+	}
+	v1CurrencyPair struct {
+		ID           uint   `gorm:"primaryKey"`
+		CurrencyFrom string `gorm:"column:cc_from;uniqueIndex:idx_currency_pair"`
+		CurrencyTo   string `gorm:"column:cc_to;uniqueIndex:idx_currency_pair"`
+	}
+	v1Subscription struct {
+		ID     uint           `gorm:"primaryKey"`
+		UserID uint           `gorm:"uniqueIndex:idx_user_pair"`
+		PairID uint           `gorm:"uniqueIndex:idx_user_pair"`
+		Pair   v1CurrencyPair `gorm:"foreignKey:PairID"`
+	}
+	v1Rate struct {
+		ID           uint   `gorm:"primaryKey"`
+		CurrencyFrom string `gorm:"column:cc_from"`
+		CurrencyTo   string `gorm:"column:cc_to"`
+		Rate         float32
+		Created      int64
+	}
+	v1Alert struct {
+		ID           uint   `gorm:"primaryKey"`
+		UserID       uint   `gorm:"index"`
+		User         v1User `gorm:"foreignKey:UserID"`
+		CurrencyFrom string `gorm:"column:cc_from;index:idx_alert_pair"`
+		CurrencyTo   string `gorm:"column:cc_to;index:idx_alert_pair"`
+		Condition    string
+		Threshold    float32
+		Triggered    bool
+		LastFired    int64
+		Created      int64
+	}
+)
+
+// v3OutboxMessage is a snapshot of the outbox table of the third schema version.
+type v3OutboxMessage struct {
+	ID          uint   `gorm:"primaryKey"`
+	Payload     string `gorm:"type:text"`
+	Attempts    int
+	LastError   string
+	NextAttempt int64 `gorm:"index"`
+	Created     int64
+	Sent        int64 `gorm:"index"`
+}
+
+// v4User is a snapshot of the users table columns added in the fourth schema version.
+type v4User struct {
+	Locale string `gorm:"default:en"`
+}
+
+// v5User is a snapshot of the users table columns added in the fifth schema version.
+type v5User struct {
+	DeliveryTime string `gorm:"default:12:00"`
+	Timezone     string `gorm:"default:UTC"`
+	Frequency    string `gorm:"default:daily"`
+	// LastNotified defaults to zero, so that existing users are due rather than NULL
+	LastNotified int64 `gorm:"default:0;not null"`
+}
+
+// v5UserColumns are the fields of the columns added in the fifth schema version.
+var v5UserColumns = []string{"DeliveryTime", "Timezone", "Frequency", "LastNotified"}
+
+// v6User is a snapshot of the users table column added in the sixth schema version.
+type v6User struct {
+	// ConfirmationSentAt defaults to zero, so that existing pending users may be sent one
+	ConfirmationSentAt int64 `gorm:"default:0;not null"`
+}
+
+func (v1User) TableName() string         { return "users" }
+func (v1CurrencyPair) TableName() string { return "currency_pairs" }
+func (v1Subscription) TableName() string { return "subscriptions" }
+func (v1Rate) TableName() string         { return "rates" }
+func (v1Alert) TableName() string        { return "alerts" }
+
+func (v3OutboxMessage) TableName() string { return "outbox_messages" }
+
+func (v4User) TableName() string { return "users" }
+func (v5User) TableName() string { return "users" }
+func (v6User) TableName() string { return "users" }
+
+// v1Tables are listed in the creation order, so that referenced tables come first.
+var v1Tables = []any{&v1User{}, &v1CurrencyPair{}, &v1Subscription{}, &v1Rate{}, &v1Alert{}}
+
+// decimalColumns lists float columns which keep exact decimals since the second version.
+var decimalColumns = []struct {
+	table  string
+	column string
+}{
+	{table: "rates", column: "rate"},
+	{table: "alerts", column: "threshold"},
+}
+
+// Migrations returns the schema history of the service, oldest first.
+func Migrations() []database.Migration {
+	return []database.Migration{
+		{Version: 1, Name: "create_tables", Up: createTables, Down: dropTables},
+		{Version: 2, Name: "decimal_rates", Up: decimalRatesUp, Down: decimalRatesDown},
+		{Version: 3, Name: "create_outbox", Up: createOutbox, Down: dropOutbox},
+		{Version: 4, Name: "user_locale", Up: addUserLocale, Down: dropUserLocale},
+		{Version: 5, Name: "user_schedule", Up: addUserSchedule, Down: dropUserSchedule},
+		{
+			Version: 6, Name: "confirmation_sent",
+			Up: addConfirmationSent, Down: dropConfirmationSent,
+		},
+	}
+}
+
+// adoptTable creates the table, or completes one created by AutoMigrate
+// of earlier versions with missing columns and indexes.
+func adoptTable(tx *gorm.DB, model any) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(model) {
+		return migrator.CreateTable(model)
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || migrator.HasColumn(model, field.DBName) {
+			continue
+		}
+		if err := migrator.AddColumn(model, field.Name); err != nil {
+			return err
+		}
+	}
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if migrator.HasIndex(model, index.Name) {
+			continue
+		}
+		if err := migrator.CreateIndex(model, index.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createTables(tx *gorm.DB) error {
+	for _, model := range v1Tables {
+		if err := adoptTable(tx, model); err != nil {
+			return fmt.Errorf("creating %T: %w", model, err)
+		}
+	}
+	return nil
+}
+
+func dropTables(tx *gorm.DB) error {
+	for i := len(v1Tables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(v1Tables[i]); err != nil {
+			return fmt.Errorf("dropping %T: %w", v1Tables[i], err)
+		}
+	}
+	return nil
+}
+
+// isTextColumn reports whether the column of the table is textual.
+func isTextColumn(tx *gorm.DB, table, column string) (bool, error) {
+	columnTypes, err := tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false, err
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() != column {
+			continue
+		}
+		name := strings.ToLower(columnType.DatabaseTypeName())
+		return strings.Contains(name, "char") || strings.Contains(name, "text"), nil
+	}
+	return false, fmt.Errorf("column %s.%s not found", table, column)
+}
+
+// replaceColumn replaces the column with a new one of the column type,
+// filled by the fill function with values converted from the old one.
+func replaceColumn(
+	tx *gorm.DB, table, column, columnType string,
+	fill func(tx *gorm.DB, table, from, to string) error,
+) error {
+	tmp := column + "_new"
+	err := tx.Exec(
+		"ALTER TABLE ? ADD COLUMN ? "+columnType,
+		clause.Table{Name: table}, clause.Column{Name: tmp},
+	).Error
+	if err != nil {
+		return err
+	}
+	if err := fill(tx, table, column, tmp); err != nil {
+		return err
+	}
+	err = tx.Exec(
+		"ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column},
+	).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec(
+		"ALTER TABLE ? RENAME COLUMN ? TO ?",
+		clause.Table{Name: table}, clause.Column{Name: tmp}, clause.Column{Name: column},
+	).Error
+}
+
+// fillDecimals stores the shortest decimals which round-trip to the float32
+// values, so that 41.1235 stored as 41.12350082397461 becomes "41.1235" again.
+func fillDecimals(tx *gorm.DB, table, from, to string) error {
+	var rows []struct {
+		ID    uint
+		Value float64
+	}
+	if err := tx.Table(table).Select("id", from+" AS value").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		value := decimal.NewFromFloat32(float32(row.Value)).String()
+		if err := tx.Table(table).Where("id = ?", row.ID).Update(to, value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fillFloats(tx *gorm.DB, table, from, to string) error {
+	var rows []struct {
+		ID    uint
+		Value string
+	}
+	if err := tx.Table(table).Select("id", from+" AS value").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		value, err := decimal.NewFromString(row.Value)
+		if err != nil {
+			return fmt.Errorf("parsing %s.%s of %d: %w", table, from, row.ID, err)
+		}
+		err = tx.Table(table).Where("id = ?", row.ID).Update(to, value.InexactFloat64()).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decimalRatesUp converts float rates and alert thresholds to exact decimals,
+// skipping columns already converted by AutoMigrate of earlier versions.
+func decimalRatesUp(tx *gorm.DB) error {
+	for _, c := range decimalColumns {
+		isText, err := isTextColumn(tx, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if isText {
+			continue
+		}
+		err = replaceColumn(tx, c.table, c.column, decimalColumnType, fillDecimals)
+		if err != nil {
+			return fmt.Errorf("converting %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+func decimalRatesDown(tx *gorm.DB) error {
+	for _, c := range decimalColumns {
+		isText, err := isTextColumn(tx, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if !isText {
+			continue
+		}
+		if err := replaceColumn(tx, c.table, c.column, "REAL", fillFloats); err != nil {
+			return fmt.Errorf("converting %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+func createOutbox(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v3OutboxMessage{})
+}
+
+func dropOutbox(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v3OutboxMessage{})
+}
+
+func addUserLocale(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v4User{}, "Locale")
+}
+
+func dropUserLocale(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v4User{}, "Locale")
+}
+
+func addUserSchedule(tx *gorm.DB) error {
+	for _, column := range v5UserColumns {
+		if err := tx.Migrator().AddColumn(&v5User{}, column); err != nil {
+			return fmt.Errorf("adding users.%s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func dropUserSchedule(tx *gorm.DB) error {
+	for _, column := range v5UserColumns {
+		if err := tx.Migrator().DropColumn(&v5User{}, column); err != nil {
+			return fmt.Errorf("dropping users.%s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func addConfirmationSent(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v6User{}, "ConfirmationSentAt")
+}
+
+func dropConfirmationSent(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v6User{}, "ConfirmationSentAt")
+}
@@ -0,0 +1,142 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMigrator opens a named in-memory database not shared with other tests,
+// as the migrated tables would clash with the shared one.
+func newMigrator(t *testing.T) (*database.DB, *database.Migrator) {
+	t.Helper()
+	db, err := database.New(config.Config{
+		DatabaseService: "sqlite",
+		DatabaseDSN:     "file:" + t.Name() + "?mode=memory&cache=shared",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := database.NewMigrator(db, models.Migrations())
+	require.NoError(t, err)
+	return db, migrator
+}
+
+func TestMigrationsFreshDatabase(t *testing.T) {
+	// Arrange
+	db, migrator := newMigrator(t)
+	// Act
+	_, err := migrator.Up()
+	// Assert
+	require.NoError(t, err)
+	user := &models.User{
+		Email:         "example@gmail.com",
+		Subscriptions: models.NewSubscriptions(pairs("USD/UAH")),
+	}
+	require.NoError(t, models.NewUserRepository(db).Create(user))
+	require.NoError(t, models.NewAlertRepository(db).Create(&models.Alert{
+		UserID: user.ID, CurrencyFrom: "USD", CurrencyTo: "UAH",
+		Condition: models.AlertAbove, Threshold: dec("42.125"),
+	}))
+	rate := &models.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("41.123456789")}
+	require.NoError(t, models.NewRateRepository(db).Create(rate))
+	var stored models.Rate
+	require.NoError(t, db.Connection().First(&stored, rate.ID).Error)
+	assert.Equal(t, "41.123456789", stored.Rate.String())
+	alerts, err := models.NewAlertRepository(db).FindByPair("USD", "UAH")
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "42.125", alerts[0].Threshold.String())
+}
+
+func TestMigrationsLegacyDatabase(t *testing.T) {
+	// Arrange
+	db, migrator := newMigrator(t)
+	conn := db.Connection()
+	// Tables created by AutoMigrate of versions storing rates as floats
+	// and before subscription statuses were introduced
+	require.NoError(t, conn.Exec(
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, created_at DATETIME, "+
+			"updated_at DATETIME, deleted_at DATETIME, email TEXT)",
+	).Error)
+	require.NoError(t, conn.Exec("INSERT INTO users (email) VALUES (?)", "old@gmail.com").Error)
+	require.NoError(t, conn.Exec(
+		"CREATE TABLE rates (id INTEGER PRIMARY KEY, cc_from TEXT, cc_to TEXT, "+
+			"rate REAL, created INTEGER)",
+	).Error)
+	require.NoError(t, conn.Exec(
+		"INSERT INTO rates (cc_from, cc_to, rate, created) VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+		"USD", "UAH", float32(41.1235), 1, "EUR", "UAH", float32(44.5), 2,
+	).Error)
+	// Act
+	_, err := migrator.Up()
+	// Assert
+	require.NoError(t, err)
+	var rates []models.Rate
+	require.NoError(t, conn.Order("id").Find(&rates).Error)
+	require.Len(t, rates, 2)
+	assert.Equal(t, "41.1235", rates[0].Rate.String())
+	assert.Equal(t, "44.5", rates[1].Rate.String())
+	users, err := models.NewUserRepository(db).FindConfirmed()
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "old@gmail.com", users[0].Email)
+	assert.Equal(t, models.LocaleEnglish, users[0].Locale)
+	assert.Equal(t, models.DefaultSchedule(), users[0].Schedule)
+	due, err := models.NewUserRepository(db).FindDue(
+		time.Date(2024, time.June, 5, 13, 0, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "old@gmail.com", due[0].Email)
+}
+
+func TestMigrationsLegacyPendingUser(t *testing.T) {
+	// Arrange
+	db, migrator := newMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	// Users pending before confirmation times were recorded
+	_, err = migrator.Down(1)
+	require.NoError(t, err)
+	conn := db.Connection()
+	require.NoError(t, conn.Exec(
+		"INSERT INTO users (email, status) VALUES (?, ?)",
+		"pending@gmail.com", models.StatusPending,
+	).Error)
+	// Act
+	_, err = migrator.Up()
+	// Assert
+	require.NoError(t, err)
+	repo := models.NewUserRepository(db)
+	user, err := repo.FindByEmail("pending@gmail.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	claimed, err := repo.ClaimConfirmation(user, time.Now(), 5*time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMigrationsDown(t *testing.T) {
+	// Arrange
+	db, migrator := newMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	rate := &models.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("41.5")}
+	require.NoError(t, models.NewRateRepository(db).Create(rate))
+	// Act
+	rolledBack, err := migrator.Down(len(models.Migrations()))
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, len(models.Migrations()), rolledBack)
+	tables := []string{
+		"users", "currency_pairs", "subscriptions", "rates", "alerts", "outbox_messages",
+	}
+	for _, table := range tables {
+		assert.False(t, db.Connection().Migrator().HasTable(table), table)
+	}
+}
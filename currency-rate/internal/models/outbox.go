@@ -0,0 +1,22 @@
+package models
+
+import "fmt"
+
+// OutboxMessage is a broker message stored before publishing, so that
+// messages survive broker outages and are published by the relay later.
+type OutboxMessage struct {
+	ID      uint   `gorm:"primaryKey"`
+	Payload string `gorm:"type:text"`
+	// Attempts is the number of failed publishing attempts.
+	Attempts  int
+	LastError string
+	// NextAttempt is when the message may be published next, in unix seconds.
+	NextAttempt int64 `gorm:"index"`
+	Created     int64 `gorm:"autoCreateTime"` // Use unix seconds as creating time
+	// Sent is the publishing time in unix seconds, zero while pending.
+	Sent int64 `gorm:"index"`
+}
+
+func (m OutboxMessage) String() string {
+	return fmt.Sprintf("OutboxMessage<%d, attempts %d, sent %d>", m.ID, m.Attempts, m.Sent)
+}
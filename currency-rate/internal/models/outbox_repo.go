@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+type OutboxRepository struct {
+	db DB
+}
+
+// newOutboxMessage creates a pending message of the payload due to be published at once.
+func newOutboxMessage(payload []byte) *OutboxMessage {
+	return &OutboxMessage{
+		Payload:     string(payload),
+		NextAttempt: time.Now().Unix(),
+	}
+}
+
+// Add stores the payload as a pending message.
+func (r *OutboxRepository) Add(payload []byte) error {
+	return r.db.Connection().Create(newOutboxMessage(payload)).Error
+}
+
+// FindPending returns up to limit pending messages due to be published
+// by the time, oldest first.
+func (r *OutboxRepository) FindPending(now time.Time, limit int) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	err := r.db.Connection().Where(
+		"sent = 0 AND next_attempt <= ?", now.Unix(),
+	).Order("id").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *OutboxRepository) MarkSent(message *OutboxMessage) error {
+	message.Sent = time.Now().Unix()
+	return r.db.Connection().Model(message).Update("sent", message.Sent).Error
+}
+
+// MarkFailed records the failed publishing attempt and postpones the message.
+func (r *OutboxRepository) MarkFailed(
+	message *OutboxMessage, reason string, nextAttempt time.Time,
+) error {
+	message.Attempts++
+	message.LastError = reason
+	message.NextAttempt = nextAttempt.Unix()
+	return r.db.Connection().Model(message).Updates(map[string]any{
+		"attempts":     message.Attempts,
+		"last_error":   message.LastError,
+		"next_attempt": message.NextAttempt,
+	}).Error
+}
+
+// DeleteSentBefore deletes messages published before the time
+// and returns the number of deleted messages.
+func (r *OutboxRepository) DeleteSentBefore(before time.Time) (int64, error) {
+	result := r.db.Connection().Where(
+		"sent > 0 AND sent < ?", before.Unix(),
+	).Delete(&OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// CountPending returns the number of messages not published yet.
+func (r *OutboxRepository) CountPending() (int64, error) {
+	var count int64
+	err := r.db.Connection().Model(&OutboxMessage{}).Where("sent = 0").Count(&count).Error
+	return count, err
+}
+
+func NewOutboxRepository(db DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
@@ -0,0 +1,95 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRepositoryFindPending(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.OutboxMessage{})
+	repo := models.NewOutboxRepository(db)
+	require.NoError(t, repo.Add([]byte("first")))
+	require.NoError(t, repo.Add([]byte("second")))
+	require.NoError(t, repo.Add([]byte("third")))
+	// Act
+	messages, err := repo.FindPending(time.Now(), 2)
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "first", messages[0].Payload)
+	assert.Equal(t, "second", messages[1].Payload)
+}
+
+func TestOutboxRepositoryMarkSent(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.OutboxMessage{})
+	repo := models.NewOutboxRepository(db)
+	require.NoError(t, repo.Add([]byte("message")))
+	messages, err := repo.FindPending(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	// Act
+	err = repo.MarkSent(&messages[0])
+	// Assert
+	require.NoError(t, err)
+	messages, err = repo.FindPending(time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	count, err := repo.CountPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestOutboxRepositoryMarkFailed(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.OutboxMessage{})
+	repo := models.NewOutboxRepository(db)
+	require.NoError(t, repo.Add([]byte("message")))
+	messages, err := repo.FindPending(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	nextAttempt := time.Now().Add(time.Hour)
+	// Act
+	err = repo.MarkFailed(&messages[0], "broker is down", nextAttempt)
+	// Assert
+	require.NoError(t, err)
+	pending, err := repo.FindPending(time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	pending, err = repo.FindPending(nextAttempt, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "broker is down", pending[0].LastError)
+	count, err := repo.CountPending()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOutboxRepositoryDeleteSentBefore(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.OutboxMessage{})
+	repo := models.NewOutboxRepository(db)
+	old := &models.OutboxMessage{Payload: "old", Sent: time.Now().Add(-2 * time.Hour).Unix()}
+	recent := &models.OutboxMessage{Payload: "recent", Sent: time.Now().Unix()}
+	for _, message := range []*models.OutboxMessage{old, recent} {
+		require.NoError(t, db.Connection().Create(message).Error)
+	}
+	require.NoError(t, repo.Add([]byte("pending")))
+	// Act
+	deleted, err := repo.DeleteSentBefore(time.Now().Add(-time.Hour))
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	var payloads []string
+	require.NoError(t, db.Connection().Model(&models.OutboxMessage{}).Order("id").Pluck(
+		"payload", &payloads,
+	).Error)
+	assert.Equal(t, []string{"recent", "pending"}, payloads)
+}
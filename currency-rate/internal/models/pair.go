@@ -0,0 +1,32 @@
+package models
+
+import "fmt"
+
+// CurrencyPair is a pair of currencies users can subscribe to.
+type CurrencyPair struct {
+	ID           uint   `gorm:"primaryKey"`
+	CurrencyFrom string `gorm:"column:cc_from;uniqueIndex:idx_currency_pair"`
+	CurrencyTo   string `gorm:"column:cc_to;uniqueIndex:idx_currency_pair"`
+}
+
+func (p CurrencyPair) String() string {
+	return fmt.Sprintf("%s/%s", p.CurrencyFrom, p.CurrencyTo)
+}
+
+// Subscription links a user with a currency pair the user follows.
+type Subscription struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint `gorm:"uniqueIndex:idx_user_pair"`
+	PairID uint `gorm:"uniqueIndex:idx_user_pair"`
+	Pair   CurrencyPair
+}
+
+// NewSubscriptions creates subscriptions for the pairs,
+// to be assigned to a user before creating it.
+func NewSubscriptions(pairs []CurrencyPair) []Subscription {
+	subscriptions := make([]Subscription, 0, len(pairs))
+	for _, pair := range pairs {
+		subscriptions = append(subscriptions, Subscription{Pair: pair})
+	}
+	return subscriptions
+}
@@ -1,15 +1,25 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/shopspring/decimal"
+)
 
 type Rate struct {
-	ID           uint   `gorm:"primaryKey"`
-	CurrencyFrom string `gorm:"column:cc_from"`
-	CurrencyTo   string `gorm:"column:cc_to"`
-	Rate         float32
-	Created      int64 `gorm:"autoCreateTime"` // Use unix seconds as creating time
+	ID           uint            `gorm:"primaryKey"`
+	CurrencyFrom string          `gorm:"column:cc_from"`
+	CurrencyTo   string          `gorm:"column:cc_to"`
+	Rate         decimal.Decimal `gorm:"type:varchar(64)"` // Text keeps the exact decimal in SQLite
+	Created      int64           `gorm:"autoCreateTime"`   // Use unix seconds as creating time
+	// Cache tells whether the rate was served from a cache, it isn't stored.
+	Cache rate.CacheStatus `gorm:"-"`
+	// Provider and Legs tell where the rate came from, they aren't stored either.
+	Provider string     `gorm:"-"`
+	Legs     []rate.Leg `gorm:"-"`
 }
 
 func (r Rate) String() string {
-	return fmt.Sprintf("Rate<%d, %s-%s: %f>", r.ID, r.CurrencyFrom, r.CurrencyTo, r.Rate)
+	return fmt.Sprintf("Rate<%d, %s-%s: %s>", r.ID, r.CurrencyFrom, r.CurrencyTo, r.Rate)
 }
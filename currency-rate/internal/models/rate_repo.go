@@ -1,5 +1,13 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type RateRepository struct {
 	db DB
 }
@@ -8,6 +16,72 @@ func (r *RateRepository) Create(rate *Rate) error {
 	return r.db.Connection().Create(rate).Error
 }
 
+// FindFirstSince returns the earliest rate of the currency pair created
+// not before since, or nil if there is no such rate.
+func (r *RateRepository) FindFirstSince(ccFrom, ccTo string, since time.Time) (*Rate, error) {
+	rate := &Rate{}
+	err := r.db.Connection().Where(
+		"cc_from = ? AND cc_to = ? AND created >= ?", ccFrom, ccTo, since.Unix(),
+	).Order("created, id").First(rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// rangeQuery selects rates of the currency pair created within [since, until).
+func (r *RateRepository) rangeQuery(ccFrom, ccTo string, since, until time.Time) *gorm.DB {
+	return r.db.Connection().Model(&Rate{}).Where(
+		"cc_from = ? AND cc_to = ? AND created >= ? AND created < ?",
+		ccFrom, ccTo, since.Unix(), until.Unix(),
+	)
+}
+
+// bucketsQuery selects distinct start times of interval-long buckets, in unix seconds,
+// which have rates within the range. Integer arithmetic on the unix creation time
+// keeps the query portable between SQLite and Postgres.
+func (r *RateRepository) bucketsQuery(
+	ccFrom, ccTo string, since, until time.Time, interval time.Duration,
+) *gorm.DB {
+	seconds := int64(interval.Seconds())
+	return r.rangeQuery(ccFrom, ccTo, since, until).Select(
+		fmt.Sprintf("created - created %% %d AS bucket", seconds),
+	).Group("bucket")
+}
+
+// CountBuckets returns the number of interval-long buckets having rates within the range.
+func (r *RateRepository) CountBuckets(
+	ccFrom, ccTo string, since, until time.Time, interval time.Duration,
+) (int64, error) {
+	var count int64
+	buckets := r.bucketsQuery(ccFrom, ccTo, since, until, interval)
+	err := r.db.Connection().Table("(?) AS buckets", buckets).Count(&count).Error
+	return count, err
+}
+
+// FindBuckets returns a page of start times, in unix seconds, of interval-long
+// buckets having rates within the range, ordered by time.
+func (r *RateRepository) FindBuckets(
+	ccFrom, ccTo string, since, until time.Time, interval time.Duration, limit, offset int,
+) ([]int64, error) {
+	var buckets []int64
+	err := r.bucketsQuery(ccFrom, ccTo, since, until, interval).Order(
+		"bucket",
+	).Limit(limit).Offset(offset).Pluck("bucket", &buckets).Error
+	return buckets, err
+}
+
+// FindRange returns rates of the currency pair created within [since, until),
+// ordered by creation time.
+func (r *RateRepository) FindRange(ccFrom, ccTo string, since, until time.Time) ([]Rate, error) {
+	var rates []Rate
+	err := r.rangeQuery(ccFrom, ccTo, since, until).Order("created, id").Find(&rates).Error
+	return rates, err
+}
+
 func NewRateRepository(db DB) *RateRepository {
 	return &RateRepository{db: db}
 }
@@ -2,20 +2,80 @@ package models_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func dec(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
 func TestRateRepositoryCreate(t *testing.T) {
 	t.Parallel()
 	db := database.SetUpTest(t, &models.Rate{})
 	repo := models.NewRateRepository(db)
-	rate := &models.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: 27.5}
+	rate := &models.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("27.5")}
 	err := repo.Create(rate)
 	require.NoError(t, err)
 	assert.NotZero(t, rate.ID)
 	assert.NotNil(t, rate.Created)
 }
+
+func TestRateRepositoryFindFirstSince(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Rate{})
+	repo := models.NewRateRepository(db)
+	now := time.Now()
+	rates := []*models.Rate{
+		{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("27.1"), Created: now.Add(-48 * time.Hour).Unix()},
+		{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("27.2"), Created: now.Add(-12 * time.Hour).Unix()},
+		{CurrencyFrom: "EUR", CurrencyTo: "UAH", Rate: dec("30.1"), Created: now.Add(-13 * time.Hour).Unix()},
+		{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("27.3"), Created: now.Unix()},
+	}
+	for _, rate := range rates {
+		require.NoError(t, repo.Create(rate))
+	}
+	// Act
+	found, err := repo.FindFirstSince("USD", "UAH", now.Add(-24*time.Hour))
+	missing, missingErr := repo.FindFirstSince("PLN", "UAH", now.Add(-24*time.Hour))
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, rates[1].ID, found.ID)
+	require.NoError(t, missingErr)
+	assert.Nil(t, missing)
+}
+
+func TestRateRepositoryBuckets(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, &models.Rate{})
+	repo := models.NewRateRepository(db)
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []time.Duration{
+		10 * time.Minute, 20 * time.Minute, 2 * time.Hour, 2*time.Hour + time.Minute, 5 * time.Hour,
+	} {
+		require.NoError(t, repo.Create(&models.Rate{
+			CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: dec("27.5"), Created: day.Add(offset).Unix(),
+		}))
+	}
+	require.NoError(t, repo.Create(&models.Rate{
+		CurrencyFrom: "EUR", CurrencyTo: "UAH", Rate: dec("30.5"), Created: day.Add(time.Hour).Unix(),
+	}))
+	since, until := day, day.Add(24*time.Hour)
+	// Act
+	total, err := repo.CountBuckets("USD", "UAH", since, until, time.Hour)
+	require.NoError(t, err)
+	page, err := repo.FindBuckets("USD", "UAH", since, until, time.Hour, 2, 1)
+	require.NoError(t, err)
+	rates, err := repo.FindRange("USD", "UAH", day.Add(2*time.Hour), day.Add(3*time.Hour))
+	require.NoError(t, err)
+	// Assert
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []int64{day.Add(2 * time.Hour).Unix(), day.Add(5 * time.Hour).Unix()}, page)
+	assert.Len(t, rates, 2)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	// Embed the timezone database, so that subscriber timezones
+	// are available in containers without one
+	_ "time/tzdata"
+)
+
+type Frequency string
+
+const (
+	// FrequencyDaily delivers notifications every day.
+	FrequencyDaily Frequency = "daily"
+	// FrequencyWeekdays delivers notifications from Monday to Friday.
+	FrequencyWeekdays Frequency = "weekdays"
+	// FrequencyWeekly delivers notifications on Mondays.
+	FrequencyWeekly Frequency = "weekly"
+)
+
+const (
+	// DeliveryTimeLayout is a format of delivery times, e.g. 09:30.
+	DeliveryTimeLayout  = "15:04"
+	DefaultDeliveryTime = "12:00"
+	DefaultTimezone     = "UTC"
+)
+
+func (f Frequency) IsValid() bool {
+	switch f {
+	case FrequencyDaily, FrequencyWeekdays, FrequencyWeekly:
+		return true
+	}
+	return false
+}
+
+// Includes tells whether notifications are delivered on the weekday.
+func (f Frequency) Includes(day time.Weekday) bool {
+	switch f {
+	case FrequencyWeekdays:
+		return day != time.Saturday && day != time.Sunday
+	case FrequencyWeekly:
+		return day == time.Monday
+	default:
+		return true
+	}
+}
+
+// Schedule is when the user receives notifications: at the delivery time
+// of the timezone on the days of the frequency.
+type Schedule struct {
+	DeliveryTime string    `gorm:"default:12:00" json:"deliveryTime"`
+	Timezone     string    `gorm:"default:UTC" json:"timezone"`
+	Frequency    Frequency `gorm:"default:daily" json:"frequency"`
+}
+
+// DefaultSchedule delivers notifications daily at noon UTC.
+func DefaultSchedule() Schedule {
+	return Schedule{
+		DeliveryTime: DefaultDeliveryTime,
+		Timezone:     DefaultTimezone,
+		Frequency:    FrequencyDaily,
+	}
+}
+
+// Validate checks that the delivery time, timezone and frequency are supported.
+func (s Schedule) Validate() error {
+	if _, err := time.Parse(DeliveryTimeLayout, s.DeliveryTime); err != nil {
+		return fmt.Errorf("invalid delivery time: %s", s.DeliveryTime)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %s", s.Timezone)
+	}
+	if !s.Frequency.IsValid() {
+		return fmt.Errorf("invalid frequency: %s", s.Frequency)
+	}
+	return nil
+}
+
+// Location returns the location of the schedule timezone, UTC if it is invalid.
+func (s Schedule) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// DeliveryOn returns the delivery moment on the day of the time in the schedule timezone,
+// or false if nothing is delivered that day. Invalid schedule parts fall back to defaults.
+func (s Schedule) DeliveryOn(t time.Time) (time.Time, bool) {
+	location := s.Location()
+	clock, err := time.Parse(DeliveryTimeLayout, s.DeliveryTime)
+	if err != nil {
+		clock, _ = time.Parse(DeliveryTimeLayout, DefaultDeliveryTime)
+	}
+	local := t.In(location)
+	if !s.Frequency.Includes(local.Weekday()) {
+		return time.Time{}, false
+	}
+	year, month, day := local.Date()
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, location), true
+}
@@ -0,0 +1,136 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		schedule    models.Schedule
+		expectError bool
+	}{
+		{name: "default", schedule: models.DefaultSchedule()},
+		{
+			name: "kyiv weekdays",
+			schedule: models.Schedule{
+				DeliveryTime: "09:30", Timezone: "Europe/Kyiv", Frequency: models.FrequencyWeekdays,
+			},
+		},
+		{
+			name: "invalid time",
+			schedule: models.Schedule{
+				DeliveryTime: "25:00", Timezone: "UTC", Frequency: models.FrequencyDaily,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid timezone",
+			schedule: models.Schedule{
+				DeliveryTime: "09:30", Timezone: "Mars/Olympus", Frequency: models.FrequencyDaily,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid frequency",
+			schedule: models.Schedule{
+				DeliveryTime: "09:30", Timezone: "UTC", Frequency: "hourly",
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			err := tc.schedule.Validate()
+			// Assert
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUserIsDue(t *testing.T) {
+	// Wednesday, 07:00 UTC is 10:00 in Kyiv
+	now := time.Date(2024, time.June, 5, 7, 0, 0, 0, time.UTC)
+	kyiv := func(deliveryTime string, frequency models.Frequency) models.Schedule {
+		return models.Schedule{
+			DeliveryTime: deliveryTime, Timezone: "Europe/Kyiv", Frequency: frequency,
+		}
+	}
+	testCases := []struct {
+		name         string
+		schedule     models.Schedule
+		lastNotified time.Time
+		expected     bool
+	}{
+		{
+			name:     "delivery time has come",
+			schedule: kyiv("10:00", models.FrequencyDaily),
+			expected: true,
+		},
+		{
+			name:     "delivery time is later",
+			schedule: kyiv("10:01", models.FrequencyDaily),
+			expected: false,
+		},
+		{
+			name:     "earlier delivery time",
+			schedule: kyiv("08:00", models.FrequencyWeekdays),
+			expected: true,
+		},
+		{
+			name:         "notified today",
+			schedule:     kyiv("08:00", models.FrequencyDaily),
+			lastNotified: now.Add(-time.Minute),
+			expected:     false,
+		},
+		{
+			name:         "notified yesterday",
+			schedule:     kyiv("08:00", models.FrequencyDaily),
+			lastNotified: now.Add(-24 * time.Hour),
+			expected:     true,
+		},
+		{
+			name:     "weekly on Mondays",
+			schedule: kyiv("08:00", models.FrequencyWeekly),
+			expected: false,
+		},
+		{
+			name: "same clock in UTC is later",
+			schedule: models.Schedule{
+				DeliveryTime: "10:00", Timezone: "UTC", Frequency: models.FrequencyDaily,
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			user := models.User{Schedule: tc.schedule}
+			if !tc.lastNotified.IsZero() {
+				user.LastNotified = tc.lastNotified.Unix()
+			}
+			// Act & Assert
+			assert.Equal(t, tc.expected, user.IsDue(now))
+		})
+	}
+}
+
+func TestUserIsDueWeekdays(t *testing.T) {
+	// Saturday
+	now := time.Date(2024, time.June, 8, 13, 0, 0, 0, time.UTC)
+	user := models.User{Schedule: models.Schedule{
+		DeliveryTime: "12:00", Timezone: "UTC", Frequency: models.FrequencyWeekdays,
+	}}
+	assert.False(t, user.IsDue(now))
+	user.Frequency = models.FrequencyDaily
+	assert.True(t, user.IsDue(now))
+}
@@ -2,15 +2,89 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+type SubscriptionStatus string
+
+const (
+	// StatusPending is a status of a subscription awaiting email confirmation.
+	StatusPending SubscriptionStatus = "pending"
+	// StatusConfirmed is a status of a subscription receiving notifications.
+	StatusConfirmed SubscriptionStatus = "confirmed"
+)
+
+type Locale string
+
+const (
+	LocaleEnglish   Locale = "en"
+	LocaleUkrainian Locale = "uk"
+	// DefaultLocale is used for users without a supported locale.
+	DefaultLocale = LocaleEnglish
+)
+
+func (l Locale) IsValid() bool {
+	switch l {
+	case LocaleEnglish, LocaleUkrainian:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	gorm.Model
 	Email string `json:"email"`
+	// Status defaults to confirmed so that users subscribed before double opt-in
+	// keep receiving notifications, new subscriptions are explicitly created pending.
+	Status SubscriptionStatus `gorm:"default:confirmed" json:"status"`
+	// Locale is a language notifications are sent in.
+	Locale Locale `gorm:"default:en" json:"locale"`
+	// Schedule is when notifications are delivered.
+	Schedule `gorm:"embedded"`
+	// LastNotified is when the user was last sent notifications, as unix seconds,
+	// so that they are mailed once per delivery.
+	LastNotified int64 `json:"-"`
+	// ConfirmationSentAt is when the user was last sent a confirmation email,
+	// as unix seconds, so that confirmations aren't resent too often.
+	ConfirmationSentAt int64 `json:"-"`
+	// Subscriptions are currency pairs the user follows.
+	Subscriptions []Subscription `json:"-"`
+}
+
+// Pairs returns currency pairs of loaded user subscriptions.
+func (u User) Pairs() []CurrencyPair {
+	pairs := make([]CurrencyPair, 0, len(u.Subscriptions))
+	for _, subscription := range u.Subscriptions {
+		pairs = append(pairs, subscription.Pair)
+	}
+	return pairs
+}
+
+func (u User) IsConfirmed() bool {
+	return u.Status == StatusConfirmed
+}
+
+// NotificationLocale returns the user locale, or the default one if it isn't supported.
+func (u User) NotificationLocale() Locale {
+	if u.Locale.IsValid() {
+		return u.Locale
+	}
+	return DefaultLocale
+}
+
+// IsDue tells whether the user is to be notified at the time, i.e. today's delivery
+// time of the schedule has come and the user hasn't been notified since then.
+// Deliveries missed on the previous days are not caught up.
+func (u User) IsDue(now time.Time) bool {
+	delivery, ok := u.Schedule.DeliveryOn(now)
+	if !ok || now.Before(delivery) {
+		return false
+	}
+	return u.LastNotified < delivery.Unix()
 }
 
 func (u User) String() string {
-	return fmt.Sprintf("User<%d, %#v>", u.ID, u.Email)
+	return fmt.Sprintf("User<%d, %#v, %s>", u.ID, u.Email, u.Status)
 }
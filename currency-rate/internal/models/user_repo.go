@@ -1,9 +1,15 @@
 package models
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+const subscriptionsPreload = "Subscriptions.Pair"
+
 type DB interface {
 	Connection() *gorm.DB
 }
@@ -16,16 +22,200 @@ func NewUserRepository(db DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// resolvePairs looks up currency pairs of subscriptions,
+// creating the missing ones, so that pairs are shared among users.
+func resolvePairs(tx *gorm.DB, subscriptions []Subscription) error {
+	for i := range subscriptions {
+		pair := subscriptions[i].Pair
+		err := tx.Where(&CurrencyPair{
+			CurrencyFrom: pair.CurrencyFrom,
+			CurrencyTo:   pair.CurrencyTo,
+		}).FirstOrCreate(&pair).Error
+		if err != nil {
+			return err
+		}
+		subscriptions[i].Pair = pair
+		subscriptions[i].PairID = pair.ID
+	}
+	return nil
+}
+
+// Create creates the user along with its subscriptions.
 func (r *UserRepository) Create(user *User) error {
-	return r.db.Connection().Create(user).Error
+	return r.db.Connection().Transaction(func(tx *gorm.DB) error {
+		if err := resolvePairs(tx, user.Subscriptions); err != nil {
+			return err
+		}
+		return tx.Create(user).Error
+	})
+}
+
+// SetPairs replaces currency pairs the user is subscribed to.
+func (r *UserRepository) SetPairs(user *User, pairs []CurrencyPair) error {
+	subscriptions := NewSubscriptions(pairs)
+	err := r.db.Connection().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&Subscription{}).Error; err != nil {
+			return err
+		}
+		if err := resolvePairs(tx, subscriptions); err != nil {
+			return err
+		}
+		for i := range subscriptions {
+			subscriptions[i].UserID = user.ID
+		}
+		if len(subscriptions) == 0 {
+			return nil
+		}
+		return tx.Create(&subscriptions).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.Subscriptions = subscriptions
+	return nil
 }
 
 func (r *UserRepository) FindAll() ([]User, error) {
 	var users []User
-	err := r.db.Connection().Find(&users).Error
+	err := r.db.Connection().Preload(subscriptionsPreload).Find(&users).Error
+	return users, err
+}
+
+// FindConfirmed returns users which have confirmed their subscription.
+func (r *UserRepository) FindConfirmed() ([]User, error) {
+	var users []User
+	err := r.db.Connection().Preload(subscriptionsPreload).Where(
+		"status = ?", StatusConfirmed,
+	).Find(&users).Error
 	return users, err
 }
 
+// FindByEmail returns the user with the email or nil if there is no such user.
+func (r *UserRepository) FindByEmail(email string) (*User, error) {
+	user := &User{}
+	err := r.db.Connection().Preload(subscriptionsPreload).Where(
+		"email = ?", email,
+	).First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Confirm marks the user subscription as confirmed.
+func (r *UserRepository) Confirm(user *User) error {
+	user.Status = StatusConfirmed
+	return r.db.Connection().Model(user).Update("status", StatusConfirmed).Error
+}
+
+// ClaimConfirmation records that the user is sent a confirmation at the time, unless one
+// was already sent within the cooldown. Only one of concurrent claims succeeds.
+func (r *UserRepository) ClaimConfirmation(
+	user *User, at time.Time, cooldown time.Duration,
+) (bool, error) {
+	result := r.db.Connection().Model(&User{}).Where(
+		"id = ? AND confirmation_sent_at <= ?", user.ID, at.Add(-cooldown).Unix(),
+	).Update("confirmation_sent_at", at.Unix())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.ConfirmationSentAt = at.Unix()
+	return true, nil
+}
+
+// ReleaseConfirmation undoes the claim made at the time, e.g. when the confirmation
+// failed to be sent, so that the user may be sent one again without waiting for the cooldown.
+// A later claim is left as is.
+func (r *UserRepository) ReleaseConfirmation(user *User, claimedAt time.Time) error {
+	err := r.db.Connection().Model(&User{}).Where(
+		"id = ? AND confirmation_sent_at = ?", user.ID, claimedAt.Unix(),
+	).Update("confirmation_sent_at", 0).Error
+	if err != nil {
+		return err
+	}
+	if user.ConfirmationSentAt == claimedAt.Unix() {
+		user.ConfirmationSentAt = 0
+	}
+	return nil
+}
+
+// dueScheduleClause matches users of the schedule not notified since its delivery moment.
+const dueScheduleClause = "(delivery_time = ? AND timezone = ? AND frequency = ?" +
+	" AND last_notified < ?)"
+
+// FindDue returns confirmed users which are to be notified at the time by their schedules,
+// as told by User.IsDue. Delivery moments are computed per distinct schedule, so that
+// users are filtered by the database rather than loaded every time.
+func (r *UserRepository) FindDue(now time.Time) ([]User, error) {
+	var schedules []Schedule
+	err := r.db.Connection().Model(&User{}).Distinct(
+		"delivery_time", "timezone", "frequency",
+	).Where("status = ?", StatusConfirmed).Scan(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	clauses := make([]string, 0, len(schedules))
+	args := make([]any, 0, len(schedules)*4)
+	for _, schedule := range schedules {
+		delivery, ok := schedule.DeliveryOn(now)
+		if !ok || now.Before(delivery) {
+			continue
+		}
+		clauses = append(clauses, dueScheduleClause)
+		args = append(
+			args, schedule.DeliveryTime, schedule.Timezone, schedule.Frequency, delivery.Unix(),
+		)
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+	var users []User
+	err = r.db.Connection().Preload(subscriptionsPreload).Where(
+		"status = ?", StatusConfirmed,
+	).Where(strings.Join(clauses, " OR "), args...).Find(&users).Error
+	return users, err
+}
+
+// MarkNotified records that the user was notified at the time and stores
+// the notification payload in the outbox within one transaction, so that
+// the user is neither mailed twice nor recorded as notified without a mail.
+func (r *UserRepository) MarkNotified(user *User, at time.Time, payload []byte) error {
+	err := r.db.Connection().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", user.ID).Update(
+			"last_notified", at.Unix(),
+		).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(newOutboxMessage(payload)).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.LastNotified = at.Unix()
+	return nil
+}
+
+// SetSchedule changes when the user is notified.
+func (r *UserRepository) SetSchedule(user *User, schedule Schedule) error {
+	user.Schedule = schedule
+	return r.db.Connection().Model(user).Select(
+		"delivery_time", "timezone", "frequency",
+	).Updates(schedule).Error
+}
+
+// SetLocale changes the language of the user notifications.
+func (r *UserRepository) SetLocale(user *User, locale Locale) error {
+	user.Locale = locale
+	return r.db.Connection().Model(user).Update("locale", locale).Error
+}
+
 func (r *UserRepository) Exists(user *User) (bool, error) {
 	var count int64
 	err := r.db.Connection().Model(&User{}).Where("email = ?", user.Email).Count(
@@ -33,3 +223,17 @@ func (r *UserRepository) Exists(user *User) (bool, error) {
 	).Error
 	return count > 0, err
 }
+
+// Delete permanently removes the user with the same email
+// along with its subscriptions and alerts.
+func (r *UserRepository) Delete(user *User) error {
+	return r.db.Connection().Transaction(func(tx *gorm.DB) error {
+		userIDs := tx.Unscoped().Model(&User{}).Select("id").Where("email = ?", user.Email)
+		for _, related := range []any{&Subscription{}, &Alert{}} {
+			if err := tx.Where("user_id IN (?)", userIDs).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("email = ?", user.Email).Delete(&User{}).Error
+	})
+}
@@ -2,6 +2,7 @@ package models_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/database"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
@@ -9,8 +10,31 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func userModels() []any {
+	return []any{
+		&models.User{}, &models.CurrencyPair{}, &models.Subscription{}, &models.Alert{},
+		&models.OutboxMessage{},
+	}
+}
+
+func pairs(p ...string) []models.CurrencyPair {
+	result := make([]models.CurrencyPair, 0, len(p))
+	for _, pair := range p {
+		result = append(result, models.CurrencyPair{CurrencyFrom: pair[:3], CurrencyTo: pair[4:]})
+	}
+	return result
+}
+
+func pairNames(user models.User) []string {
+	names := make([]string, 0, len(user.Subscriptions))
+	for _, pair := range user.Pairs() {
+		names = append(names, pair.String())
+	}
+	return names
+}
+
 func TestUserRepositoryCreate(t *testing.T) {
-	db := database.SetUpTest(t, &models.User{})
+	db := database.SetUpTest(t, userModels()...)
 	repo := models.NewUserRepository(db)
 	user := &models.User{Email: "example@gmail.com"}
 	err := repo.Create(user)
@@ -20,7 +44,7 @@ func TestUserRepositoryCreate(t *testing.T) {
 
 func TestUserRepositoryFindAll(t *testing.T) {
 	// Prepare
-	db := database.SetUpTest(t, &models.User{})
+	db := database.SetUpTest(t, userModels()...)
 	repo := models.NewUserRepository(db)
 	user1 := &models.User{Email: "example1@gmail.com"}
 	user2 := &models.User{Email: "example2@gmail.com"}
@@ -37,7 +61,7 @@ func TestUserRepositoryFindAll(t *testing.T) {
 
 func TestUserRepositoryExists(t *testing.T) {
 	// Prepare
-	db := database.SetUpTest(t, &models.User{})
+	db := database.SetUpTest(t, userModels()...)
 	repo := models.NewUserRepository(db)
 	user := &models.User{Email: "example@gmail.com"}
 	err := repo.Create(user)
@@ -51,7 +75,7 @@ func TestUserRepositoryExists(t *testing.T) {
 
 func TestUserRepositoryNotExists(t *testing.T) {
 	// Prepare
-	db := database.SetUpTest(t, &models.User{})
+	db := database.SetUpTest(t, userModels()...)
 	repo := models.NewUserRepository(db)
 	user := &models.User{Email: "example@gmail.com"}
 	// Act
@@ -60,3 +84,298 @@ func TestUserRepositoryNotExists(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, exists)
 }
+
+func TestUserRepositoryDelete(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com"}
+	err := repo.Create(user)
+	require.NoError(t, err)
+	// Act
+	err = repo.Delete(&models.User{Email: user.Email})
+	// Assert
+	require.NoError(t, err)
+	exists, err := repo.Exists(user)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUserRepositoryFindConfirmed(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	confirmed := &models.User{Email: "example1@gmail.com", Status: models.StatusConfirmed}
+	pending := &models.User{Email: "example2@gmail.com", Status: models.StatusPending}
+	require.NoError(t, repo.Create(confirmed))
+	require.NoError(t, repo.Create(pending))
+	// Act
+	users, err := repo.FindConfirmed()
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, confirmed.Email, users[0].Email)
+}
+
+func TestUserRepositoryFindByEmail(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com", Status: models.StatusPending}
+	require.NoError(t, repo.Create(user))
+	// Act
+	found, err := repo.FindByEmail(user.Email)
+	missing, missingErr := repo.FindByEmail("missing@gmail.com")
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, models.StatusPending, found.Status)
+	require.NoError(t, missingErr)
+	assert.Nil(t, missing)
+}
+
+func TestUserRepositoryConfirm(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com", Status: models.StatusPending}
+	require.NoError(t, repo.Create(user))
+	// Act
+	err := repo.Confirm(user)
+	// Assert
+	require.NoError(t, err)
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.True(t, found.IsConfirmed())
+}
+
+func TestUserRepositorySetLocale(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com"}
+	require.NoError(t, repo.Create(user))
+	require.Equal(t, models.LocaleEnglish, user.Locale)
+	// Act
+	err := repo.SetLocale(user, models.LocaleUkrainian)
+	// Assert
+	require.NoError(t, err)
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, models.LocaleUkrainian, found.Locale)
+}
+
+func TestUserRepositoryDefaultSchedule(t *testing.T) {
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com"}
+	require.NoError(t, repo.Create(user))
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultSchedule(), found.Schedule)
+}
+
+func TestUserRepositorySetSchedule(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com"}
+	require.NoError(t, repo.Create(user))
+	schedule := models.Schedule{
+		DeliveryTime: "09:30", Timezone: "Europe/Kyiv", Frequency: models.FrequencyWeekly,
+	}
+	// Act
+	err := repo.SetSchedule(user, schedule)
+	// Assert
+	require.NoError(t, err)
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, schedule, found.Schedule)
+}
+
+func TestUserRepositoryClaimConfirmation(t *testing.T) {
+	// Arrange
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com", Status: models.StatusPending}
+	require.NoError(t, repo.Create(user))
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	cooldown := 5 * time.Minute
+	// Act
+	first, firstErr := repo.ClaimConfirmation(user, now, cooldown)
+	second, secondErr := repo.ClaimConfirmation(user, now.Add(time.Minute), cooldown)
+	third, thirdErr := repo.ClaimConfirmation(user, now.Add(cooldown), cooldown)
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	require.NoError(t, thirdErr)
+	assert.True(t, first)
+	assert.False(t, second)
+	assert.True(t, third)
+	stored, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(cooldown).Unix(), stored.ConfirmationSentAt)
+}
+
+func TestUserRepositoryReleaseConfirmation(t *testing.T) {
+	// Arrange
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{Email: "example@gmail.com", Status: models.StatusPending}
+	require.NoError(t, repo.Create(user))
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	cooldown := 5 * time.Minute
+	claimed, err := repo.ClaimConfirmation(user, now, cooldown)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	// Act
+	releaseErr := repo.ReleaseConfirmation(user, now)
+	reclaimed, reclaimErr := repo.ClaimConfirmation(user, now.Add(time.Minute), cooldown)
+	staleErr := repo.ReleaseConfirmation(user, now)
+	// Assert
+	require.NoError(t, releaseErr)
+	require.NoError(t, reclaimErr)
+	require.NoError(t, staleErr)
+	assert.True(t, reclaimed)
+	stored, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute).Unix(), stored.ConfirmationSentAt)
+}
+
+func emails(users []models.User) []string {
+	result := make([]string, 0, len(users))
+	for _, user := range users {
+		result = append(result, user.Email)
+	}
+	return result
+}
+
+func TestUserRepositoryFindDue(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	// It is Wednesday, 15:30 in Kyiv
+	now := time.Date(2024, time.June, 5, 12, 30, 0, 0, time.UTC)
+	due := &models.User{Email: "due@gmail.com", Schedule: models.DefaultSchedule()}
+	later := &models.User{Email: "later@gmail.com", Schedule: models.Schedule{
+		DeliveryTime: "13:00", Timezone: "UTC", Frequency: models.FrequencyDaily,
+	}}
+	pending := &models.User{
+		Email: "pending@gmail.com", Status: models.StatusPending, Schedule: models.DefaultSchedule(),
+	}
+	kyiv := &models.User{Email: "kyiv@gmail.com", Schedule: models.Schedule{
+		DeliveryTime: "15:00", Timezone: "Europe/Kyiv", Frequency: models.FrequencyDaily,
+	}}
+	weekly := &models.User{Email: "weekly@gmail.com", Schedule: models.Schedule{
+		DeliveryTime: "09:00", Timezone: "UTC", Frequency: models.FrequencyWeekly,
+	}}
+	for _, user := range []*models.User{due, later, pending, kyiv, weekly} {
+		require.NoError(t, repo.Create(user))
+	}
+	// Act
+	found, err := repo.FindDue(now)
+	// Assert
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{due.Email, kyiv.Email}, emails(found))
+	// Notified users are not due again on the same day
+	for i := range found {
+		require.NoError(t, repo.MarkNotified(&found[i], now, []byte("notification")))
+	}
+	found, err = repo.FindDue(now.Add(10 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	found, err = repo.FindDue(now.Add(25 * time.Hour))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{due.Email, later.Email, kyiv.Email}, emails(found))
+}
+
+func TestUserRepositoryMarkNotified(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	outbox := models.NewOutboxRepository(db)
+	user := &models.User{Email: "example@gmail.com"}
+	require.NoError(t, repo.Create(user))
+	now := time.Now()
+	// Act
+	err := repo.MarkNotified(user, now, []byte("notification"))
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix(), user.LastNotified)
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix(), found.LastNotified)
+	messages, err := outbox.FindPending(now, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "notification", messages[0].Payload)
+}
+
+func TestUserRepositoryCreateWithPairs(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user1 := &models.User{
+		Email:         "example1@gmail.com",
+		Subscriptions: models.NewSubscriptions(pairs("USD/UAH", "EUR/UAH")),
+	}
+	user2 := &models.User{
+		Email:         "example2@gmail.com",
+		Subscriptions: models.NewSubscriptions(pairs("EUR/UAH")),
+	}
+	// Act
+	err1 := repo.Create(user1)
+	err2 := repo.Create(user2)
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	found, err := repo.FindByEmail(user1.Email)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"USD/UAH", "EUR/UAH"}, pairNames(*found))
+	// Pairs are shared between users
+	assert.Equal(t, user1.Subscriptions[1].PairID, user2.Subscriptions[0].PairID)
+	var pairCount int64
+	require.NoError(t, db.Connection().Model(&models.CurrencyPair{}).Count(&pairCount).Error)
+	assert.Equal(t, int64(2), pairCount)
+}
+
+func TestUserRepositorySetPairs(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{
+		Email:         "example@gmail.com",
+		Subscriptions: models.NewSubscriptions(pairs("USD/UAH", "EUR/UAH")),
+	}
+	require.NoError(t, repo.Create(user))
+	// Act
+	err := repo.SetPairs(user, pairs("PLN/UAH"))
+	// Assert
+	require.NoError(t, err)
+	found, err := repo.FindByEmail(user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PLN/UAH"}, pairNames(*found))
+}
+
+func TestUserRepositoryDeleteSubscriptions(t *testing.T) {
+	// Prepare
+	db := database.SetUpTest(t, userModels()...)
+	repo := models.NewUserRepository(db)
+	user := &models.User{
+		Email:         "example@gmail.com",
+		Subscriptions: models.NewSubscriptions(pairs("USD/UAH")),
+	}
+	require.NoError(t, repo.Create(user))
+	alert := &models.Alert{UserID: user.ID, CurrencyFrom: "USD", CurrencyTo: "UAH"}
+	require.NoError(t, models.NewAlertRepository(db).Create(alert))
+	// Act
+	err := repo.Delete(&models.User{Email: user.Email})
+	// Assert
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Connection().Model(&models.Subscription{}).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, db.Connection().Model(&models.Alert{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
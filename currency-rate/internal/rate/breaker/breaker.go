@@ -0,0 +1,243 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
+)
+
+// ErrOpen is returned when the provider is skipped because its breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State string
+
+const (
+	// StateClosed passes requests to the provider, counting their failures.
+	StateClosed State = "closed"
+	// StateOpen skips the provider until the cool-down passes.
+	StateOpen State = "open"
+	// StateHalfOpen lets a few probe requests decide whether the provider recovered.
+	StateHalfOpen State = "half-open"
+)
+
+// Health is a snapshot of the provider state and of the requests within the current window.
+type Health struct {
+	Provider string
+	State    State
+	// LastSuccess is zero if the provider never succeeded.
+	LastSuccess time.Time
+	// LastError is nil if the provider never failed.
+	LastError error
+	Requests  int
+	Failures  int
+	ErrorRate float64
+}
+
+// Fetcher is a RateFetcher decorator implementing the circuit breaker pattern,
+// so that an unavailable provider is skipped instead of being waited for on every request.
+// While open it skips straight to the fetcher set with SetNext, which only works
+// if the fallback is set on the breaker: a fallback set on the wrapped fetcher
+// would be counted as the provider succeeding. Unsupported currencies and requests
+// cancelled by the caller aren't counted as provider failures.
+//
+// Example usage:
+//
+//	nbu := breaker.NewFetcher("nbu", fetchers.NewNBURateFetcher(), config.NewFromEnv())
+//	beacon := breaker.NewFetcher("currencybeacon", beaconFetcher, config.NewFromEnv())
+//	beacon.SetNext(nbu)
+//	rate, err := beacon.FetchRate(context.Background(), "USD", "UAH")
+type Fetcher struct {
+	name    string
+	fetcher fetchers.RateFetcher
+	config  config.Config
+	next    fetchers.RateFetcher
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	lastSuccess time.Time
+	lastError   error
+}
+
+// admission is the state a request was passed to the provider in,
+// so that its outcome is recorded against that state rather than the current one.
+type admission struct {
+	generation uint64
+	probe      bool
+}
+
+// allow tells whether the request may be passed to the provider,
+// moving the open breaker to half-open once the cool-down passes.
+func (f *Fetcher) allow(now time.Time) (admission, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch f.state {
+	case StateOpen:
+		if now.Sub(f.openedAt) < f.config.CoolDown {
+			return admission{}, false
+		}
+		f.transition(StateHalfOpen)
+		f.probes = 0
+		slog.Info("circuit breaker half-open", slog.String("provider", f.name))
+		fallthrough
+	case StateHalfOpen:
+		if f.probes >= f.config.HalfOpenRequests {
+			return admission{}, false
+		}
+		f.probes++
+		return admission{generation: f.generation, probe: true}, true
+	default:
+		if now.Sub(f.windowStart) >= f.config.Window {
+			f.resetWindow(now)
+		}
+	}
+	return admission{generation: f.generation}, true
+}
+
+// transition moves the breaker to the state, outdating the requests in flight.
+func (f *Fetcher) transition(state State) {
+	f.state = state
+	f.generation++
+}
+
+func (f *Fetcher) resetWindow(now time.Time) {
+	f.windowStart = now
+	f.requests = 0
+	f.failures = 0
+}
+
+func (f *Fetcher) open(now time.Time) {
+	f.transition(StateOpen)
+	f.openedAt = now
+	slog.Warn(
+		"circuit breaker opened",
+		slog.String("provider", f.name),
+		slog.Int("requests", f.requests),
+		slog.Int("failures", f.failures),
+		slog.Any("error", f.lastError),
+	)
+}
+
+// record counts the outcome of the request passed to the provider.
+// Outcomes of requests admitted before the state changed are ignored,
+// e.g. a probe finishing after another probe has already closed the breaker.
+func (f *Fetcher) record(ctx context.Context, now time.Time, admitted admission, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if admitted.generation != f.generation {
+		return
+	}
+	if admitted.probe {
+		f.probes--
+	}
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up on the request, it tells nothing about the provider
+		return
+	}
+	f.requests++
+	if err == nil || errors.Is(err, rate.ErrUnsupportedCurrency) {
+		f.lastSuccess = now
+		if admitted.probe {
+			f.transition(StateClosed)
+			f.resetWindow(now)
+			slog.Info("circuit breaker closed", slog.String("provider", f.name))
+		}
+		return
+	}
+	f.failures++
+	f.lastError = err
+	if admitted.probe {
+		f.open(now)
+		return
+	}
+	if f.requests >= f.config.MinRequests &&
+		float64(f.failures)/float64(f.requests) >= f.config.FailureRatio {
+		f.open(now)
+	}
+}
+
+// Health returns the current state of the provider.
+func (f *Fetcher) Health() Health {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	health := Health{
+		Provider:    f.name,
+		State:       f.state,
+		LastSuccess: f.lastSuccess,
+		LastError:   f.lastError,
+		Requests:    f.requests,
+		Failures:    f.failures,
+	}
+	if f.requests > 0 {
+		health.ErrorRate = float64(f.failures) / float64(f.requests)
+	}
+	return health
+}
+
+func (f *Fetcher) fallback(ctx context.Context, ccFrom, ccTo string, err error) (rate.Rate, error) {
+	if f.next == nil {
+		return rate.Rate{}, err
+	}
+	slog.Warn(
+		"falling back to next fetcher",
+		slog.String("provider", f.name),
+		slog.Any("next", f.next),
+		slog.Any("error", err),
+	)
+	return f.next.FetchRate(ctx, ccFrom, ccTo)
+}
+
+func (f *Fetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	admitted, ok := f.allow(time.Now())
+	if !ok {
+		return f.fallback(ctx, ccFrom, ccTo, fmt.Errorf("%w: %s", ErrOpen, f.name))
+	}
+	result, err := f.fetcher.FetchRate(ctx, ccFrom, ccTo)
+	f.record(ctx, time.Now(), admitted, err)
+	if err != nil {
+		return f.fallback(ctx, ccFrom, ccTo, err)
+	}
+	return result, nil
+}
+
+func (f *Fetcher) SetNext(next fetchers.RateFetcher) {
+	f.next = next
+}
+
+func (f *Fetcher) String() string {
+	return fmt.Sprintf("CircuitBreaker{%s}", f.name)
+}
+
+// NewFetcher wraps the fetcher of the named provider in a closed circuit breaker.
+func NewFetcher(name string, fetcher fetchers.RateFetcher, config config.Config) *Fetcher {
+	return &Fetcher{
+		name:        name,
+		fetcher:     fetcher,
+		config:      config,
+		state:       StateClosed,
+		windowStart: time.Now(),
+	}
+}
+
+// Monitor reports the health of the providers behind the circuit breakers.
+type Monitor []*Fetcher
+
+func (m Monitor) Health() []Health {
+	health := make([]Health, 0, len(m))
+	for _, fetcher := range m {
+		health = append(health, fetcher.Health())
+	}
+	return health
+}
@@ -0,0 +1,277 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRateFetcher struct {
+	mock.Mock
+}
+
+func (m *mockRateFetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	args := m.Called(ctx, ccFrom, ccTo)
+	return args.Get(0).(rate.Rate), args.Error(1)
+}
+
+func (m *mockRateFetcher) SetNext(fetchers.RateFetcher) {}
+
+var errUnavailable = errors.New("unavailable")
+
+func usdRate(value string) rate.Rate {
+	return rate.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString(value)}
+}
+
+func testConfig(coolDown time.Duration) config.Config {
+	return config.Config{
+		FailureRatio:     0.5,
+		MinRequests:      2,
+		Window:           time.Hour,
+		CoolDown:         coolDown,
+		HalfOpenRequests: 1,
+	}
+}
+
+func fetchTimes(f *breaker.Fetcher, times int) {
+	for range times {
+		_, _ = f.FetchRate(context.Background(), "USD", "UAH")
+	}
+}
+
+func TestFetcherOpens(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{}, errUnavailable)
+	fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(time.Hour))
+	// Act
+	fetchTimes(fetcher, 2)
+	_, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.ErrorIs(t, err, breaker.ErrOpen)
+	mockFetcher.AssertNumberOfCalls(t, "FetchRate", 2)
+	health := fetcher.Health()
+	assert.Equal(t, breaker.StateOpen, health.State)
+	assert.Equal(t, "beacon", health.Provider)
+	assert.ErrorIs(t, health.LastError, errUnavailable)
+	assert.InDelta(t, 1.0, health.ErrorRate, 0.001)
+}
+
+func TestFetcherStaysClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+	}{
+		{
+			name: "below-min-requests",
+			errs: []error{errUnavailable},
+		},
+		{
+			name: "below-ratio",
+			errs: []error{nil, nil, errUnavailable},
+		},
+		{
+			name: "unsupported-currency",
+			errs: []error{
+				fmt.Errorf("%w: XYZ", rate.ErrUnsupportedCurrency),
+				fmt.Errorf("%w: XYZ", rate.ErrUnsupportedCurrency),
+				fmt.Errorf("%w: XYZ", rate.ErrUnsupportedCurrency),
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockFetcher := new(mockRateFetcher)
+			for _, err := range tc.errs {
+				mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate("41.1"), err).Once()
+			}
+			fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(time.Hour))
+			// Act
+			fetchTimes(fetcher, len(tc.errs))
+			// Assert
+			assert.Equal(t, breaker.StateClosed, fetcher.Health().State)
+			mockFetcher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetcherCancelledNotCounted(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{}, context.Canceled)
+	fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Act
+	for range 3 {
+		_, _ = fetcher.FetchRate(ctx, "USD", "UAH")
+	}
+	// Assert
+	health := fetcher.Health()
+	assert.Equal(t, breaker.StateClosed, health.State)
+	assert.Zero(t, health.Requests)
+}
+
+func TestFetcherFallsBack(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{}, errUnavailable)
+	nextFetcher := new(mockRateFetcher)
+	nextFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate("41.5"), nil)
+	fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(time.Hour))
+	fetcher.SetNext(nextFetcher)
+	// Act
+	fetchTimes(fetcher, 2)
+	result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "41.5", result.Rate.String())
+	mockFetcher.AssertNumberOfCalls(t, "FetchRate", 2)
+	nextFetcher.AssertNumberOfCalls(t, "FetchRate", 3)
+}
+
+func TestFetcherHalfOpen(t *testing.T) {
+	tests := []struct {
+		name          string
+		probeErr      error
+		expectedState breaker.State
+	}{
+		{
+			name:          "recovered",
+			expectedState: breaker.StateClosed,
+		},
+		{
+			name:          "still-failing",
+			probeErr:      errUnavailable,
+			expectedState: breaker.StateOpen,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockFetcher := new(mockRateFetcher)
+			mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").
+				Return(rate.Rate{}, errUnavailable).Twice()
+			mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").
+				Return(usdRate("41.1"), tc.probeErr).Once()
+			fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(10*time.Millisecond))
+			fetchTimes(fetcher, 2)
+			require.Equal(t, breaker.StateOpen, fetcher.Health().State)
+			time.Sleep(20 * time.Millisecond)
+			// Act
+			_, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+			// Assert
+			assert.ErrorIs(t, err, tc.probeErr)
+			assert.Equal(t, tc.expectedState, fetcher.Health().State)
+			mockFetcher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetcherHalfOpenProbes(t *testing.T) {
+	// Arrange
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").
+		Return(rate.Rate{}, errUnavailable).Twice()
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Run(func(mock.Arguments) {
+		close(probing)
+		<-release
+	}).Return(usdRate("41.1"), nil).Once()
+	fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(10*time.Millisecond))
+	fetchTimes(fetcher, 2)
+	time.Sleep(20 * time.Millisecond)
+	go fetchTimes(fetcher, 1)
+	<-probing
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	close(release)
+	// Assert
+	require.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, breaker.StateHalfOpen, fetcher.Health().State)
+}
+
+// blockingCall expects a provider call returning the rate once released,
+// signalling when the call started.
+func blockingCall(m *mockRateFetcher, result rate.Rate) (<-chan struct{}, chan<- struct{}) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m.On("FetchRate", mock.Anything, "USD", "UAH").Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(result, nil).Once()
+	return started, release
+}
+
+func fetchAsync(f *breaker.Fetcher) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fetchTimes(f, 1)
+	}()
+	return done
+}
+
+func TestFetcherStaleOutcomeIgnored(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	staleStarted, releaseStale := blockingCall(mockFetcher, usdRate("41.1"))
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").
+		Return(rate.Rate{}, errUnavailable).Twice()
+	probeStarted, releaseProbe := blockingCall(mockFetcher, usdRate("41.2"))
+	fetcher := breaker.NewFetcher("beacon", mockFetcher, testConfig(10*time.Millisecond))
+	staleDone := fetchAsync(fetcher)
+	<-staleStarted
+	fetchTimes(fetcher, 2)
+	require.Equal(t, breaker.StateOpen, fetcher.Health().State)
+	time.Sleep(20 * time.Millisecond)
+	probeDone := fetchAsync(fetcher)
+	<-probeStarted
+	// Act
+	close(releaseStale)
+	<-staleDone
+	// Assert
+	assert.Equal(t, breaker.StateHalfOpen, fetcher.Health().State)
+	close(releaseProbe)
+	<-probeDone
+	assert.Equal(t, breaker.StateClosed, fetcher.Health().State)
+	mockFetcher.AssertExpectations(t)
+}
+
+func TestMonitorHealth(t *testing.T) {
+	// Arrange
+	okFetcher := new(mockRateFetcher)
+	okFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate("41.1"), nil)
+	failingFetcher := new(mockRateFetcher)
+	failingFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{}, errUnavailable)
+	ok := breaker.NewFetcher("nbu", okFetcher, testConfig(time.Hour))
+	failing := breaker.NewFetcher("beacon", failingFetcher, testConfig(time.Hour))
+	fetchTimes(ok, 1)
+	fetchTimes(failing, 1)
+	monitor := breaker.Monitor{failing, ok}
+	// Act
+	health := monitor.Health()
+	// Assert
+	require.Len(t, health, 2)
+	assert.Equal(t, "beacon", health[0].Provider)
+	assert.True(t, health[0].LastSuccess.IsZero())
+	assert.InDelta(t, 1.0, health[0].ErrorRate, 0.001)
+	assert.Equal(t, "nbu", health[1].Provider)
+	assert.False(t, health[1].LastSuccess.IsZero())
+	assert.NoError(t, health[1].LastError)
+	assert.Equal(t, 1, health[1].Requests)
+	assert.Zero(t, health[1].ErrorRate)
+}
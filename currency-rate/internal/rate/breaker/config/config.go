@@ -0,0 +1,95 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 5
+	defaultWindow           = time.Minute
+	defaultCoolDown         = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+type Config struct {
+	// FailureRatio is the share of failed requests within the window,
+	// from 0 exclusive to 1 inclusive, at which the breaker opens.
+	FailureRatio float64
+	// MinRequests is the least number of requests within the window
+	// before the failure ratio is considered.
+	MinRequests int
+	// Window is how long request outcomes are counted before being reset.
+	Window time.Duration
+	// CoolDown is how long the open breaker skips the provider before probing it.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of concurrent probe requests of the half-open breaker.
+	HalfOpenRequests int
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func intOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func ratioOrDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio <= 0 || ratio > 1 {
+		slog.Error(
+			"invalid ratio, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return ratio
+}
+
+func NewFromEnv() Config {
+	return Config{
+		FailureRatio:     ratioOrDefault("RATE_BREAKER_FAILURE_RATIO", defaultFailureRatio),
+		MinRequests:      intOrDefault("RATE_BREAKER_MIN_REQUESTS", defaultMinRequests),
+		Window:           durationOrDefault("RATE_BREAKER_WINDOW", defaultWindow),
+		CoolDown:         durationOrDefault("RATE_BREAKER_COOLDOWN", defaultCoolDown),
+		HalfOpenRequests: intOrDefault("RATE_BREAKER_HALF_OPEN_REQUESTS", defaultHalfOpenRequests),
+	}
+}
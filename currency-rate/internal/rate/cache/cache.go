@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/cache/config"
+	"golang.org/x/sync/singleflight"
+)
+
+type RateFetcher interface {
+	FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error)
+}
+
+type entry struct {
+	rate    rate.Rate
+	fetched time.Time
+}
+
+// Fetcher is a RateFetcher decorator caching rates of the wrapped fetcher
+// per currency pair. Concurrent requests of the same pair share a single
+// upstream fetch, and expired rates are served as stale while the upstream fails.
+//
+// Example usage:
+//
+//	fetcher := cache.NewFetcher(fetchers.NewNBURateFetcher(), config.NewFromEnv())
+//	rate, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+type Fetcher struct {
+	fetcher RateFetcher
+	config  config.Config
+	group   singleflight.Group
+	mu      sync.RWMutex
+	entries map[string]entry
+}
+
+func key(ccFrom, ccTo string) string {
+	return ccFrom + "/" + ccTo
+}
+
+func (f *Fetcher) get(key string) (entry, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	e, ok := f.entries[key]
+	return e, ok
+}
+
+func (f *Fetcher) set(key string, r rate.Rate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[key] = entry{rate: r, fetched: time.Now()}
+}
+
+// refresh fetches the rate from the upstream once for all concurrent callers.
+// The shared fetch isn't bound to the context of any single caller.
+// Only the caller whose fetch was run gets a miss, the ones joining it get a hit.
+func (f *Fetcher) refresh(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	k := key(ccFrom, ccTo)
+	// Set by the flight before its result is sent, so it is safe to read after receiving it
+	fetched := false
+	ch := f.group.DoChan(k, func() (any, error) {
+		fetched = true
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), f.config.FetchTimeout)
+		defer cancel()
+		r, err := f.fetcher.FetchRate(fetchCtx, ccFrom, ccTo)
+		if err != nil {
+			return rate.Rate{}, err
+		}
+		f.set(k, r)
+		return r, nil
+	})
+	select {
+	case <-ctx.Done():
+		return rate.Rate{}, ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return rate.Rate{}, result.Err
+		}
+		r := result.Val.(rate.Rate)
+		r.Cache = rate.CacheHit
+		if fetched {
+			r.Cache = rate.CacheMiss
+		}
+		return r, nil
+	}
+}
+
+func (f *Fetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	ttl := f.config.TTLFor(ccFrom, ccTo)
+	if ttl <= 0 {
+		return f.fetcher.FetchRate(ctx, ccFrom, ccTo)
+	}
+	cached, ok := f.get(key(ccFrom, ccTo))
+	age := time.Since(cached.fetched)
+	if ok && age < ttl {
+		cached.rate.Cache = rate.CacheHit
+		return cached.rate, nil
+	}
+	r, err := f.refresh(ctx, ccFrom, ccTo)
+	if err == nil {
+		return r, nil
+	}
+	if ok && age < ttl+f.config.StaleTTL {
+		slog.Warn(
+			"serving stale rate",
+			slog.Any("rate", cached.rate), slog.Any("age", age), slog.Any("error", err),
+		)
+		cached.rate.Cache = rate.CacheStale
+		return cached.rate, nil
+	}
+	return rate.Rate{}, fmt.Errorf("cache refreshing: %w", err)
+}
+
+func NewFetcher(fetcher RateFetcher, config config.Config) *Fetcher {
+	return &Fetcher{
+		fetcher: fetcher,
+		config:  config,
+		entries: make(map[string]entry),
+	}
+}
@@ -0,0 +1,185 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/cache"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/cache/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRateFetcher struct {
+	mock.Mock
+}
+
+func (m *mockRateFetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	args := m.Called(ctx, ccFrom, ccTo)
+	return args.Get(0).(rate.Rate), args.Error(1)
+}
+
+func usdRate(value float32) rate.Rate {
+	return rate.Rate{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.NewFromFloat32(value)}
+}
+
+func testConfig(ttl time.Duration) config.Config {
+	return config.Config{TTL: ttl, StaleTTL: time.Hour, FetchTimeout: time.Second}
+}
+
+func TestFetchRateHit(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil).Once()
+	fetcher := cache.NewFetcher(mockFetcher, testConfig(time.Hour))
+	// Act
+	first, firstErr := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	second, secondErr := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, rate.CacheMiss, first.Cache)
+	assert.Equal(t, rate.CacheHit, second.Cache)
+	assert.Equal(t, "41", second.Rate.String())
+	mockFetcher.AssertNumberOfCalls(t, "FetchRate", 1)
+}
+
+func TestFetchRateExpired(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil).Once()
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(42), nil).Once()
+	fetcher := cache.NewFetcher(mockFetcher, testConfig(10*time.Millisecond))
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, rate.CacheMiss, result.Cache)
+	assert.Equal(t, "42", result.Rate.String())
+}
+
+func TestFetchRatePairTTL(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil)
+	cfg := testConfig(time.Hour)
+	cfg.PairTTL = map[string]time.Duration{"USD/UAH": 0}
+	fetcher := cache.NewFetcher(mockFetcher, cfg)
+	// Act
+	_, _ = fetcher.FetchRate(context.Background(), "USD", "UAH")
+	result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, result.Cache)
+	mockFetcher.AssertNumberOfCalls(t, "FetchRate", 2)
+}
+
+func TestFetchRateStale(t *testing.T) {
+	testCases := []struct {
+		name          string
+		staleTTL      time.Duration
+		expectedError bool
+	}{
+		{name: "stale", staleTTL: time.Hour},
+		{name: "too-old", staleTTL: time.Millisecond, expectedError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockFetcher := new(mockRateFetcher)
+			mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil).Once()
+			mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(
+				rate.Rate{}, errors.New("upstream is down"),
+			)
+			cfg := testConfig(10 * time.Millisecond)
+			cfg.StaleTTL = tc.staleTTL
+			fetcher := cache.NewFetcher(mockFetcher, cfg)
+			_, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+			require.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+			// Act
+			result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+			// Assert
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, rate.CacheStale, result.Cache)
+			assert.Equal(t, "41", result.Rate.String())
+		})
+	}
+}
+
+func TestFetchRateUnsupported(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "XYZ", "UAH").Return(
+		rate.Rate{}, rate.ErrUnsupportedCurrency,
+	)
+	fetcher := cache.NewFetcher(mockFetcher, testConfig(time.Hour))
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "XYZ", "UAH")
+	// Assert
+	require.ErrorIs(t, err, rate.ErrUnsupportedCurrency)
+}
+
+func TestFetchRateSingleFlight(t *testing.T) {
+	// Arrange
+	const callers = 10
+	release := make(chan time.Time)
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil).
+		WaitUntil(release)
+	fetcher := cache.NewFetcher(mockFetcher, testConfig(time.Hour))
+	// Act
+	var wg sync.WaitGroup
+	results := make([]rate.Rate, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = fetcher.FetchRate(context.Background(), "USD", "UAH")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	// Assert
+	mockFetcher.AssertNumberOfCalls(t, "FetchRate", 1)
+	misses := 0
+	for _, result := range results {
+		assert.Equal(t, "41", result.Rate.String())
+		if result.Cache == rate.CacheMiss {
+			misses++
+		} else {
+			assert.Equal(t, rate.CacheHit, result.Cache)
+		}
+	}
+	assert.Equal(t, 1, misses)
+}
+
+func TestFetchRateCallerCanceled(t *testing.T) {
+	// Arrange
+	release := make(chan time.Time)
+	defer close(release)
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate(41), nil).
+		WaitUntil(release)
+	fetcher := cache.NewFetcher(mockFetcher, testConfig(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// Act
+	_, err := fetcher.FetchRate(ctx, "USD", "UAH")
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
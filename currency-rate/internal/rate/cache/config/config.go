@@ -0,0 +1,87 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTTL          = 5 * time.Minute
+	defaultStaleTTL     = 24 * time.Hour
+	defaultFetchTimeout = 5 * time.Second
+	pairsSeparator      = ","
+	ttlSeparator        = "="
+)
+
+type Config struct {
+	// TTL is a lifetime of cached rates of pairs not listed in PairTTL.
+	// Non-positive value disables caching.
+	TTL time.Duration
+	// PairTTL overrides TTL for pairs in the "USD/UAH" format.
+	PairTTL map[string]time.Duration
+	// StaleTTL is how long after expiration a rate may still be served
+	// when the upstream fetchers fail.
+	StaleTTL time.Duration
+	// FetchTimeout limits an upstream fetch shared by concurrent requests.
+	FetchTimeout time.Duration
+}
+
+// TTLFor returns the lifetime of cached rates of the currency pair.
+func (c Config) TTLFor(ccFrom, ccTo string) time.Duration {
+	if ttl, ok := c.PairTTL[ccFrom+"/"+ccTo]; ok {
+		return ttl
+	}
+	return c.TTL
+}
+
+func parseDuration(key, value string, defaultValue time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return parseDuration(key, value, defaultValue)
+}
+
+// pairTTLs parses per pair lifetimes like "USD/UAH=1h,EUR/UAH=10m".
+func pairTTLs(key string, defaultValue time.Duration) map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	value := os.Getenv(key)
+	if value == "" {
+		return ttls
+	}
+	for _, item := range strings.Split(value, pairsSeparator) {
+		pair, ttl, found := strings.Cut(strings.TrimSpace(item), ttlSeparator)
+		if !found {
+			slog.Error("invalid pair TTL, skipping", slog.Any("key", key), slog.Any("value", item))
+			continue
+		}
+		ttls[strings.ToUpper(pair)] = parseDuration(key, ttl, defaultValue)
+	}
+	return ttls
+}
+
+func NewFromEnv() Config {
+	ttl := durationOrDefault("RATE_CACHE_TTL", defaultTTL)
+	return Config{
+		TTL:          ttl,
+		PairTTL:      pairTTLs("RATE_CACHE_PAIR_TTL", ttl),
+		StaleTTL:     durationOrDefault("RATE_CACHE_STALE_TTL", defaultStaleTTL),
+		FetchTimeout: durationOrDefault("RATE_CACHE_FETCH_TIMEOUT", defaultFetchTimeout),
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Strategy string
+
+const (
+	// StrategyChain queries providers one by one until one succeeds.
+	StrategyChain Strategy = "chain"
+	// StrategyFirst queries providers concurrently and takes the first success.
+	StrategyFirst Strategy = "first"
+	// StrategyMedian queries providers concurrently and takes the median of the successes.
+	StrategyMedian Strategy = "median"
+)
+
+const currencyCodeLength = 3
+
+const (
+	defaultStrategy  = StrategyChain
+	defaultQuorum    = 2
+	defaultTolerance = "0.01"
+	defaultPivot     = "USD"
+	// defaultNBURefreshInterval is how long the NBU catalogue of rates is reused.
+	defaultNBURefreshInterval = time.Hour
+)
+
+type Config struct {
+	// Strategy is how rates are fetched from multiple providers.
+	Strategy Strategy
+	// Quorum is the least number of providers that must respond for the median.
+	Quorum int
+	// Tolerance is the largest allowed deviation of a provider rate from the median,
+	// as a fraction of it, e.g. 0.01 for 1%. Zero disables the check.
+	Tolerance decimal.Decimal
+	// Pivot is the currency cross rates are derived through by providers
+	// publishing rates against any currency. NBU rates are always derived through UAH.
+	Pivot string
+	// NBURefreshInterval is how long rates of all currencies loaded from the NBU are reused.
+	NBURefreshInterval time.Duration
+}
+
+func (s Strategy) IsValid() bool {
+	switch s {
+	case StrategyChain, StrategyFirst, StrategyMedian:
+		return true
+	}
+	return false
+}
+
+func strategyOrDefault(key string, defaultValue Strategy) Strategy {
+	value := Strategy(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+	if !value.IsValid() {
+		slog.Error(
+			"invalid strategy, using default value",
+			slog.Any("key", key),
+			slog.Any("value", value),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return value
+}
+
+func intOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func decimalOrDefault(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := decimal.NewFromString(value)
+	if err != nil || number.IsNegative() {
+		slog.Error(
+			"invalid decimal, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func currencyOrDefault(key, defaultValue string) string {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv(key)))
+	if value == "" {
+		return defaultValue
+	}
+	if len(value) != currencyCodeLength {
+		slog.Error(
+			"invalid currency code, using default value",
+			slog.Any("key", key),
+			slog.Any("value", value),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return value
+}
+
+func NewFromEnv() Config {
+	return Config{
+		Strategy: strategyOrDefault("RATE_FETCH_STRATEGY", defaultStrategy),
+		Quorum:   intOrDefault("RATE_FETCH_QUORUM", defaultQuorum),
+		Tolerance: decimalOrDefault(
+			"RATE_FETCH_TOLERANCE", decimal.RequireFromString(defaultTolerance),
+		),
+		Pivot: currencyOrDefault("RATE_CROSS_PIVOT", defaultPivot),
+		NBURefreshInterval: durationOrDefault(
+			"RATE_NBU_REFRESH_INTERVAL", defaultNBURefreshInterval,
+		),
+	}
+}
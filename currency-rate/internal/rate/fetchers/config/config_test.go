@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	tests := []struct {
+		name              string
+		env               map[string]string
+		expectedStrategy  config.Strategy
+		expectedQuorum    int
+		expectedTolerance string
+		expectedPivot     string
+		expectedRefresh   time.Duration
+	}{
+		{
+			name:              "defaults",
+			env:               map[string]string{},
+			expectedStrategy:  config.StrategyChain,
+			expectedQuorum:    2,
+			expectedTolerance: "0.01",
+			expectedPivot:     "USD",
+			expectedRefresh:   time.Hour,
+		},
+		{
+			name: "set",
+			env: map[string]string{
+				"RATE_FETCH_STRATEGY":       "median",
+				"RATE_FETCH_QUORUM":         "3",
+				"RATE_FETCH_TOLERANCE":      "0.005",
+				"RATE_CROSS_PIVOT":          "eur",
+				"RATE_NBU_REFRESH_INTERVAL": "30m",
+			},
+			expectedStrategy:  config.StrategyMedian,
+			expectedQuorum:    3,
+			expectedTolerance: "0.005",
+			expectedPivot:     "EUR",
+			expectedRefresh:   30 * time.Minute,
+		},
+		{
+			name: "invalid",
+			env: map[string]string{
+				"RATE_FETCH_STRATEGY":       "fastest",
+				"RATE_FETCH_QUORUM":         "0",
+				"RATE_FETCH_TOLERANCE":      "-1",
+				"RATE_CROSS_PIVOT":          "EURO",
+				"RATE_NBU_REFRESH_INTERVAL": "hourly",
+			},
+			expectedStrategy:  config.StrategyChain,
+			expectedQuorum:    2,
+			expectedTolerance: "0.01",
+			expectedPivot:     "USD",
+			expectedRefresh:   time.Hour,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			for _, key := range []string{
+				"RATE_FETCH_STRATEGY", "RATE_FETCH_QUORUM", "RATE_FETCH_TOLERANCE",
+				"RATE_CROSS_PIVOT", "RATE_NBU_REFRESH_INTERVAL",
+			} {
+				t.Setenv(key, tc.env[key])
+			}
+			// Act
+			cfg := config.NewFromEnv()
+			// Assert
+			assert.Equal(t, tc.expectedStrategy, cfg.Strategy)
+			assert.Equal(t, tc.expectedQuorum, cfg.Quorum)
+			assert.Equal(t, tc.expectedTolerance, cfg.Tolerance.String())
+			assert.Equal(t, tc.expectedPivot, cfg.Pivot)
+			assert.Equal(t, tc.expectedRefresh, cfg.NBURefreshInterval)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/shopspring/decimal"
+)
+
+// crossPrecision is the number of decimal places kept in derived rates.
+const crossPrecision = 12
+
+// CrossFetcher is a RateFetcher decorator deriving rates of the pairs the provider
+// doesn't publish from two legs through the pivot currency, e.g. EUR/USD from
+// EUR/UAH and UAH/USD of the NBU. A leg published only in the opposite direction
+// is inverted. The legs used are recorded in the rate.
+// The fetcher set with SetNext is tried only once neither the direct nor the cross rate
+// could be fetched, so it belongs on the CrossFetcher: set on the wrapped fetcher, it
+// would answer every leg the provider doesn't publish before a cross rate is tried.
+//
+// Example usage:
+//
+//	fetcher := NewCrossFetcher(NewNBURateFetcher(), NBUPivot)
+//	rate, err := fetcher.FetchRate(context.Background(), "EUR", "USD")
+//	fmt.Println(rate, rate.Legs)
+type CrossFetcher struct {
+	fetcher RateFetcher
+	pivot   string
+	next    RateFetcher
+}
+
+func legOf(r rate.Rate, inverted bool) rate.Leg {
+	return rate.Leg{
+		CurrencyFrom: r.CurrencyFrom,
+		CurrencyTo:   r.CurrencyTo,
+		Rate:         r.Rate,
+		Provider:     r.Provider,
+		Inverted:     inverted,
+	}
+}
+
+// leg fetches the rate of the pair, or the reciprocal of the opposite pair
+// if the provider doesn't support the pair.
+func (c *CrossFetcher) leg(ctx context.Context, ccFrom, ccTo string) (rate.Rate, rate.Leg, error) {
+	result, err := c.fetcher.FetchRate(ctx, ccFrom, ccTo)
+	if err == nil {
+		return result, legOf(result, false), nil
+	}
+	if !errors.Is(err, rate.ErrUnsupportedCurrency) {
+		return rate.Rate{}, rate.Leg{}, err
+	}
+	opposite, oppositeErr := c.fetcher.FetchRate(ctx, ccTo, ccFrom)
+	if oppositeErr != nil {
+		return rate.Rate{}, rate.Leg{}, fmt.Errorf("%w, opposite pair: %w", err, oppositeErr)
+	}
+	if opposite.Rate.IsZero() {
+		return rate.Rate{}, rate.Leg{}, fmt.Errorf("inverting zero rate of %s/%s", ccTo, ccFrom)
+	}
+	return rate.Rate{
+		CurrencyFrom: ccFrom,
+		CurrencyTo:   ccTo,
+		Rate:         decimal.NewFromInt(1).Div(opposite.Rate).Round(crossPrecision),
+		Time:         opposite.Time,
+		Provider:     opposite.Provider,
+	}, legOf(opposite, true), nil
+}
+
+// providers joins the distinct providers of the legs.
+func providers(legs []rate.Leg) string {
+	names := make([]string, 0, len(legs))
+	for _, leg := range legs {
+		if !slices.Contains(names, leg.Provider) {
+			names = append(names, leg.Provider)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *CrossFetcher) fetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	direct, leg, err := c.leg(ctx, ccFrom, ccTo)
+	if err == nil {
+		if leg.Inverted {
+			direct.Legs = []rate.Leg{leg}
+		}
+		return direct, nil
+	}
+	if !errors.Is(err, rate.ErrUnsupportedCurrency) || ccFrom == c.pivot || ccTo == c.pivot {
+		return rate.Rate{}, err
+	}
+	toPivot, fromLeg, err := c.leg(ctx, ccFrom, c.pivot)
+	if err != nil {
+		return rate.Rate{}, fmt.Errorf("%s/%s leg: %w", ccFrom, c.pivot, err)
+	}
+	fromPivot, toLeg, err := c.leg(ctx, c.pivot, ccTo)
+	if err != nil {
+		return rate.Rate{}, fmt.Errorf("%s/%s leg: %w", c.pivot, ccTo, err)
+	}
+	legs := []rate.Leg{fromLeg, toLeg}
+	result := rate.Rate{
+		CurrencyFrom: ccFrom,
+		CurrencyTo:   ccTo,
+		Rate:         toPivot.Rate.Mul(fromPivot.Rate).Round(crossPrecision),
+		Time:         toPivot.Time,
+		Provider:     providers(legs),
+		Legs:         legs,
+	}
+	// A cross rate is as old as its oldest leg
+	if fromPivot.Time.Before(result.Time) {
+		result.Time = fromPivot.Time
+	}
+	return result, nil
+}
+
+func (c *CrossFetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	var result rate.Rate
+	var err error
+	if ccFrom == ccTo {
+		result = rate.Rate{
+			CurrencyFrom: ccFrom, CurrencyTo: ccTo, Rate: decimal.NewFromInt(1), Time: time.Now(),
+		}
+	} else {
+		result, err = c.fetchRate(ctx, ccFrom, ccTo)
+	}
+	slog.Info(
+		"fetched rate",
+		slog.String("fetcher", fmt.Sprint(c)),
+		slog.Any("rate", result),
+		slog.Any("legs", result.Legs),
+		slog.Any("error", err),
+	)
+	if err == nil {
+		return result, nil
+	}
+	if c.next != nil {
+		return c.next.FetchRate(ctx, ccFrom, ccTo)
+	}
+	return rate.Rate{}, err
+}
+
+func (c *CrossFetcher) SetNext(next RateFetcher) {
+	c.next = next
+}
+
+func (c *CrossFetcher) String() string {
+	return fmt.Sprintf("CrossFetcher{%s via %s}", c.fetcher, c.pivot)
+}
+
+// NewCrossFetcher wraps the fetcher to derive rates through the pivot currency.
+func NewCrossFetcher(fetcher RateFetcher, pivot string) *CrossFetcher {
+	return &CrossFetcher{fetcher: fetcher, pivot: pivot}
+}
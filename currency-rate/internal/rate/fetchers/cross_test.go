@@ -0,0 +1,156 @@
+package fetchers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pairFetcher publishes only the listed pairs, like the NBU publishes rates against UAH.
+type pairFetcher struct {
+	rates map[string]string
+	err   error
+	calls int
+}
+
+func (p *pairFetcher) FetchRate(_ context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	p.calls++
+	if p.err != nil {
+		return rate.Rate{}, p.err
+	}
+	value, ok := p.rates[ccFrom+"/"+ccTo]
+	if !ok {
+		return rate.Rate{}, fmt.Errorf("%w: %s/%s", rate.ErrUnsupportedCurrency, ccFrom, ccTo)
+	}
+	return rate.Rate{
+		CurrencyFrom: ccFrom,
+		CurrencyTo:   ccTo,
+		Rate:         decimal.RequireFromString(value),
+		Time:         time.Now(),
+		Provider:     "nbu",
+	}, nil
+}
+
+func (p *pairFetcher) SetNext(fetchers.RateFetcher) {}
+
+func (p *pairFetcher) String() string {
+	return "pairFetcher{}"
+}
+
+func nbuPairs() *pairFetcher {
+	return &pairFetcher{rates: map[string]string{"USD/UAH": "40", "EUR/UAH": "44"}}
+}
+
+func TestCrossFetcher(t *testing.T) {
+	tests := []struct {
+		name         string
+		ccFrom       string
+		ccTo         string
+		expectedRate string
+		expectedLegs []rate.Leg
+	}{
+		{
+			name:         "direct",
+			ccFrom:       "USD",
+			ccTo:         "UAH",
+			expectedRate: "40",
+		},
+		{
+			name:         "inverted",
+			ccFrom:       "UAH",
+			ccTo:         "USD",
+			expectedRate: "0.025",
+			expectedLegs: []rate.Leg{{
+				CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("40"),
+				Provider: "nbu", Inverted: true,
+			}},
+		},
+		{
+			name:         "cross",
+			ccFrom:       "EUR",
+			ccTo:         "USD",
+			expectedRate: "1.1",
+			expectedLegs: []rate.Leg{
+				{
+					CurrencyFrom: "EUR", CurrencyTo: "UAH", Rate: decimal.RequireFromString("44"),
+					Provider: "nbu",
+				},
+				{
+					CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("40"),
+					Provider: "nbu", Inverted: true,
+				},
+			},
+		},
+		{
+			name:         "identity",
+			ccFrom:       "UAH",
+			ccTo:         "UAH",
+			expectedRate: "1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			fetcher := fetchers.NewCrossFetcher(nbuPairs(), fetchers.NBUPivot)
+			// Act
+			result, err := fetcher.FetchRate(context.Background(), tc.ccFrom, tc.ccTo)
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.ccFrom, result.CurrencyFrom)
+			assert.Equal(t, tc.ccTo, result.CurrencyTo)
+			assert.Equal(t, tc.expectedRate, result.Rate.String())
+			assert.Equal(t, tc.expectedLegs, result.Legs)
+			assert.False(t, result.Time.IsZero())
+		})
+	}
+}
+
+func TestCrossFetcherProvider(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewCrossFetcher(nbuPairs(), fetchers.NBUPivot)
+	// Act
+	result, err := fetcher.FetchRate(context.Background(), "EUR", "USD")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "nbu", result.Provider)
+}
+
+func TestCrossFetcherUnsupported(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewCrossFetcher(nbuPairs(), fetchers.NBUPivot)
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "PLN", "USD")
+	// Assert
+	require.ErrorIs(t, err, rate.ErrUnsupportedCurrency)
+}
+
+func TestCrossFetcherProviderError(t *testing.T) {
+	// Arrange
+	provider := &pairFetcher{err: errors.New("unavailable")}
+	fetcher := fetchers.NewCrossFetcher(provider, fetchers.NBUPivot)
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "EUR", "USD")
+	// Assert
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, rate.ErrUnsupportedCurrency)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestCrossFetcherNext(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewCrossFetcher(nbuPairs(), fetchers.NBUPivot)
+	fetcher.SetNext(&pairFetcher{rates: map[string]string{"PLN/USD": "0.25"}})
+	// Act
+	result, err := fetcher.FetchRate(context.Background(), "PLN", "USD")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "0.25", result.Rate.String())
+}
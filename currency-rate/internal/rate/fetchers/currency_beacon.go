@@ -5,46 +5,47 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
+	"net/url"
 	"slices"
 	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
 	"github.com/ericchiang/css"
+	"github.com/shopspring/decimal"
 	"golang.org/x/net/html"
 )
 
 const (
 	cssSelector            = "tbody tr > td:first-child > a"
 	supportedCurrenciesURL = "https://currencybeacon.com/supported-currencies"
-	baseURL                = "https://api.currencybeacon.com/v1/latest?" +
-		"api_key=%s&base=%s&symbols=%s"
+	currencyBeaconBaseURL  = "https://api.currencybeacon.com/v1"
+	currencyBeaconProvider = "currencybeacon"
 )
 
 type endpointResponse struct {
-	Rates map[string]float32 `json:"rates"`
+	Rates map[string]decimal.Decimal `json:"rates"`
 }
 
 type CurrencyBeaconFetcher struct {
 	APIKey              string
 	next                RateFetcher
 	supportedCurrencies []string
+	options             options
 }
 
 func (c *CurrencyBeaconFetcher) SupportedCurrencies(ctx context.Context) []string {
 	if c.supportedCurrencies != nil {
 		return c.supportedCurrencies
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, supportedCurrenciesURL, nil)
-	if err != nil {
-		slog.Error(
-			"creating request",
-			slog.String("fetcher", fmt.Sprint(c)), slog.Any("error", err),
-		)
-		return nil
-	}
-	response, err := http.DefaultClient.Do(req)
+	sel, _ := css.Parse(cssSelector)
+	var node *html.Node
+	err := c.options.get(ctx, c.options.currenciesURL, func(body io.Reader) error {
+		var parseErr error
+		node, parseErr = html.Parse(body)
+		return parseErr
+	})
 	slog.Info(
 		"fetching supported currencies",
 		slog.String("fetcher", fmt.Sprint(c)), slog.Any("error", err),
@@ -52,16 +53,6 @@ func (c *CurrencyBeaconFetcher) SupportedCurrencies(ctx context.Context) []strin
 	if err != nil {
 		return nil
 	}
-	defer response.Body.Close()
-	sel, _ := css.Parse(cssSelector)
-	node, err := html.Parse(response.Body)
-	if err != nil {
-		slog.Error(
-			"parsing html",
-			slog.String("fetcher", fmt.Sprint(c)), slog.Any("error", err),
-		)
-		return nil
-	}
 	currencies := make([]string, 0, 100)
 	for _, n := range sel.Select(node) {
 		currencies = append(currencies, n.FirstChild.Data)
@@ -73,21 +64,13 @@ func (c *CurrencyBeaconFetcher) SupportedCurrencies(ctx context.Context) []strin
 func (c *CurrencyBeaconFetcher) fetchRate(
 	ctx context.Context, ccFrom, ccTo string,
 ) (rate.Rate, error) {
-	formattedURL := fmt.Sprintf(baseURL, c.APIKey, ccFrom, ccTo)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, formattedURL, nil)
-	if err != nil {
-		return rate.Rate{}, err
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return rate.Rate{}, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return rate.Rate{}, fmt.Errorf("fetching url: %s", resp.Status)
-	}
+	query := url.Values{"api_key": {c.APIKey}, "base": {ccFrom}, "symbols": {ccTo}}
 	var data endpointResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	latestURL := c.options.baseURL + "/latest?" + query.Encode()
+	err := c.options.get(ctx, latestURL, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&data)
+	})
+	if err != nil {
 		return rate.Rate{}, err
 	}
 	value, ok := data.Rates[ccTo]
@@ -99,6 +82,7 @@ func (c *CurrencyBeaconFetcher) fetchRate(
 		CurrencyTo:   ccTo,
 		Rate:         value,
 		Time:         time.Now(),
+		Provider:     currencyBeaconProvider,
 	}, nil
 }
 
@@ -116,10 +100,10 @@ func (c *CurrencyBeaconFetcher) FetchRate(
 		return rate.Rate{}, err
 	}
 	if !slices.Contains(supportedCurrencies, ccFrom) {
-		return rate.Rate{}, fmt.Errorf("unsupported currency: %s", ccFrom)
+		return rate.Rate{}, fmt.Errorf("%w: %s", rate.ErrUnsupportedCurrency, ccFrom)
 	}
 	if !slices.Contains(supportedCurrencies, ccTo) {
-		return rate.Rate{}, fmt.Errorf("unsupported currency: %s", ccTo)
+		return rate.Rate{}, fmt.Errorf("%w: %s", rate.ErrUnsupportedCurrency, ccTo)
 	}
 	result, err := c.fetchRate(ctx, ccFrom, ccTo)
 	slog.Info(
@@ -145,8 +129,9 @@ func (c *CurrencyBeaconFetcher) String() string {
 	return "CurrencyBeaconFetcher{}"
 }
 
-func NewCurrencyBeaconFetcher(apiKey string) *CurrencyBeaconFetcher {
+func NewCurrencyBeaconFetcher(apiKey string, opts ...Option) *CurrencyBeaconFetcher {
 	return &CurrencyBeaconFetcher{
-		APIKey: apiKey,
+		APIKey:  apiKey,
+		options: newOptions(currencyBeaconBaseURL, supportedCurrenciesURL, opts),
 	}
 }
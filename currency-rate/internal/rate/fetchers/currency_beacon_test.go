@@ -2,44 +2,120 @@ package fetchers_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+const (
+	testAPIKey          = "key"
+	supportedCurrencies = `<html><body><table><tbody>
+<tr><td><a>USD</a></td><td>United States Dollar</td></tr>
+<tr><td><a>EUR</a></td><td>Euro</td></tr>
+<tr><td><a>UAH</a></td><td>Ukrainian Hryvnia</td></tr>
+</tbody></table></body></html>`
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// currencyBeaconServer stands in for the CurrencyBeacon API and its supported currencies page.
+// Rate requests without the test API key are rejected.
+func currencyBeaconServer(t *testing.T, currenciesStatus int) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/supported-currencies", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(currenciesStatus)
+		_, _ = w.Write([]byte(supportedCurrencies))
+	})
+	mux.HandleFunc("/v1/latest", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("api_key") != testAPIKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "USD", query.Get("base"))
+		_, _ = w.Write([]byte(`{"rates":{"` + query.Get("symbols") + `":0.9215}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newCurrencyBeaconFetcher(server *httptest.Server, apiKey string) fetchers.RateFetcher {
+	return fetchers.NewCurrencyBeaconFetcher(
+		apiKey,
+		fetchers.WithBaseURL(server.URL+"/v1"),
+		fetchers.WithCurrenciesURL(server.URL+"/supported-currencies"),
+	)
+}
+
 func TestFetchRate(t *testing.T) {
 	tests := []struct {
-		name          string
-		from          string
-		to            string
-		expectedError bool
+		name             string
+		from             string
+		to               string
+		apiKey           string
+		currenciesStatus int
+		expectedRate     string
+		expectedError    error
 	}{
 		{
-			name:          "unsupported-currency",
-			from:          "-",
-			to:            "UAH",
-			expectedError: true,
+			name:             "success",
+			from:             "USD",
+			to:               "EUR",
+			apiKey:           testAPIKey,
+			currenciesStatus: http.StatusOK,
+			expectedRate:     "0.9215",
+		},
+		{
+			name:             "unsupported-currency",
+			from:             "-",
+			to:               "UAH",
+			apiKey:           testAPIKey,
+			currenciesStatus: http.StatusOK,
+			expectedError:    rate.ErrUnsupportedCurrency,
 		},
 		{
-			name:          "no-API-key",
-			from:          "USD",
-			to:            "EUR",
-			expectedError: true,
+			name:             "no-API-key",
+			from:             "USD",
+			to:               "EUR",
+			currenciesStatus: http.StatusOK,
+		},
+		{
+			name:             "no-supported-currencies",
+			from:             "USD",
+			to:               "EUR",
+			apiKey:           testAPIKey,
+			currenciesStatus: http.StatusInternalServerError,
 		},
 	}
 
-	b := fetchers.NewCurrencyBeaconFetcher("")
-
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			_, err := b.FetchRate(context.Background(), tc.from, tc.to)
-			if tc.expectedError {
-				assert.Error(t, err)
+			// Arrange
+			b := newCurrencyBeaconFetcher(currencyBeaconServer(t, tc.currenciesStatus), tc.apiKey)
+			// Act
+			result, err := b.FetchRate(context.Background(), tc.from, tc.to)
+			// Assert
+			if tc.expectedRate != "" {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedRate, result.Rate.String())
+				assert.Equal(t, "currencybeacon", result.Provider)
 				return
 			}
-			assert.NoError(t, err)
+			assert.Error(t, err)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			}
 		})
 	}
 }
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
 )
 
 // NBURateFetcher is a RateFetcher implementation that fetches rates from
@@ -18,63 +22,155 @@ import (
 // API docs: https://bank.gov.ua/ua/open-data/api-dev
 // NOTE: CurrencyTo can only be "UAH", as the NBU API only supports fetching rates for UAH
 //
+// Rates of all currencies published for the date are loaded in a single request
+// and reused for the refresh interval, see WithRefreshInterval.
+//
 // Example usage:
 //
-//	fetcher := NewNBURateFetcher()
+//	fetcher := NewNBURateFetcher(WithTimeout(5 * time.Second))
 //	rate, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
 //	if err != nil {
 //		log.Fatal(err)
 //	}
 //	fmt.Println(rate)
 type NBURateFetcher struct {
-	next RateFetcher
+	next    RateFetcher
+	options options
+
+	group     singleflight.Group
+	mu        sync.RWMutex
+	catalogue nbuCatalogue
+}
+
+// nbuCatalogue holds rates of all currencies the NBU published for the date.
+type nbuCatalogue struct {
+	date    string
+	rates   map[string]decimal.Decimal
+	fetched time.Time
+}
+
+const (
+	uahCC = "UAH"
+	// NBUPivot is the currency all NBU rates are published against.
+	NBUPivot      = uahCC
+	nbuProvider   = "nbu"
+	nbuBaseURL    = "https://bank.gov.ua/NBUStatService/v1/statdirectory/exchange"
+	nbuDateLayout = "20060102"
+)
+
+func (n *NBURateFetcher) formatURL(date string) string {
+	return fmt.Sprintf("%s?date=%s&json", n.options.baseURL, date)
 }
 
-const uahCC = "UAH"
+func (n *NBURateFetcher) loadCatalogue(ctx context.Context, date string) (nbuCatalogue, error) {
+	var data []struct {
+		Rate decimal.Decimal `json:"rate"`
+		CC   string          `json:"cc"`
+	}
+	err := n.options.get(ctx, n.formatURL(date), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&data)
+	})
+	if err != nil {
+		return nbuCatalogue{}, err
+	}
+	if len(data) == 0 {
+		return nbuCatalogue{}, errors.New("no rate data found")
+	}
+	rates := make(map[string]decimal.Decimal, len(data)+1)
+	rates[uahCC] = decimal.NewFromInt(1)
+	for _, item := range data {
+		rates[strings.ToUpper(item.CC)] = item.Rate
+	}
+	return nbuCatalogue{date: date, rates: rates, fetched: time.Now()}, nil
+}
 
-func (n *NBURateFetcher) SupportedCurrencies(_ context.Context) []string {
-	return []string{uahCC, "USD"}
+// catalogueOn returns the catalogue of the date, loading it once for all concurrent
+// callers when it's missing or older than the refresh interval. If loading fails,
+// the previously loaded catalogue of the same date is returned.
+// The load isn't cancelled with the caller that started it, so that the other
+// callers waiting for it don't fail, but every caller stops waiting once its context is done.
+func (n *NBURateFetcher) catalogueOn(ctx context.Context, t time.Time) (nbuCatalogue, error) {
+	date := t.Format(nbuDateLayout)
+	n.mu.RLock()
+	cached := n.catalogue
+	n.mu.RUnlock()
+	if cached.date == date && time.Since(cached.fetched) < n.options.refreshInterval {
+		return cached, nil
+	}
+	ch := n.group.DoChan(date, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		if n.options.timeout > 0 {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithTimeout(loadCtx, n.options.timeout)
+			defer cancel()
+		}
+		catalogue, err := n.loadCatalogue(loadCtx, date)
+		if err != nil {
+			return nil, err
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.catalogue = catalogue
+		return catalogue, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nbuCatalogue{}, ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			if cached.date == date {
+				slog.Warn(
+					"serving previous catalogue",
+					slog.String("fetcher", fmt.Sprint(n)),
+					slog.Any("fetched", cached.fetched),
+					slog.Any("error", result.Err),
+				)
+				return cached, nil
+			}
+			return nbuCatalogue{}, fmt.Errorf("loading catalogue: %w", result.Err)
+		}
+		return result.Val.(nbuCatalogue), nil
+	}
 }
 
-func (n *NBURateFetcher) formatURL(cc string, date time.Time) string {
-	currentDate := fmt.Sprintf("%d%02d%02d", date.Year(), date.Month(), date.Day())
-	const baseURL = "https://bank.gov.ua/NBUStatService/v1/statdirectory/" +
-		"exchange?valcode=%s&date=%s&json"
-	return fmt.Sprintf(baseURL, cc, currentDate)
+// SupportedCurrencies returns the currencies published by the NBU today,
+// or nil if they couldn't be loaded.
+func (n *NBURateFetcher) SupportedCurrencies(ctx context.Context) []string {
+	catalogue, err := n.catalogueOn(ctx, time.Now())
+	if err != nil {
+		slog.Info(
+			"fetching supported currencies",
+			slog.String("fetcher", fmt.Sprint(n)), slog.Any("error", err),
+		)
+		return nil
+	}
+	currencies := make([]string, 0, len(catalogue.rates))
+	for cc := range catalogue.rates {
+		currencies = append(currencies, cc)
+	}
+	slices.Sort(currencies)
+	return currencies
 }
 
 func (n *NBURateFetcher) fetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
 	if ccTo != uahCC {
-		return rate.Rate{}, fmt.Errorf("invalid currency from: %s", ccFrom)
+		return rate.Rate{}, fmt.Errorf("%w: %s", rate.ErrUnsupportedCurrency, ccTo)
 	}
 	result := rate.Rate{
 		CurrencyFrom: ccFrom,
 		CurrencyTo:   ccTo,
-		Time:         time.Now(),
+		Provider:     nbuProvider,
 	}
-	if !slices.Contains(n.SupportedCurrencies(ctx), ccFrom) {
-		return result, fmt.Errorf("unsupported currency: %s", ccFrom)
-	}
-	formattedURL := n.formatURL(ccFrom, time.Now())
-	req, err := http.NewRequest(http.MethodGet, formattedURL, nil)
+	catalogue, err := n.catalogueOn(ctx, time.Now())
 	if err != nil {
 		return result, err
 	}
-	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
-	if err != nil {
-		return result, err
-	}
-	defer resp.Body.Close()
-	var data []struct {
-		Rate float32 `json:"rate"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return result, err
-	}
-	if len(data) == 0 {
-		return result, errors.New("no rate data found")
+	value, ok := catalogue.rates[ccFrom]
+	if !ok {
+		return result, fmt.Errorf("%w: %s", rate.ErrUnsupportedCurrency, ccFrom)
 	}
-	result.Rate = data[0].Rate
+	result.Rate = value
+	result.Time = catalogue.fetched
 	return result, nil
 }
 
@@ -101,6 +197,6 @@ func (n *NBURateFetcher) String() string {
 	return "NBURateFetcher{}"
 }
 
-func NewNBURateFetcher() *NBURateFetcher {
-	return &NBURateFetcher{}
+func NewNBURateFetcher(opts ...Option) *NBURateFetcher {
+	return &NBURateFetcher{options: newOptions(nbuBaseURL, "", opts)}
 }
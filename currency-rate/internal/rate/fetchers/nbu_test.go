@@ -2,8 +2,14 @@ package fetchers_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,7 +36,8 @@ func TestFetchRate_Failure(t *testing.T) {
 		},
 	}
 
-	b := fetchers.NewNBURateFetcher()
+	server := nbuServer(t, http.StatusOK, nbuCatalogue)
+	b := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -45,10 +52,264 @@ func TestFetchRate_Failure(t *testing.T) {
 	}
 }
 
+// nbuCatalogue is a shortened list of rates the NBU publishes for a date.
+const nbuCatalogue = `[
+	{"r030":840,"txt":"Долар США","rate":41.2345,"cc":"USD","exchangedate":"03.06.2024"},
+	{"r030":978,"txt":"Євро","rate":44.1234,"cc":"EUR","exchangedate":"03.06.2024"},
+	{"r030":985,"txt":"Злотий","rate":10.2815,"cc":"PLN","exchangedate":"03.06.2024"}
+]`
+
+// nbuServer stands in for the NBU API, responding to catalogue requests with the body.
+func nbuServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.False(t, r.URL.Query().Has("valcode"))
+		assert.Len(t, r.URL.Query().Get("date"), len("20060102"))
+		assert.True(t, r.URL.Query().Has("json"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
 func TestNBUFetchRate(t *testing.T) {
-	t.Parallel()
-	nbu := fetchers.NewNBURateFetcher()
+	// Arrange
+	server := nbuServer(t, http.StatusOK, nbuCatalogue)
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	// Act
 	rate, err := nbu.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
 	require.NoError(t, err)
-	assert.Greater(t, rate.Rate, float32(0))
+	assert.Equal(t, "41.2345", rate.Rate.String())
+	assert.Equal(t, "USD", rate.CurrencyFrom)
+	assert.Equal(t, "UAH", rate.CurrencyTo)
+	assert.Equal(t, "nbu", rate.Provider)
+}
+
+func TestNBUFetchRate_ProviderFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "server-error", status: http.StatusInternalServerError, body: "error"},
+		{name: "no-data", status: http.StatusOK, body: "[]"},
+		{name: "malformed", status: http.StatusOK, body: "{"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			server := nbuServer(t, tc.status, tc.body)
+			nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+			// Act
+			_, err := nbu.FetchRate(context.Background(), "USD", "UAH")
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNBUFetchRate_UserAgentAndClient(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "rates-bot/1.0", r.UserAgent())
+		_, _ = w.Write([]byte(`[{"rate":41.5,"cc":"USD"}]`))
+	}))
+	defer server.Close()
+	requests := 0
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	nbu := fetchers.NewNBURateFetcher(
+		fetchers.WithBaseURL(server.URL),
+		fetchers.WithHTTPClient(client),
+		fetchers.WithUserAgent("rates-bot/1.0"),
+	)
+	// Act
+	rate, err := nbu.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "41.5", rate.Rate.String())
+	assert.Equal(t, 1, requests)
+}
+
+func TestNBUFetchRate_Timeout(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	nbu := fetchers.NewNBURateFetcher(
+		fetchers.WithBaseURL(server.URL),
+		fetchers.WithTimeout(50*time.Millisecond),
+	)
+	// Act
+	start := time.Now()
+	_, err := nbu.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNBUFetchRate_Next(t *testing.T) {
+	// Arrange
+	failing := nbuServer(t, http.StatusServiceUnavailable, "")
+	working := nbuServer(t, http.StatusOK, `[{"rate":41.5,"cc":"USD"}]`)
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(failing.URL))
+	nbu.SetNext(fetchers.NewNBURateFetcher(fetchers.WithBaseURL(working.URL)))
+	// Act
+	rate, err := nbu.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "41.5", rate.Rate.String())
+}
+
+// countingNBUServer stands in for the NBU API, counting catalogue requests.
+func countingNBUServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(nbuCatalogue))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNBUFetchRate_Catalogue(t *testing.T) {
+	// Arrange
+	var requests atomic.Int32
+	server := countingNBUServer(t, &requests)
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	expected := map[string]string{"USD": "41.2345", "EUR": "44.1234", "PLN": "10.2815", "UAH": "1"}
+	// Act
+	rates := make(map[string]string, len(expected))
+	for cc := range expected {
+		result, err := nbu.FetchRate(context.Background(), cc, "UAH")
+		require.NoError(t, err)
+		rates[cc] = result.Rate.String()
+	}
+	_, err := nbu.FetchRate(context.Background(), "GBP", "UAH")
+	// Assert
+	assert.Equal(t, expected, rates)
+	assert.ErrorIs(t, err, rate.ErrUnsupportedCurrency)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNBUFetchRate_ConcurrentRequests(t *testing.T) {
+	// Arrange
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		// Keep the request in flight until all callers are waiting for it
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(nbuCatalogue))
+	}))
+	defer server.Close()
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	// Act
+	var wg sync.WaitGroup
+	for _, cc := range []string{"USD", "EUR", "PLN", "USD", "EUR", "PLN"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := nbu.FetchRate(context.Background(), cc, "UAH")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	// Assert
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNBUFetchRate_CallerCancelled(t *testing.T) {
+	// Arrange
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(nbuCatalogue))
+	}))
+	defer server.Close()
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	waiting := make(chan error, 1)
+	// Act
+	start := time.Now()
+	go func() {
+		_, err := nbu.FetchRate(context.Background(), "EUR", "UAH")
+		waiting <- err
+	}()
+	_, cancelledErr := nbu.FetchRate(ctx, "USD", "UAH")
+	cancelledElapsed := time.Since(start)
+	// Assert
+	require.ErrorIs(t, cancelledErr, context.DeadlineExceeded)
+	assert.Less(t, cancelledElapsed, 100*time.Millisecond)
+	require.NoError(t, <-waiting)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNBUFetchRate_Refresh(t *testing.T) {
+	// Arrange
+	var requests atomic.Int32
+	server := countingNBUServer(t, &requests)
+	nbu := fetchers.NewNBURateFetcher(
+		fetchers.WithBaseURL(server.URL),
+		fetchers.WithRefreshInterval(10*time.Millisecond),
+	)
+	// Act
+	_, firstErr := nbu.FetchRate(context.Background(), "USD", "UAH")
+	time.Sleep(20 * time.Millisecond)
+	_, secondErr := nbu.FetchRate(context.Background(), "EUR", "UAH")
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestNBUFetchRate_RefreshFailure(t *testing.T) {
+	// Arrange
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(nbuCatalogue))
+	}))
+	defer server.Close()
+	nbu := fetchers.NewNBURateFetcher(
+		fetchers.WithBaseURL(server.URL),
+		fetchers.WithRefreshInterval(time.Nanosecond),
+	)
+	// Act
+	_, firstErr := nbu.FetchRate(context.Background(), "USD", "UAH")
+	result, secondErr := nbu.FetchRate(context.Background(), "EUR", "UAH")
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, "44.1234", result.Rate.String())
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestNBUSupportedCurrencies(t *testing.T) {
+	// Arrange
+	server := nbuServer(t, http.StatusOK, nbuCatalogue)
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	// Act
+	currencies := nbu.SupportedCurrencies(context.Background())
+	// Assert
+	assert.Equal(t, []string{"EUR", "PLN", "UAH", "USD"}, currencies)
+}
+
+func TestNBUSupportedCurrencies_Failure(t *testing.T) {
+	// Arrange
+	server := nbuServer(t, http.StatusInternalServerError, "error")
+	nbu := fetchers.NewNBURateFetcher(fetchers.WithBaseURL(server.URL))
+	// Act
+	currencies := nbu.SupportedCurrencies(context.Background())
+	// Assert
+	assert.Nil(t, currencies)
 }
@@ -0,0 +1,110 @@
+package fetchers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultUserAgent       = "currency-rate"
+	defaultTimeout         = 10 * time.Second
+	defaultRefreshInterval = time.Hour
+)
+
+// options configure HTTP requests of the fetchers to their providers.
+type options struct {
+	client          *http.Client
+	baseURL         string
+	currenciesURL   string
+	userAgent       string
+	timeout         time.Duration
+	refreshInterval time.Duration
+}
+
+// Option configures a rate fetcher.
+type Option func(*options)
+
+// WithHTTPClient sets the client requests are sent with, e.g. to route them via a proxy.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithBaseURL sets the URL of the provider API, e.g. of a test server.
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.baseURL = url
+	}
+}
+
+// WithCurrenciesURL sets the URL of the page listing supported currencies,
+// only CurrencyBeaconFetcher looks them up.
+func WithCurrenciesURL(url string) Option {
+	return func(o *options) {
+		o.currenciesURL = url
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithTimeout limits every request, including reading the response. Zero disables the limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRefreshInterval sets how long the catalogue of rates is reused before being
+// loaded again, only NBURateFetcher loads rates of all currencies at once.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.refreshInterval = interval
+	}
+}
+
+func newOptions(baseURL, currenciesURL string, opts []Option) options {
+	o := options{
+		client:          http.DefaultClient,
+		baseURL:         baseURL,
+		currenciesURL:   currenciesURL,
+		userAgent:       defaultUserAgent,
+		timeout:         defaultTimeout,
+		refreshInterval: defaultRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// get requests the URL and passes the body of a successful response to the read function.
+func (o options) get(ctx context.Context, url string, read func(body io.Reader) error) error {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", o.userAgent)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching url: %s", resp.Status)
+	}
+	return read(resp.Body)
+}
@@ -0,0 +1,207 @@
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers/config"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrNoQuorum is returned when fewer providers than the quorum responded.
+	ErrNoQuorum = errors.New("not enough providers responded")
+	// ErrDisagreement is returned when provider rates deviate from the median
+	// by more than the tolerance.
+	ErrDisagreement = errors.New("providers disagree")
+)
+
+// ProviderResult is an outcome of fetching the rate from a single provider.
+type ProviderResult struct {
+	Provider string
+	Rate     rate.Rate
+	Err      error
+	Elapsed  time.Duration
+}
+
+// FetchError is returned by ParallelFetcher when the rate couldn't be decided,
+// along with the results of the providers for debugging.
+type FetchError struct {
+	Err     error
+	Results []ProviderResult
+}
+
+func (e *FetchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// ParallelFetcher is a RateFetcher querying all providers concurrently, so that
+// a slow provider doesn't delay the others. Depending on the strategy it returns
+// the first successful rate, or the median of at least quorum successful rates,
+// failing if any of them deviates from the median by more than the tolerance.
+// Providers shouldn't have next fetchers set, as they would be queried sequentially.
+//
+// Example usage:
+//
+//	fetcher := NewParallelFetcher(config.NewFromEnv(), NewNBURateFetcher(), beaconFetcher)
+//	rate, results, err := fetcher.Fetch(context.Background(), "USD", "UAH")
+//	fmt.Println(rate, results, err)
+type ParallelFetcher struct {
+	providers []RateFetcher
+	config    config.Config
+	next      RateFetcher
+}
+
+// query fetches the rate from every provider in a separate goroutine.
+// The channel is buffered, so that late providers don't block once the result is decided.
+func (p *ParallelFetcher) query(ctx context.Context, ccFrom, ccTo string) <-chan ProviderResult {
+	results := make(chan ProviderResult, len(p.providers))
+	for _, provider := range p.providers {
+		go func(provider RateFetcher) {
+			start := time.Now()
+			result, err := provider.FetchRate(ctx, ccFrom, ccTo)
+			results <- ProviderResult{
+				Provider: fmt.Sprint(provider),
+				Rate:     result,
+				Err:      err,
+				Elapsed:  time.Since(start),
+			}
+		}(provider)
+	}
+	return results
+}
+
+// providerErrors joins errors of the failed providers, so that e.g.
+// rate.ErrUnsupportedCurrency of the providers is detectable.
+func providerErrors(results []ProviderResult) error {
+	errs := make([]error, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Provider, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetchFirst returns the first successful rate, cancelling the rest of providers.
+func (p *ParallelFetcher) fetchFirst(
+	ctx context.Context, ccFrom, ccTo string,
+) (rate.Rate, []ProviderResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queried := p.query(ctx, ccFrom, ccTo)
+	results := make([]ProviderResult, 0, len(p.providers))
+	for range p.providers {
+		result := <-queried
+		results = append(results, result)
+		if result.Err == nil {
+			return result.Rate, results, nil
+		}
+	}
+	return rate.Rate{}, results, providerErrors(results)
+}
+
+// median returns the middle rate, or the mean of the two middle ones.
+func median(rates []decimal.Decimal) decimal.Decimal {
+	sorted := slices.Clone(rates)
+	slices.SortFunc(sorted, func(a, b decimal.Decimal) int { return a.Cmp(b) })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return sorted[middle-1].Add(sorted[middle]).Div(decimal.NewFromInt(2))
+}
+
+// fetchMedian waits for all providers and returns the median of the successful rates.
+func (p *ParallelFetcher) fetchMedian(
+	ctx context.Context, ccFrom, ccTo string,
+) (rate.Rate, []ProviderResult, error) {
+	queried := p.query(ctx, ccFrom, ccTo)
+	results := make([]ProviderResult, 0, len(p.providers))
+	rates := make([]decimal.Decimal, 0, len(p.providers))
+	providers := make([]string, 0, len(p.providers))
+	for range p.providers {
+		result := <-queried
+		results = append(results, result)
+		if result.Err == nil {
+			rates = append(rates, result.Rate.Rate)
+			providers = append(providers, result.Rate.Provider)
+		}
+	}
+	if len(rates) == 0 || len(rates) < p.config.Quorum {
+		err := fmt.Errorf("%w: %d of %d", ErrNoQuorum, len(rates), p.config.Quorum)
+		return rate.Rate{}, results, errors.Join(err, providerErrors(results))
+	}
+	value := median(rates)
+	if !p.config.Tolerance.IsZero() {
+		for _, r := range rates {
+			deviation := r.Sub(value).Abs().Div(value)
+			if deviation.GreaterThan(p.config.Tolerance) {
+				return rate.Rate{}, results, fmt.Errorf(
+					"%w: %s deviates from median %s by more than %s",
+					ErrDisagreement, r, value, p.config.Tolerance,
+				)
+			}
+		}
+	}
+	return rate.Rate{
+		CurrencyFrom: ccFrom,
+		CurrencyTo:   ccTo,
+		Rate:         value,
+		Time:         time.Now(),
+		Provider:     strings.Join(providers, ","),
+	}, results, nil
+}
+
+// Fetch queries the providers by the strategy, without falling back to the next fetcher.
+// Returns the decided rate along with the results of every provider queried.
+func (p *ParallelFetcher) Fetch(
+	ctx context.Context, ccFrom, ccTo string,
+) (rate.Rate, []ProviderResult, error) {
+	if p.config.Strategy == config.StrategyMedian {
+		return p.fetchMedian(ctx, ccFrom, ccTo)
+	}
+	return p.fetchFirst(ctx, ccFrom, ccTo)
+}
+
+func (p *ParallelFetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	result, results, err := p.Fetch(ctx, ccFrom, ccTo)
+	slog.Info(
+		"fetched rate",
+		slog.String("fetcher", fmt.Sprint(p)),
+		slog.Any("rate", result),
+		slog.Any("results", results),
+		slog.Any("error", err),
+	)
+	if err == nil {
+		return result, nil
+	}
+	if p.next != nil {
+		return p.next.FetchRate(ctx, ccFrom, ccTo)
+	}
+	return rate.Rate{}, &FetchError{Err: err, Results: results}
+}
+
+func (p *ParallelFetcher) SetNext(next RateFetcher) {
+	p.next = next
+}
+
+func (p *ParallelFetcher) String() string {
+	return fmt.Sprintf("ParallelFetcher{%s}", p.config.Strategy)
+}
+
+// NewParallelFetcher creates a fetcher querying the providers by the strategy
+// of the config, any strategy but median takes the first successful rate.
+func NewParallelFetcher(config config.Config, providers ...RateFetcher) *ParallelFetcher {
+	return &ParallelFetcher{providers: providers, config: config}
+}
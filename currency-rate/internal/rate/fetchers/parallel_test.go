@@ -0,0 +1,229 @@
+package fetchers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/fetchers/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubFetcher responds with the rate or the error after the delay,
+// unless the context is done earlier.
+type stubFetcher struct {
+	name  string
+	rate  string
+	err   error
+	delay time.Duration
+}
+
+func (s *stubFetcher) FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error) {
+	select {
+	case <-ctx.Done():
+		return rate.Rate{}, ctx.Err()
+	case <-time.After(s.delay):
+	}
+	if s.err != nil {
+		return rate.Rate{}, s.err
+	}
+	return rate.Rate{
+		CurrencyFrom: ccFrom, CurrencyTo: ccTo, Rate: decimal.RequireFromString(s.rate),
+	}, nil
+}
+
+func (s *stubFetcher) SetNext(fetchers.RateFetcher) {}
+
+func (s *stubFetcher) String() string {
+	return s.name
+}
+
+func succeeding(name, value string, delay time.Duration) *stubFetcher {
+	return &stubFetcher{name: name, rate: value, delay: delay}
+}
+
+func failing(name string, err error) *stubFetcher {
+	return &stubFetcher{name: name, err: err}
+}
+
+func TestParallelFetcherFirst(t *testing.T) {
+	tests := []struct {
+		name         string
+		providers    []fetchers.RateFetcher
+		expectedRate string
+		maxElapsed   time.Duration
+	}{
+		{
+			name: "fastest",
+			providers: []fetchers.RateFetcher{
+				succeeding("slow", "41.1", time.Second),
+				succeeding("fast", "41.5", 0),
+			},
+			expectedRate: "41.5",
+			maxElapsed:   500 * time.Millisecond,
+		},
+		{
+			name: "failed-fastest",
+			providers: []fetchers.RateFetcher{
+				succeeding("slow", "41.1", 50*time.Millisecond),
+				failing("fast", errors.New("unavailable")),
+			},
+			expectedRate: "41.1",
+			maxElapsed:   time.Second,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			fetcher := fetchers.NewParallelFetcher(
+				config.Config{Strategy: config.StrategyFirst}, tc.providers...,
+			)
+			// Act
+			start := time.Now()
+			result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRate, result.Rate.String())
+			assert.Less(t, time.Since(start), tc.maxElapsed)
+		})
+	}
+}
+
+func TestParallelFetcherFirstAllFailed(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewParallelFetcher(
+		config.Config{Strategy: config.StrategyFirst},
+		failing("beacon", fmt.Errorf("%w: XYZ", rate.ErrUnsupportedCurrency)),
+		failing("nbu", fmt.Errorf("%w: XYZ", rate.ErrUnsupportedCurrency)),
+	)
+	// Act
+	_, err := fetcher.FetchRate(context.Background(), "XYZ", "UAH")
+	// Assert
+	require.ErrorIs(t, err, rate.ErrUnsupportedCurrency)
+	var fetchErr *fetchers.FetchError
+	require.ErrorAs(t, err, &fetchErr)
+	assert.Len(t, fetchErr.Results, 2)
+}
+
+func TestParallelFetcherMedian(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        config.Config
+		providers     []fetchers.RateFetcher
+		expectedRate  string
+		expectedError error
+	}{
+		{
+			name: "odd",
+			config: config.Config{
+				Strategy: config.StrategyMedian, Quorum: 2, Tolerance: decimal.RequireFromString("0.01"),
+			},
+			providers: []fetchers.RateFetcher{
+				succeeding("a", "41.3", 0),
+				succeeding("b", "41.1", 10*time.Millisecond),
+				succeeding("c", "41.2", 0),
+			},
+			expectedRate: "41.2",
+		},
+		{
+			name: "even",
+			config: config.Config{
+				Strategy: config.StrategyMedian, Quorum: 2, Tolerance: decimal.RequireFromString("0.01"),
+			},
+			providers: []fetchers.RateFetcher{
+				succeeding("a", "41.1", 0),
+				succeeding("b", "41.2", 0),
+				failing("c", errors.New("unavailable")),
+			},
+			expectedRate: "41.15",
+		},
+		{
+			name:   "no-quorum",
+			config: config.Config{Strategy: config.StrategyMedian, Quorum: 2},
+			providers: []fetchers.RateFetcher{
+				succeeding("a", "41.1", 0),
+				failing("b", errors.New("unavailable")),
+			},
+			expectedError: fetchers.ErrNoQuorum,
+		},
+		{
+			name: "disagreement",
+			config: config.Config{
+				Strategy: config.StrategyMedian, Quorum: 2, Tolerance: decimal.RequireFromString("0.01"),
+			},
+			providers: []fetchers.RateFetcher{
+				succeeding("a", "41.1", 0),
+				succeeding("b", "45", 0),
+			},
+			expectedError: fetchers.ErrDisagreement,
+		},
+		{
+			name:   "no-tolerance",
+			config: config.Config{Strategy: config.StrategyMedian, Quorum: 2},
+			providers: []fetchers.RateFetcher{
+				succeeding("a", "41", 0),
+				succeeding("b", "45", 0),
+			},
+			expectedRate: "43",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			fetcher := fetchers.NewParallelFetcher(tc.config, tc.providers...)
+			// Act
+			result, results, err := fetcher.Fetch(context.Background(), "USD", "UAH")
+			// Assert
+			assert.Len(t, results, len(tc.providers))
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRate, result.Rate.String())
+			assert.Equal(t, "USD", result.CurrencyFrom)
+			assert.Equal(t, "UAH", result.CurrencyTo)
+		})
+	}
+}
+
+func TestParallelFetcherResults(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewParallelFetcher(
+		config.Config{Strategy: config.StrategyMedian, Quorum: 1},
+		succeeding("beacon", "41.1", 0),
+		failing("nbu", errors.New("unavailable")),
+	)
+	// Act
+	_, fetched, err := fetcher.Fetch(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	results := make(map[string]fetchers.ProviderResult)
+	for _, result := range fetched {
+		results[result.Provider] = result
+	}
+	require.Len(t, results, 2)
+	assert.Equal(t, "41.1", results["beacon"].Rate.Rate.String())
+	assert.NoError(t, results["beacon"].Err)
+	assert.Error(t, results["nbu"].Err)
+}
+
+func TestParallelFetcherNext(t *testing.T) {
+	// Arrange
+	fetcher := fetchers.NewParallelFetcher(
+		config.Config{Strategy: config.StrategyFirst},
+		failing("beacon", errors.New("unavailable")),
+	)
+	fetcher.SetNext(succeeding("fallback", "41.5", 0))
+	// Act
+	result, err := fetcher.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "41.5", result.Rate.String())
+}
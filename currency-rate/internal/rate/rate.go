@@ -1,17 +1,54 @@
 package rate
 
 import (
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrUnsupportedCurrency is returned by fetchers when the requested
+// currency pair can't be served by the provider.
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// CacheStatus tells whether the rate was served from a cache.
+type CacheStatus string
+
+const (
+	// CacheMiss is a rate freshly fetched from the upstream provider.
+	CacheMiss CacheStatus = "MISS"
+	// CacheHit is a cached rate within its lifetime.
+	CacheHit CacheStatus = "HIT"
+	// CacheStale is an expired cached rate served because the upstream failed.
+	CacheStale CacheStatus = "STALE"
 )
 
+// Leg is a rate a cross rate was derived from, as published by the provider.
+type Leg struct {
+	CurrencyFrom string
+	CurrencyTo   string
+	Rate         decimal.Decimal
+	Provider     string
+	// Inverted tells that the reciprocal of the rate was used,
+	// as the provider publishes the pair only in the opposite direction.
+	Inverted bool
+}
+
 type Rate struct {
 	CurrencyFrom string
 	CurrencyTo   string
-	Rate         float32
-	Time         time.Time
+	// Rate keeps the exact decimal value published by the provider.
+	Rate decimal.Decimal
+	Time time.Time
+	// Cache is empty if the rate wasn't passed through a cache.
+	Cache CacheStatus
+	// Provider names the providers the rate was fetched from, separated by commas.
+	Provider string
+	// Legs are empty unless the rate is a cross rate derived through a pivot currency.
+	Legs []Leg
 }
 
 func (r Rate) String() string {
-	return fmt.Sprintf("Rate<%s -> %s: %f>", r.CurrencyFrom, r.CurrencyTo, r.Rate)
+	return fmt.Sprintf("Rate<%s -> %s: %s>", r.CurrencyFrom, r.CurrencyTo, r.Rate)
 }
@@ -2,66 +2,814 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/token"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 const (
 	RatePath      = "/rate"
 	SubscribePath = "/subscribe"
-	ccFrom        = "USD"
-	ccTo          = "UAH"
+	// ConfirmSubscriptionPath accepts GET with a signed token as the last path segment.
+	ConfirmSubscriptionPath = SubscribePath + "/confirm"
+	// UnsubscribePath accepts POST with email, and POST with a signed token as the last
+	// path segment for one-click unsubscribe links, which GET renders a confirmation page of.
+	UnsubscribePath = "/unsubscribe"
+	// AlertsPath accepts GET and POST with a signed alerts token of the user
+	// as the next path segment, followed by the alert ID and "delete" to delete one.
+	AlertsPath      = "/alerts"
+	RateHistoryPath = "/rates/history"
+	OutboxPath      = "/outbox"
+	ProvidersPath   = "/rate/providers"
+	ccFrom          = "USD"
+	ccTo            = "UAH"
 )
 
+const (
+	currencyCodeLength = 3
+	pairsSeparator     = ","
+	pairSeparator      = "/"
+)
+
+const (
+	defaultHistoryPeriod = 7 * 24 * time.Hour
+	defaultHistoryLimit  = 100
+	maxHistoryLimit      = 1000
+)
+
+// historyIntervals maps interval query values to bucket durations.
+var historyIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+const cacheHeader = "X-Cache"
+
+// Rates are rendered as JSON numbers with the exact decimal digits.
+type rateResponse struct {
+	CurrencyFrom string           `json:"from"`
+	CurrencyTo   string           `json:"to"`
+	Rate         json.Number      `json:"rate"`
+	Timestamp    time.Time        `json:"timestamp"`
+	Cache        rate.CacheStatus `json:"cache,omitempty"`
+	Provider     string           `json:"provider,omitempty"`
+	Legs         []legResponse    `json:"legs,omitempty"`
+}
+
+type legResponse struct {
+	CurrencyFrom string      `json:"from"`
+	CurrencyTo   string      `json:"to"`
+	Rate         json.Number `json:"rate"`
+	Provider     string      `json:"provider"`
+	Inverted     bool        `json:"inverted"`
+}
+
+type candleResponse struct {
+	Time  time.Time   `json:"time"`
+	Open  json.Number `json:"open"`
+	High  json.Number `json:"high"`
+	Low   json.Number `json:"low"`
+	Close json.Number `json:"close"`
+}
+
+type historyResponse struct {
+	CurrencyFrom string           `json:"from"`
+	CurrencyTo   string           `json:"to"`
+	Interval     string           `json:"interval"`
+	Total        int64            `json:"total"`
+	Limit        int              `json:"limit"`
+	Offset       int              `json:"offset"`
+	Items        []candleResponse `json:"items"`
+}
+
+type alertResponse struct {
+	ID        uint                  `json:"id"`
+	Pair      string                `json:"pair"`
+	Condition models.AlertCondition `json:"condition"`
+	Threshold json.Number           `json:"threshold"`
+	Triggered bool                  `json:"triggered"`
+}
+
+type providerResponse struct {
+	Provider    string        `json:"provider"`
+	State       breaker.State `json:"state"`
+	LastSuccess *time.Time    `json:"lastSuccess"`
+	LastError   string        `json:"lastError,omitempty"`
+	Requests    int           `json:"requests"`
+	Failures    int           `json:"failures"`
+	ErrorRate   float64       `json:"errorRate"`
+}
+
+type AlertRepository interface {
+	Create(alert *models.Alert) error
+	FindByUser(userID uint) ([]models.Alert, error)
+	Delete(userID, alertID uint) (bool, error)
+}
+
+type OutboxRepository interface {
+	CountPending() (int64, error)
+}
+
 type UserRepository interface {
 	Exists(user *models.User) (bool, error)
+	FindByEmail(email string) (*models.User, error)
 	Create(user *models.User) error
+	Confirm(user *models.User) error
+	SetPairs(user *models.User, pairs []models.CurrencyPair) error
+	SetLocale(user *models.User, locale models.Locale) error
+	SetSchedule(user *models.User, schedule models.Schedule) error
+	ClaimConfirmation(user *models.User, at time.Time, cooldown time.Duration) (bool, error)
+	ReleaseConfirmation(user *models.User, claimedAt time.Time) error
+	Delete(user *models.User) error
 }
 
-// NewGetRateHandler is a handler that fetches the exchange rate between USD and UAH
+// parseCurrencyCode normalizes the currency code to upper case and checks that
+// it has the ISO-4217 alphabetic code format. Empty value falls back to the default.
+func parseCurrencyCode(value, defaultValue string) (string, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	code := strings.ToUpper(value)
+	if len(code) != currencyCodeLength {
+		return "", fmt.Errorf("invalid currency code: %s", value)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code: %s", value)
+		}
+	}
+	return code, nil
+}
+
+// parseCurrencyPairs parses comma-separated currency pairs like "USD/UAH,EUR/UAH".
+// Empty value falls back to the default pair, duplicates are dropped.
+// Pairs of the same currency are rejected, as there is no rate to follow.
+func parseCurrencyPairs(value string) ([]models.CurrencyPair, error) {
+	if value == "" {
+		return []models.CurrencyPair{{CurrencyFrom: ccFrom, CurrencyTo: ccTo}}, nil
+	}
+	rawPairs := strings.Split(value, pairsSeparator)
+	pairs := make([]models.CurrencyPair, 0, len(rawPairs))
+	for _, rawPair := range rawPairs {
+		rawFrom, rawTo, found := strings.Cut(strings.TrimSpace(rawPair), pairSeparator)
+		if !found {
+			return nil, fmt.Errorf("invalid currency pair: %s", rawPair)
+		}
+		from, err := parseCurrencyCode(rawFrom, "")
+		if err != nil || from == "" {
+			return nil, fmt.Errorf("invalid currency pair: %s", rawPair)
+		}
+		to, err := parseCurrencyCode(rawTo, "")
+		if err != nil || to == "" {
+			return nil, fmt.Errorf("invalid currency pair: %s", rawPair)
+		}
+		if from == to {
+			return nil, fmt.Errorf("currency pair of the same currency: %s", rawPair)
+		}
+		pair := models.CurrencyPair{CurrencyFrom: from, CurrencyTo: to}
+		if !slices.Contains(pairs, pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
+}
+
+// checkPairsSupported fetches the rate of every pair, so that the pairs
+// the fetchers don't support are rejected the same way GET /rate rejects them.
+// Other errors are ignored, as a provider outage shouldn't prevent subscribing.
+func checkPairsSupported(
+	ctx context.Context, rateService RateService, pairs []models.CurrencyPair,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, RateTimeout)
+	defer cancel()
+	for _, pair := range pairs {
+		_, err := rateService.FetchRate(ctx, pair.CurrencyFrom, pair.CurrencyTo)
+		if errors.Is(err, rate.ErrUnsupportedCurrency) {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLocale parses the language of notifications, e.g. "uk" or "uk-UA",
+// ignoring the region. Empty value falls back to the default locale.
+func parseLocale(value string) (models.Locale, error) {
+	if value == "" {
+		return models.DefaultLocale, nil
+	}
+	language, _, _ := strings.Cut(strings.ReplaceAll(value, "_", "-"), "-")
+	locale := models.Locale(strings.ToLower(language))
+	if !locale.IsValid() {
+		return "", fmt.Errorf("unsupported locale: %s", value)
+	}
+	return locale, nil
+}
+
+// NewGetRateHandler is a handler that fetches the exchange rate between two currencies
 // from a RateFetcher interface and returns it as a JSON response.
+// The currencies are passed as "from" and "to" query parameters and default to USD and UAH.
+// If the currency code is malformed, returns a 400 Bad Request status code.
+// If the currency pair is not supported by the fetchers, returns a 422 status code.
+// Whether the rate was served from a cache is reported in the "cache" field
+// and the X-Cache header as HIT, MISS or STALE.
 func NewGetRateHandler(rateService RateService, timeout time.Duration) func(*gin.Context) {
 	return func(c *gin.Context) {
+		from, err := parseCurrencyCode(c.Query("from"), ccFrom)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := parseCurrencyCode(c.Query("to"), ccTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
-		rate, err := rateService.FetchRate(ctx, ccFrom, ccTo)
+		result, err := rateService.FetchRate(ctx, from, to)
+		if errors.Is(err, rate.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if result.Cache != "" {
+			c.Header(cacheHeader, string(result.Cache))
+		}
+		legs := make([]legResponse, 0, len(result.Legs))
+		for _, leg := range result.Legs {
+			legs = append(legs, legResponse{
+				CurrencyFrom: leg.CurrencyFrom,
+				CurrencyTo:   leg.CurrencyTo,
+				Rate:         json.Number(leg.Rate.String()),
+				Provider:     leg.Provider,
+				Inverted:     leg.Inverted,
+			})
+		}
+		c.JSON(http.StatusOK, rateResponse{
+			CurrencyFrom: result.CurrencyFrom,
+			CurrencyTo:   result.CurrencyTo,
+			Rate:         json.Number(result.Rate.String()),
+			Timestamp:    time.Unix(result.Created, 0).UTC(),
+			Cache:        result.Cache,
+			Provider:     result.Provider,
+			Legs:         legs,
+		})
+	}
+}
+
+// parseTime parses RFC 3339 time. Empty value falls back to the default.
+func parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", value)
+	}
+	return t, nil
+}
+
+// parseNonNegative parses a non-negative integer. Empty value falls back to the default.
+func parseNonNegative(name, value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// parsePage parses "limit" and "offset" query parameters.
+func parsePage(c *gin.Context) (int, int, error) {
+	limit, err := parseNonNegative("limit", c.Query("limit"), defaultHistoryLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit == 0 || limit > maxHistoryLimit {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+	}
+	offset, err := parseNonNegative("offset", c.Query("offset"), 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
+
+// parseHistoryQuery parses rate history query parameters of the request.
+func parseHistoryQuery(c *gin.Context) (*service.HistoryQuery, error) {
+	q := &service.HistoryQuery{}
+	var err error
+	if q.CurrencyFrom, err = parseCurrencyCode(c.Query("from"), ccFrom); err != nil {
+		return nil, err
+	}
+	if q.CurrencyTo, err = parseCurrencyCode(c.Query("to"), ccTo); err != nil {
+		return nil, err
+	}
+	if q.Until, err = parseTime(c.Query("until"), time.Now()); err != nil {
+		return nil, err
+	}
+	if q.Since, err = parseTime(c.Query("since"), q.Until.Add(-defaultHistoryPeriod)); err != nil {
+		return nil, err
+	}
+	if !q.Since.Before(q.Until) {
+		return nil, errors.New("since must be before until")
+	}
+	interval, ok := historyIntervals[c.DefaultQuery("interval", "day")]
+	if !ok {
+		return nil, fmt.Errorf("invalid interval: %s", c.Query("interval"))
+	}
+	q.Interval = interval
+	if q.Limit, q.Offset, err = parsePage(c); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// NewGetRateHistoryHandler is a handler that returns open, high, low and close
+// rates of the stored currency pair rates grouped by time buckets.
+// Query parameters are "from" and "to" currencies (default to USD and UAH),
+// "since" and "until" RFC 3339 times (default to the last 7 days),
+// "interval" of "hour" or "day" (defaults to day), "limit" (defaults to 100,
+// at most 1000) and "offset" of the buckets.
+// If the parameters are malformed, returns a 400 Bad Request status code.
+func NewGetRateHistoryHandler(historyService HistoryService) func(*gin.Context) {
+	return func(c *gin.Context) {
+		query, err := parseHistoryQuery(c)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, err.Error())
 			return
 		}
-		c.JSON(http.StatusOK, rate.Rate)
+		page, err := historyService.History(*query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		items := make([]candleResponse, 0, len(page.Candles))
+		for _, candle := range page.Candles {
+			items = append(items, candleResponse{
+				Time:  candle.Time,
+				Open:  json.Number(candle.Open.String()),
+				High:  json.Number(candle.High.String()),
+				Low:   json.Number(candle.Low.String()),
+				Close: json.Number(candle.Close.String()),
+			})
+		}
+		c.JSON(http.StatusOK, historyResponse{
+			CurrencyFrom: query.CurrencyFrom,
+			CurrencyTo:   query.CurrencyTo,
+			Interval:     c.DefaultQuery("interval", "day"),
+			Total:        page.Total,
+			Limit:        query.Limit,
+			Offset:       query.Offset,
+			Items:        items,
+		})
+	}
+}
+
+// NewGetOutboxHandler is a handler that returns the "backlog" number of
+// email commands stored in the outbox, but not published to the broker yet.
+func NewGetOutboxHandler(repo OutboxRepository) func(*gin.Context) {
+	return func(c *gin.Context) {
+		backlog, err := repo.CountPending()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"backlog": backlog})
+	}
+}
+
+// NewGetProvidersHandler is a handler that returns the circuit breaker state
+// of every rate provider, its last success and the error rate of the current window.
+func NewGetProvidersHandler(monitor ProviderMonitor) func(*gin.Context) {
+	return func(c *gin.Context) {
+		health := monitor.Health()
+		providers := make([]providerResponse, 0, len(health))
+		for _, h := range health {
+			provider := providerResponse{
+				Provider:  h.Provider,
+				State:     h.State,
+				Requests:  h.Requests,
+				Failures:  h.Failures,
+				ErrorRate: h.ErrorRate,
+			}
+			if !h.LastSuccess.IsZero() {
+				provider.LastSuccess = &h.LastSuccess
+			}
+			if h.LastError != nil {
+				provider.LastError = h.LastError.Error()
+			}
+			providers = append(providers, provider)
+		}
+		c.JSON(http.StatusOK, gin.H{"providers": providers})
+	}
+}
+
+// subscription is a subscribe request: what, in which language and when to notify about.
+type subscription struct {
+	email    string
+	pairs    []models.CurrencyPair
+	locale   models.Locale
+	schedule models.Schedule
+}
+
+// parseSchedule parses optional "deliveryTime", "timezone" and "frequency"
+// POST parameters, the missing ones default to those of the default schedule.
+func parseSchedule(c *gin.Context) (models.Schedule, error) {
+	schedule := models.Schedule{
+		DeliveryTime: c.DefaultPostForm("deliveryTime", models.DefaultDeliveryTime),
+		Timezone:     c.DefaultPostForm("timezone", models.DefaultTimezone),
+		Frequency: models.Frequency(
+			c.DefaultPostForm("frequency", string(models.FrequencyDaily)),
+		),
 	}
+	return schedule, schedule.Validate()
+}
+
+func parseSubscription(c *gin.Context) (*subscription, error) {
+	email := c.PostForm("email")
+	if email == "" {
+		return nil, errors.New("email is required")
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, errors.New("invalid email")
+	}
+	pairs, err := parseCurrencyPairs(c.PostForm("pairs"))
+	if err != nil {
+		return nil, err
+	}
+	locale, err := parseLocale(c.PostForm("locale"))
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := parseSchedule(c)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription{email: email, pairs: pairs, locale: locale, schedule: schedule}, nil
+}
+
+// updatePendingSubscription replaces pairs, locale and schedule of the pending user.
+func updatePendingSubscription(
+	repo UserRepository, user *models.User, sub *subscription,
+) error {
+	if err := repo.SetPairs(user, sub.pairs); err != nil {
+		return err
+	}
+	if user.Locale != sub.locale {
+		if err := repo.SetLocale(user, sub.locale); err != nil {
+			return err
+		}
+	}
+	if user.Schedule != sub.schedule {
+		return repo.SetSchedule(user, sub.schedule)
+	}
+	return nil
+}
+
+// savePendingSubscription creates a pending user of the subscription sent
+// a confirmation at the time, or updates the existing one.
+func savePendingSubscription(
+	repo UserRepository, user *models.User, sub *subscription, now time.Time,
+) (*models.User, error) {
+	if user != nil {
+		return user, updatePendingSubscription(repo, user, sub)
+	}
+	user = &models.User{
+		Email:              sub.email,
+		Status:             models.StatusPending,
+		Locale:             sub.locale,
+		Schedule:           sub.schedule,
+		Subscriptions:      models.NewSubscriptions(sub.pairs),
+		ConfirmationSentAt: now.Unix(),
+	}
+	return user, repo.Create(user)
 }
 
 // NewSubscribeUserHandler is a handler that subscribes a user by email.
 // The email is passed as a POST parameter and is required.
+// The currency pairs are passed as an optional "pairs" POST parameter,
+// e.g. "USD/UAH,EUR/UAH", and default to USD/UAH.
+// The language of notifications is passed as an optional "locale" POST parameter,
+// "en" or "uk", and defaults to English.
+// When to notify is passed as optional "deliveryTime" (e.g. "09:30"), IANA "timezone"
+// and "frequency" ("daily", "weekdays" or "weekly") POST parameters,
+// and defaults to daily at 12:00 UTC.
+// The subscription is created pending and the user is sent a confirmation email.
+// If any of the parameters is malformed, returns a 400 Bad Request status code.
+// If any of the pairs is not supported by the fetchers, returns a 422 status code.
 // If the user is already subscribed, returns a 409 Conflict status code.
-// If the subscription is successful, returns a 200 OK status code.
-func NewSubscribeUserHandler(repo UserRepository) func(*gin.Context) {
+// If the subscription is pending, its pairs, locale and schedule are replaced
+// and the confirmation email is sent again, unless one was sent within the cooldown,
+// then returns a 429 Too Many Requests status code.
+// If the confirmation email fails to be sent, the cooldown isn't started,
+// so that the user may retry at once.
+// If the confirmation email is sent, returns a 200 OK status code.
+func NewSubscribeUserHandler(
+	repo UserRepository,
+	confirmer SubscriptionConfirmer,
+	rateService RateService,
+	cooldown time.Duration,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		sub, err := parseSubscription(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		err = checkPairsSupported(c.Request.Context(), rateService, sub.pairs)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		user, err := repo.FindByEmail(sub.email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		if user != nil && user.IsConfirmed() {
+			c.JSON(http.StatusConflict, "")
+			return
+		}
+		now := time.Now()
+		if user != nil {
+			claimed, err := repo.ClaimConfirmation(user, now, cooldown)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !claimed {
+				c.JSON(http.StatusTooManyRequests, "confirmation was sent recently")
+				return
+			}
+		}
+		user, err = savePendingSubscription(repo, user, sub, now)
+		if err == nil {
+			err = confirmer.SendConfirmation(c.Request.Context(), *user)
+		}
+		if err != nil {
+			err = errors.Join(err, repo.ReleaseConfirmation(user, now))
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, "")
+	}
+}
+
+// NewConfirmSubscriptionHandler is a handler that confirms a pending subscription
+// by a signed token passed as a path parameter, as sent in the confirmation email.
+// If the token is invalid, returns a 400 Bad Request status code.
+// If the token is expired, returns a 410 Gone status code.
+// If the user is not subscribed, returns a 404 Not Found status code.
+// If the subscription is confirmed, returns a 200 OK status code.
+func NewConfirmSubscriptionHandler(
+	repo UserRepository, verifier TokenVerifier,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		email, err := verifier.Verify(c.Param("token"))
+		if errors.Is(err, token.ErrTokenExpired) {
+			c.JSON(http.StatusGone, err.Error())
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		user, err := repo.FindByEmail(email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, "")
+			return
+		}
+		if !user.IsConfirmed() {
+			if err := repo.Confirm(user); err != nil {
+				c.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		c.JSON(http.StatusOK, "")
+	}
+}
+
+func unsubscribe(c *gin.Context, repo UserRepository, email string) {
+	user := &models.User{Email: email}
+	exists, err := repo.Exists(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, "")
+		return
+	}
+	if err := repo.Delete(user); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "")
+}
+
+// NewUnsubscribeUserHandler is a handler that unsubscribes a user by email.
+// The email is passed as a POST parameter and is required.
+// If the user is not subscribed, returns a 404 Not Found status code.
+// If the unsubscription is successful, returns a 200 OK status code.
+func NewUnsubscribeUserHandler(repo UserRepository) func(*gin.Context) {
 	return func(c *gin.Context) {
 		email := c.PostForm("email")
 		if email == "" {
 			c.JSON(http.StatusBadRequest, "email is required")
 			return
 		}
-		user := &models.User{Email: email}
-		exists, err := repo.Exists(user)
+		unsubscribe(c, repo, email)
+	}
+}
+
+// NewUnsubscribeByTokenHandler is a handler that unsubscribes a user by a signed
+// token passed as a path parameter, as used in unsubscribe links of emails
+// once confirmed on their page.
+// If the token is invalid, returns a 400 Bad Request status code.
+// If the user is not subscribed, returns a 404 Not Found status code.
+func NewUnsubscribeByTokenHandler(
+	repo UserRepository, verifier TokenVerifier,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		email, err := verifier.Verify(c.Param("token"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		unsubscribe(c, repo, email)
+	}
+}
+
+// parseAlert parses alert parameters from the POST form of the request.
+func parseAlert(c *gin.Context) (*models.Alert, error) {
+	pairs, err := parseCurrencyPairs(c.PostForm("pair"))
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) != 1 {
+		return nil, errors.New("exactly one currency pair is required")
+	}
+	condition := models.AlertCondition(c.PostForm("condition"))
+	if !condition.IsValid() {
+		return nil, fmt.Errorf("invalid alert condition: %s", condition)
+	}
+	threshold, err := decimal.NewFromString(c.PostForm("threshold"))
+	if err != nil || !threshold.IsPositive() {
+		return nil, errors.New("threshold must be a positive number")
+	}
+	return &models.Alert{
+		CurrencyFrom: pairs[0].CurrencyFrom,
+		CurrencyTo:   pairs[0].CurrencyTo,
+		Condition:    condition,
+		Threshold:    threshold,
+	}, nil
+}
+
+// alertsUser returns the user of the signed alerts token passed as a path parameter.
+// Responds with a 400 Bad Request status code if the token is invalid, and with
+// a 403 Forbidden one if the user hasn't confirmed a subscription.
+func alertsUser(
+	c *gin.Context, repo UserRepository, verifier TokenVerifier,
+) (*models.User, bool) {
+	email, err := verifier.Verify(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	user, err := repo.FindByEmail(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if user == nil || !user.IsConfirmed() {
+		c.JSON(http.StatusForbidden, "confirmed subscription is required")
+		return nil, false
+	}
+	return user, true
+}
+
+// NewListAlertsHandler is a handler that lists rate alerts of the user
+// of the signed alerts token passed as a path parameter, as linked in notifications.
+// If the token is invalid, returns a 400 Bad Request status code.
+// If the user hasn't confirmed a subscription, returns a 403 Forbidden status code.
+// Otherwise returns a 200 OK status code with the alerts.
+func NewListAlertsHandler(
+	userRepo UserRepository, alertRepo AlertRepository, verifier TokenVerifier,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		user, ok := alertsUser(c, userRepo, verifier)
+		if !ok {
+			return
+		}
+		alerts, err := alertRepo.FindByUser(user.ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, err.Error())
 			return
 		}
-		if exists {
-			c.JSON(http.StatusConflict, "")
+		response := make([]alertResponse, 0, len(alerts))
+		for _, alert := range alerts {
+			response = append(response, alertResponse{
+				ID:        alert.ID,
+				Pair:      alert.CurrencyFrom + pairSeparator + alert.CurrencyTo,
+				Condition: alert.Condition,
+				Threshold: json.Number(alert.Threshold.String()),
+				Triggered: alert.Triggered,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"alerts": response})
+	}
+}
+
+// NewCreateAlertHandler is a handler that registers a rate alert for the user
+// of the signed alerts token passed as a path parameter, as linked in notifications.
+// POST parameters are "pair" (defaults to USD/UAH), "condition"
+// (one of "above", "below" and "change") and "threshold", which is a rate for
+// above/below conditions and percents of a daily change for the change one.
+// If the token or the parameters are malformed, returns a 400 Bad Request status code.
+// If the user hasn't confirmed a subscription, returns a 403 Forbidden status code.
+// If the pair is not supported by the fetchers, returns a 422 status code.
+// If the alert is created, returns a 200 OK status code with the alert ID.
+func NewCreateAlertHandler(
+	userRepo UserRepository,
+	alertRepo AlertRepository,
+	rateService RateService,
+	verifier TokenVerifier,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		alert, err := parseAlert(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		user, ok := alertsUser(c, userRepo, verifier)
+		if !ok {
+			return
+		}
+		pair := models.CurrencyPair{CurrencyFrom: alert.CurrencyFrom, CurrencyTo: alert.CurrencyTo}
+		err = checkPairsSupported(c.Request.Context(), rateService, []models.CurrencyPair{pair})
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, err.Error())
 			return
 		}
-		err = repo.Create(user)
+		alert.UserID = user.ID
+		if err := alertRepo.Create(alert); err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": alert.ID})
+	}
+}
+
+// NewDeleteAlertHandler is a handler that deletes the rate alert by the ID passed
+// as a path parameter, of the user of the signed alerts token, as linked in alert emails.
+// If the token or the ID are malformed, returns a 400 Bad Request status code.
+// If the user hasn't confirmed a subscription, returns a 403 Forbidden status code.
+// If the user has no such alert, returns a 404 Not Found status code.
+// If the alert is deleted, returns a 200 OK status code.
+func NewDeleteAlertHandler(
+	userRepo UserRepository, alertRepo AlertRepository, verifier TokenVerifier,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		alertID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "invalid alert ID")
+			return
+		}
+		user, ok := alertsUser(c, userRepo, verifier)
+		if !ok {
+			return
+		}
+		deleted, err := alertRepo.Delete(user.ID, uint(alertID))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, err.Error())
 			return
 		}
+		if !deleted {
+			c.JSON(http.StatusNotFound, "")
+			return
+		}
 		c.JSON(http.StatusOK, "")
 	}
 }
@@ -70,6 +818,35 @@ func NewEngine(client Client) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	r.GET(RatePath, NewGetRateHandler(client.RateService, RateTimeout))
-	r.POST(SubscribePath, NewSubscribeUserHandler(client.UserRepo))
+	r.GET(RateHistoryPath, NewGetRateHistoryHandler(client.HistoryService))
+	r.POST(SubscribePath, NewSubscribeUserHandler(
+		client.UserRepo, client.Confirmer, client.RateService, client.Config.ConfirmationCooldown,
+	))
+	r.GET(
+		ConfirmSubscriptionPath+"/:token",
+		NewConfirmSubscriptionHandler(client.UserRepo, client.ConfirmationTokens),
+	)
+	r.POST(UnsubscribePath, NewUnsubscribeUserHandler(client.UserRepo))
+	alertsPath := AlertsPath + "/:token"
+	r.GET(alertsPath, NewListAlertsHandler(client.UserRepo, client.AlertRepo, client.AlertTokens))
+	r.POST(alertsPath, NewCreateAlertHandler(
+		client.UserRepo, client.AlertRepo, client.RateService, client.AlertTokens,
+	))
+	deleteAlertPath := alertsPath + "/:id/delete"
+	r.GET(deleteAlertPath, NewConfirmationPageHandler("Delete the rate alert?"))
+	// Mail clients send POST for one-click unsubscribe as per RFC 8058
+	r.POST(
+		deleteAlertPath,
+		NewDeleteAlertHandler(client.UserRepo, client.AlertRepo, client.AlertTokens),
+	)
+	r.GET(OutboxPath, NewGetOutboxHandler(client.OutboxRepo))
+	r.GET(ProvidersPath, NewGetProvidersHandler(client.ProviderMonitor))
+	unsubscribePath := UnsubscribePath + "/:token"
+	r.GET(unsubscribePath, NewConfirmationPageHandler("Unsubscribe from rate notifications?"))
+	// Mail clients send POST for one-click unsubscribe as per RFC 8058
+	r.POST(
+		unsubscribePath,
+		NewUnsubscribeByTokenHandler(client.UserRepo, client.UnsubscribeTokens),
+	)
 	return r
 }
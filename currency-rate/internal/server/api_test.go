@@ -2,16 +2,25 @@ package server_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server"
 	serverCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/token"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/pkg/settings"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -25,8 +34,63 @@ type (
 	mockRateService struct {
 		mock.Mock
 	}
+
+	mockConfirmer struct {
+		mock.Mock
+	}
+
+	mockAlertRepository struct {
+		mock.Mock
+	}
+
+	mockHistoryService struct {
+		mock.Mock
+	}
+
+	mockOutboxRepository struct {
+		mock.Mock
+	}
+
+	mockProviderMonitor struct {
+		mock.Mock
+	}
 )
 
+func (m *mockProviderMonitor) Health() []breaker.Health {
+	args := m.Called()
+	return args.Get(0).([]breaker.Health)
+}
+
+func (m *mockOutboxRepository) CountPending() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockHistoryService) History(query service.HistoryQuery) (*service.HistoryPage, error) {
+	args := m.Called(query)
+	return args.Get(0).(*service.HistoryPage), args.Error(1)
+}
+
+func (m *mockAlertRepository) Create(alert *models.Alert) error {
+	args := m.Called(alert)
+	return args.Error(0)
+}
+
+func (m *mockAlertRepository) FindByUser(userID uint) ([]models.Alert, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Alert), args.Error(1)
+}
+
+func (m *mockAlertRepository) Delete(userID, alertID uint) (bool, error) {
+	args := m.Called(userID, alertID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockConfirmer) SendConfirmation(ctx context.Context, user models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
 func (m *mockRateService) FetchRate(ctx context.Context, from, to string) (*models.Rate, error) {
 	args := m.Called(ctx, from, to)
 	return args.Get(0).(*models.Rate), args.Error(1)
@@ -47,9 +111,61 @@ func (m *mockUserRepository) Exists(user *models.User) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockUserRepository) FindByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *mockUserRepository) SetPairs(user *models.User, pairs []models.CurrencyPair) error {
+	args := m.Called(user, pairs)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) SetLocale(user *models.User, locale models.Locale) error {
+	args := m.Called(user, locale)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) SetSchedule(user *models.User, schedule models.Schedule) error {
+	args := m.Called(user, schedule)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) ClaimConfirmation(
+	user *models.User, at time.Time, cooldown time.Duration,
+) (bool, error) {
+	args := m.Called(user, at, cooldown)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockUserRepository) ReleaseConfirmation(user *models.User, claimedAt time.Time) error {
+	args := m.Called(user, claimedAt)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) Confirm(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) Delete(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+type rateResponse struct {
+	CurrencyFrom string      `json:"from"`
+	CurrencyTo   string      `json:"to"`
+	Rate         json.Number `json:"rate"`
+	Timestamp    string      `json:"timestamp"`
+	Cache        string      `json:"cache"`
+}
+
 func TestGetRate(t *testing.T) {
 	mockService := new(mockRateService)
-	mockedRate := &models.Rate{Rate: 27.5}
+	mockedRate := &models.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+	}
 	mockService.On("FetchRate", mock.Anything, "USD", "UAH").Return(mockedRate, nil)
 	engine := server.NewEngine(server.Client{
 		Config:      serverCfg.Config{Port: "8080"},
@@ -60,9 +176,165 @@ func TestGetRate(t *testing.T) {
 	rr := httptest.NewRecorder()
 	engine.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
-	rate, err := strconv.ParseFloat(rr.Body.String(), 32)
-	require.NoError(t, err)
-	assert.InDelta(t, rate, mockedRate.Rate, 0.001)
+	var response rateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "USD", response.CurrencyFrom)
+	assert.Equal(t, "UAH", response.CurrencyTo)
+	assert.Equal(t, json.Number("27.5"), response.Rate)
+	assert.NotEmpty(t, response.Timestamp)
+}
+
+func TestGetRateCache(t *testing.T) {
+	// Arrange
+	mockService := new(mockRateService)
+	mockedRate := &models.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+		Cache: rate.CacheStale,
+	}
+	mockService.On("FetchRate", mock.Anything, "USD", "UAH").Return(mockedRate, nil)
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		RateService: mockService,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.RatePath, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "STALE", rr.Header().Get("X-Cache"))
+	var response rateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "STALE", response.Cache)
+}
+
+func TestGetRateLegs(t *testing.T) {
+	// Arrange
+	mockService := new(mockRateService)
+	mockedRate := &models.Rate{
+		CurrencyFrom: "EUR", CurrencyTo: "USD", Rate: decimal.RequireFromString("1.1"),
+		Created:  time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Unix(),
+		Provider: "nbu",
+		Legs: []rate.Leg{
+			{CurrencyFrom: "EUR", CurrencyTo: "UAH", Rate: decimal.RequireFromString("44"), Provider: "nbu"},
+			{
+				CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("40"),
+				Provider: "nbu", Inverted: true,
+			},
+		},
+	}
+	mockService.On("FetchRate", mock.Anything, "EUR", "USD").Return(mockedRate, nil)
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		RateService: mockService,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.RatePath+"?from=EUR&to=USD", nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{
+		"from": "EUR",
+		"to": "USD",
+		"rate": 1.1,
+		"timestamp": "2024-06-01T12:00:00Z",
+		"provider": "nbu",
+		"legs": [
+			{"from": "EUR", "to": "UAH", "rate": 44, "provider": "nbu", "inverted": false},
+			{"from": "USD", "to": "UAH", "rate": 40, "provider": "nbu", "inverted": true}
+		]
+	}`, rr.Body.String())
+}
+
+func TestGetRateQueryParameters(t *testing.T) {
+	testCases := []struct {
+		name         string
+		query        string
+		from         string
+		to           string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "pair",
+			query:        "?from=EUR&to=PLN",
+			from:         "EUR",
+			to:           "PLN",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "lower-case",
+			query:        "?from=eur",
+			from:         "EUR",
+			to:           "UAH",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unsupported",
+			query:        "?from=XYZ&to=UAH",
+			from:         "XYZ",
+			to:           "UAH",
+			err:          fmt.Errorf("wrapped: %w", rate.ErrUnsupportedCurrency),
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "invalid-length",
+			query:        "?from=EURO",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid-characters",
+			query:        "?to=U1H",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(mockRateService)
+			var mockedRate *models.Rate
+			if tc.err == nil {
+				mockedRate = &models.Rate{
+					CurrencyFrom: tc.from, CurrencyTo: tc.to, Rate: decimal.RequireFromString("4.3"),
+				}
+			}
+			mockService.On("FetchRate", mock.Anything, tc.from, tc.to).Return(mockedRate, tc.err)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				RateService: mockService,
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodGet, server.RatePath+tc.query, nil)
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			var response rateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Equal(t, tc.from, response.CurrencyFrom)
+			assert.Equal(t, tc.to, response.CurrencyTo)
+		})
+	}
+}
+
+// supportedRates is a rate service supporting every currency but XYZ.
+func supportedRates() *mockRateService {
+	unsupported := fmt.Errorf("wrapped: %w", rate.ErrUnsupportedCurrency)
+	rateService := new(mockRateService)
+	rateService.On("FetchRate", mock.Anything, "XYZ", mock.Anything).Return(
+		(*models.Rate)(nil), unsupported,
+	)
+	rateService.On("FetchRate", mock.Anything, mock.Anything, "XYZ").Return(
+		(*models.Rate)(nil), unsupported,
+	)
+	rateService.On("FetchRate", mock.Anything, mock.Anything, mock.Anything).Return(
+		&models.Rate{}, nil,
+	)
+	return rateService
 }
 
 func TestSubscribeUserNoEmail(t *testing.T) {
@@ -79,11 +351,27 @@ func TestSubscribeUserNoEmail(t *testing.T) {
 
 func TestSubscribeUser(t *testing.T) {
 	mockRepo := new(mockUserRepository)
-	mockRepo.On("Exists", mock.Anything).Return(false, nil).Once()
-	mockRepo.On("Create", mock.Anything).Return(nil).Once()
+	user := models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+		Subscriptions: []models.Subscription{
+			{Pair: models.CurrencyPair{CurrencyFrom: "USD", CurrencyTo: "UAH"}},
+		},
+	}
+	mockRepo.On("FindByEmail", "example@gmail.com").Return((*models.User)(nil), nil).Once()
+	var created models.User
+	mockRepo.On("Create", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		created = *args.Get(0).(*models.User)
+	}).Return(nil).Once()
+	confirmer := new(mockConfirmer)
+	confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil).Once()
 	engine := server.NewEngine(server.Client{
-		Config:   serverCfg.Config{Port: "8080"},
-		UserRepo: mockRepo,
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
 	})
 
 	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
@@ -93,14 +381,448 @@ func TestSubscribeUser(t *testing.T) {
 	rr := httptest.NewRecorder()
 	engine.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+	confirmer.AssertExpectations(t)
+	// New users are created with the confirmation claimed
+	assert.NotZero(t, created.ConfirmationSentAt)
+	user.ConfirmationSentAt = created.ConfirmationSentAt
+	assert.Equal(t, user, created)
+	confirmer.AssertCalled(t, "SendConfirmation", mock.Anything, user)
+}
+
+func TestSubscribeUserInvalidEmail(t *testing.T) {
+	engine := server.NewEngine(server.Client{
+		Config:   serverCfg.Config{Port: "8080"},
+		UserRepo: &mockUserRepository{},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{
+		"email": {"example.gmail.com"},
+	}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSubscribeUserPairs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		pairs         string
+		expectedPairs []string
+		expectedCode  int
+	}{
+		{
+			name:          "multiple",
+			pairs:         "USD/UAH,EUR/UAH",
+			expectedPairs: []string{"USD/UAH", "EUR/UAH"},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "normalized",
+			pairs:         "usd/uah, EUR/uah,USD/UAH",
+			expectedPairs: []string{"USD/UAH", "EUR/UAH"},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "no-separator",
+			pairs:        "USDUAH",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid-code",
+			pairs:        "USD/UAH,EURO/UAH",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "empty-code",
+			pairs:        "USD/",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "same-currency",
+			pairs:        "USD/UAH,USD/USD",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported",
+			pairs:        "USD/UAH,XYZ/UAH",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			var created *models.User
+			mockRepo := new(mockUserRepository)
+			mockRepo.On("FindByEmail", "example@gmail.com").Return((*models.User)(nil), nil)
+			mockRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+				created = args.Get(0).(*models.User)
+			}).Return(nil)
+			confirmer := new(mockConfirmer)
+			confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				UserRepo:    mockRepo,
+				Confirmer:   confirmer,
+				RateService: supportedRates(),
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+			req.PostForm = map[string][]string{
+				"email": {"example@gmail.com"},
+				"pairs": {tc.pairs},
+			}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode != http.StatusOK {
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+				return
+			}
+			require.NotNil(t, created)
+			pairs := make([]string, 0, len(created.Subscriptions))
+			for _, pair := range created.Pairs() {
+				pairs = append(pairs, pair.String())
+			}
+			assert.Equal(t, tc.expectedPairs, pairs)
+		})
+	}
+}
+
+func TestSubscribeUserPending(t *testing.T) {
+	mockRepo := new(mockUserRepository)
+	user := &models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+	}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("ClaimConfirmation", user, mock.Anything, time.Duration(0)).Return(true, nil).Once()
+	mockRepo.On("SetPairs", user, []models.CurrencyPair{
+		{CurrencyFrom: "EUR", CurrencyTo: "UAH"},
+	}).Return(nil).Once()
+	confirmer := new(mockConfirmer)
+	confirmer.On("SendConfirmation", mock.Anything, *user).Return(nil).Once()
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{
+		"email": {user.Email},
+		"pairs": {"EUR/UAH"},
+	}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockRepo.AssertNotCalled(t, "SetLocale", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	confirmer.AssertExpectations(t)
+}
+
+func TestSubscribeUserLocale(t *testing.T) {
+	testCases := []struct {
+		name           string
+		locale         string
+		expectedLocale models.Locale
+		expectedCode   int
+	}{
+		{
+			name:           "default",
+			expectedLocale: models.LocaleEnglish,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "ukrainian",
+			locale:         "uk",
+			expectedLocale: models.LocaleUkrainian,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "region",
+			locale:         "uk_UA",
+			expectedLocale: models.LocaleUkrainian,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:         "unsupported",
+			locale:       "de",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			var created *models.User
+			mockRepo := new(mockUserRepository)
+			mockRepo.On("FindByEmail", "example@gmail.com").Return((*models.User)(nil), nil)
+			mockRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+				created = args.Get(0).(*models.User)
+			}).Return(nil)
+			confirmer := new(mockConfirmer)
+			confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				UserRepo:    mockRepo,
+				Confirmer:   confirmer,
+				RateService: supportedRates(),
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+			req.PostForm = map[string][]string{
+				"email":  {"example@gmail.com"},
+				"locale": {tc.locale},
+			}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode != http.StatusOK {
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+				return
+			}
+			require.NotNil(t, created)
+			assert.Equal(t, tc.expectedLocale, created.Locale)
+		})
+	}
+}
+
+func TestSubscribeUserPendingLocale(t *testing.T) {
+	mockRepo := new(mockUserRepository)
+	user := &models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+	}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("ClaimConfirmation", user, mock.Anything, time.Duration(0)).Return(true, nil).Once()
+	mockRepo.On("SetPairs", user, mock.Anything).Return(nil).Once()
+	mockRepo.On("SetLocale", user, models.LocaleUkrainian).Return(nil).Once()
+	confirmer := new(mockConfirmer)
+	confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil).Once()
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{
+		"email":  {user.Email},
+		"locale": {"uk"},
+	}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+	confirmer.AssertExpectations(t)
+}
+
+func TestSubscribeUserSchedule(t *testing.T) {
+	testCases := []struct {
+		name             string
+		form             map[string][]string
+		expectedSchedule models.Schedule
+		expectedCode     int
+	}{
+		{
+			name:             "default",
+			form:             map[string][]string{},
+			expectedSchedule: models.DefaultSchedule(),
+			expectedCode:     http.StatusOK,
+		},
+		{
+			name: "custom",
+			form: map[string][]string{
+				"deliveryTime": {"09:30"},
+				"timezone":     {"Europe/Kyiv"},
+				"frequency":    {"weekdays"},
+			},
+			expectedSchedule: models.Schedule{
+				DeliveryTime: "09:30",
+				Timezone:     "Europe/Kyiv",
+				Frequency:    models.FrequencyWeekdays,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid time",
+			form:         map[string][]string{"deliveryTime": {"9am"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid timezone",
+			form:         map[string][]string{"timezone": {"Kyiv"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid frequency",
+			form:         map[string][]string{"frequency": {"monthly"}},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			var created *models.User
+			mockRepo := new(mockUserRepository)
+			mockRepo.On("FindByEmail", "example@gmail.com").Return((*models.User)(nil), nil)
+			mockRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+				created = args.Get(0).(*models.User)
+			}).Return(nil)
+			confirmer := new(mockConfirmer)
+			confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				UserRepo:    mockRepo,
+				Confirmer:   confirmer,
+				RateService: supportedRates(),
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+			req.PostForm = tc.form
+			req.PostForm["email"] = []string{"example@gmail.com"}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode != http.StatusOK {
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+				return
+			}
+			require.NotNil(t, created)
+			assert.Equal(t, tc.expectedSchedule, created.Schedule)
+		})
+	}
+}
+
+func TestSubscribeUserPendingSchedule(t *testing.T) {
+	mockRepo := new(mockUserRepository)
+	user := &models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+	}
+	schedule := models.Schedule{
+		DeliveryTime: "08:00", Timezone: "Europe/Kyiv", Frequency: models.FrequencyWeekly,
+	}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("ClaimConfirmation", user, mock.Anything, time.Duration(0)).Return(true, nil).Once()
+	mockRepo.On("SetPairs", user, mock.Anything).Return(nil).Once()
+	mockRepo.On("SetSchedule", user, schedule).Return(nil).Once()
+	confirmer := new(mockConfirmer)
+	confirmer.On("SendConfirmation", mock.Anything, mock.Anything).Return(nil).Once()
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{
+		"email":        {user.Email},
+		"deliveryTime": {"08:00"},
+		"timezone":     {"Europe/Kyiv"},
+		"frequency":    {"weekly"},
+	}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetLocale", mock.Anything, mock.Anything)
+	confirmer.AssertExpectations(t)
+}
+
+func TestSubscribeUserPendingCooldown(t *testing.T) {
+	// Arrange
+	mockRepo := new(mockUserRepository)
+	user := &models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+	}
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("ClaimConfirmation", user, mock.Anything, 5*time.Minute).Return(false, nil).Once()
+	confirmer := new(mockConfirmer)
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080", ConfirmationCooldown: 5 * time.Minute},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{
+		"email": {user.Email},
+		"pairs": {"EUR/UAH"},
+	}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetPairs", mock.Anything, mock.Anything)
+	confirmer.AssertNotCalled(t, "SendConfirmation", mock.Anything, mock.Anything)
+}
+
+func TestSubscribeUserConfirmationFailed(t *testing.T) {
+	// Arrange
+	mockRepo := new(mockUserRepository)
+	user := &models.User{
+		Email:    "example@gmail.com",
+		Status:   models.StatusPending,
+		Locale:   models.LocaleEnglish,
+		Schedule: models.DefaultSchedule(),
+	}
+	var claimedAt time.Time
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("ClaimConfirmation", user, mock.Anything, 5*time.Minute).Run(
+		func(args mock.Arguments) { claimedAt = args.Get(1).(time.Time) },
+	).Return(true, nil).Once()
+	mockRepo.On("SetPairs", user, mock.Anything).Return(nil).Once()
+	mockRepo.On("ReleaseConfirmation", user, mock.MatchedBy(func(at time.Time) bool {
+		return at.Equal(claimedAt)
+	})).Return(nil).Once()
+	confirmer := new(mockConfirmer)
+	confirmer.On("SendConfirmation", mock.Anything, *user).Return(errors.New("outbox error")).Once()
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080", ConfirmationCooldown: 5 * time.Minute},
+		UserRepo:    mockRepo,
+		Confirmer:   confirmer,
+		RateService: supportedRates(),
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
+	req.PostForm = map[string][]string{"email": {user.Email}}
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	mockRepo.AssertExpectations(t)
+	confirmer.AssertExpectations(t)
 }
 
 func TestSubscribeUserAlreadySubscribed(t *testing.T) {
 	mockRepo := new(mockUserRepository)
-	mockRepo.On("Exists", mock.Anything).Return(true, nil).Once()
+	mockRepo.On("FindByEmail", "example@gmail.com").Return(&models.User{
+		Email: "example@gmail.com", Status: models.StatusConfirmed,
+	}, nil).Once()
 	engine := server.NewEngine(server.Client{
-		Config:   serverCfg.Config{Port: "8080"},
-		UserRepo: mockRepo,
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    mockRepo,
+		RateService: supportedRates(),
 	})
 
 	req := httptest.NewRequest(http.MethodPost, server.SubscribePath, nil)
@@ -112,6 +834,608 @@ func TestSubscribeUserAlreadySubscribed(t *testing.T) {
 	assert.Equal(t, http.StatusConflict, recorder.Code)
 }
 
+func TestConfirmSubscription(t *testing.T) {
+	signer := token.NewSigner("secret", "confirm")
+	testCases := []struct {
+		name         string
+		token        string
+		user         *models.User
+		expectedCode int
+	}{
+		{
+			name:         "pending",
+			token:        signer.SignWithExpiry("example@gmail.com", time.Now().Add(time.Hour)),
+			user:         &models.User{Email: "example@gmail.com", Status: models.StatusPending},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "already-confirmed",
+			token:        signer.SignWithExpiry("example@gmail.com", time.Now().Add(time.Hour)),
+			user:         &models.User{Email: "example@gmail.com", Status: models.StatusConfirmed},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not-subscribed",
+			token:        signer.SignWithExpiry("example@gmail.com", time.Now().Add(time.Hour)),
+			user:         nil,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "expired",
+			token:        signer.SignWithExpiry("example@gmail.com", time.Now().Add(-time.Hour)),
+			expectedCode: http.StatusGone,
+		},
+		{
+			name:         "invalid",
+			token:        token.NewSigner("secret", "unsubscribe").Sign("example@gmail.com"),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mockUserRepository)
+			mockRepo.On("FindByEmail", "example@gmail.com").Return(tc.user, nil).Once()
+			mockRepo.On("Confirm", tc.user).Return(nil).Once()
+			engine := server.NewEngine(server.Client{
+				Config:             serverCfg.Config{Port: "8080"},
+				UserRepo:           mockRepo,
+				ConfirmationTokens: signer,
+			})
+			// Act
+			req := httptest.NewRequest(
+				http.MethodGet, server.ConfirmSubscriptionPath+"/"+tc.token, nil,
+			)
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.user != nil && tc.user.Status == models.StatusPending {
+				mockRepo.AssertCalled(t, "Confirm", tc.user)
+			} else {
+				mockRepo.AssertNotCalled(t, "Confirm", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestConfirmationLinks(t *testing.T) {
+	signer := token.NewSigner("secret", "confirm")
+	links := server.ConfirmationLinks{
+		BaseURL: "https://example.com", Signer: signer, TTL: time.Hour,
+	}
+	url := links.ConfirmationURL(models.User{Email: "example@gmail.com"})
+	prefix := "https://example.com" + server.ConfirmSubscriptionPath + "/"
+	require.True(t, strings.HasPrefix(url, prefix))
+	email, err := signer.Verify(strings.TrimPrefix(url, prefix))
+	require.NoError(t, err)
+	assert.Equal(t, "example@gmail.com", email)
+}
+
+func TestUnsubscribeUser(t *testing.T) {
+	testCases := []struct {
+		name         string
+		email        string
+		exists       bool
+		expectedCode int
+	}{
+		{
+			name:         "success",
+			email:        "example@gmail.com",
+			exists:       true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not-subscribed",
+			email:        "example@gmail.com",
+			exists:       false,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "no-email",
+			email:        "",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mockUserRepository)
+			user := &models.User{Email: tc.email}
+			mockRepo.On("Exists", user).Return(tc.exists, nil).Once()
+			mockRepo.On("Delete", user).Return(nil).Once()
+			engine := server.NewEngine(server.Client{
+				Config:   serverCfg.Config{Port: "8080"},
+				UserRepo: mockRepo,
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.UnsubscribePath, nil)
+			req.PostForm = map[string][]string{
+				"email": {tc.email},
+			}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode == http.StatusOK {
+				mockRepo.AssertCalled(t, "Delete", user)
+			} else {
+				mockRepo.AssertNotCalled(t, "Delete", user)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeUserByToken(t *testing.T) {
+	signer := token.NewSigner("secret", "unsubscribe")
+	testCases := []struct {
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "confirmed",
+			token:        signer.Sign("example@gmail.com"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid-token",
+			token:        token.NewSigner("other", "unsubscribe").Sign("example@gmail.com"),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mockUserRepository)
+			user := &models.User{Email: "example@gmail.com"}
+			mockRepo.On("Exists", user).Return(true, nil).Once()
+			mockRepo.On("Delete", user).Return(nil).Once()
+			engine := server.NewEngine(server.Client{
+				Config:            serverCfg.Config{Port: "8080"},
+				UserRepo:          mockRepo,
+				UnsubscribeTokens: signer,
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.UnsubscribePath+"/"+tc.token, nil)
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestUnsubscribePage(t *testing.T) {
+	// Arrange
+	mockRepo := new(mockUserRepository)
+	signer := token.NewSigner("secret", "unsubscribe")
+	engine := server.NewEngine(server.Client{
+		Config:            serverCfg.Config{Port: "8080"},
+		UserRepo:          mockRepo,
+		UnsubscribeTokens: signer,
+	})
+	path := server.UnsubscribePath + "/" + signer.Sign("example@gmail.com")
+	// Act
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<form method="post">`)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestUnsubscribeLinks(t *testing.T) {
+	signer := token.NewSigner("secret", "unsubscribe")
+	links := server.UnsubscribeLinks{BaseURL: "https://example.com/", Signer: signer}
+	url := links.UnsubscribeURL(models.User{Email: "example@gmail.com"})
+	assert.Equal(t, "https://example.com/unsubscribe/"+signer.Sign("example@gmail.com"), url)
+}
+
+func alertsSigner() *token.Signer {
+	return token.NewSigner("secret", "alerts")
+}
+
+func confirmedUser() *models.User {
+	user := &models.User{Email: "example@gmail.com", Status: models.StatusConfirmed}
+	user.ID = 7
+	return user
+}
+
+func TestCreateAlert(t *testing.T) {
+	confirmed := confirmedUser()
+	validToken := alertsSigner().Sign("example@gmail.com")
+	testCases := []struct {
+		name          string
+		token         string
+		form          map[string][]string
+		user          *models.User
+		expectedCode  int
+		expectedAlert *models.Alert
+	}{
+		{
+			name: "above",
+			form: map[string][]string{
+				"pair": {"USD/UAH"}, "condition": {"above"}, "threshold": {"42"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusOK,
+			expectedAlert: &models.Alert{
+				UserID: 7, CurrencyFrom: "USD", CurrencyTo: "UAH",
+				Condition: models.AlertAbove, Threshold: decimal.RequireFromString("42"),
+			},
+		},
+		{
+			name: "change-default-pair",
+			form: map[string][]string{
+				"condition": {"change"}, "threshold": {"1.5"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusOK,
+			expectedAlert: &models.Alert{
+				UserID: 7, CurrencyFrom: "USD", CurrencyTo: "UAH",
+				Condition: models.AlertChange, Threshold: decimal.RequireFromString("1.5"),
+			},
+		},
+		{
+			name: "not-subscribed",
+			form: map[string][]string{
+				"condition": {"below"}, "threshold": {"40"},
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name: "pending",
+			form: map[string][]string{
+				"condition": {"below"}, "threshold": {"40"},
+			},
+			user:         &models.User{Email: "example@gmail.com", Status: models.StatusPending},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name: "invalid-condition",
+			form: map[string][]string{
+				"condition": {"equals"}, "threshold": {"40"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid-threshold",
+			form: map[string][]string{
+				"condition": {"above"}, "threshold": {"-1"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid-token",
+			token: token.NewSigner("secret", "unsubscribe").Sign("example@gmail.com"),
+			form: map[string][]string{
+				"condition": {"above"}, "threshold": {"40"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "same-currency",
+			form: map[string][]string{
+				"pair": {"UAH/UAH"}, "condition": {"above"}, "threshold": {"40"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "unsupported-pair",
+			form: map[string][]string{
+				"pair": {"USD/XYZ"}, "condition": {"above"}, "threshold": {"40"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "multiple-pairs",
+			form: map[string][]string{
+				"pair":      {"USD/UAH,EUR/UAH"},
+				"condition": {"above"}, "threshold": {"40"},
+			},
+			user:         confirmed,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			userRepo := new(mockUserRepository)
+			userRepo.On("FindByEmail", "example@gmail.com").Return(tc.user, nil)
+			alertRepo := new(mockAlertRepository)
+			alertRepo.On("Create", mock.Anything).Return(nil)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				UserRepo:    userRepo,
+				AlertRepo:   alertRepo,
+				RateService: supportedRates(),
+				AlertTokens: alertsSigner(),
+			})
+			alertsToken := tc.token
+			if alertsToken == "" {
+				alertsToken = validToken
+			}
+			// Act
+			req := httptest.NewRequest(http.MethodPost, server.AlertsPath+"/"+alertsToken, nil)
+			req.PostForm = tc.form
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedAlert != nil {
+				alertRepo.AssertCalled(t, "Create", tc.expectedAlert)
+			} else {
+				alertRepo.AssertNotCalled(t, "Create", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListAlerts(t *testing.T) {
+	// Arrange
+	user := confirmedUser()
+	userRepo := new(mockUserRepository)
+	userRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	alertRepo := new(mockAlertRepository)
+	alertRepo.On("FindByUser", user.ID).Return([]models.Alert{{
+		ID: 3, CurrencyFrom: "USD", CurrencyTo: "UAH",
+		Condition: models.AlertAbove, Threshold: decimal.RequireFromString("42.5"),
+		Triggered: true,
+	}}, nil).Once()
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		UserRepo:    userRepo,
+		AlertRepo:   alertRepo,
+		AlertTokens: alertsSigner(),
+	})
+	// Act
+	req := httptest.NewRequest(
+		http.MethodGet, server.AlertsPath+"/"+alertsSigner().Sign(user.Email), nil,
+	)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"alerts": [{
+		"id": 3, "pair": "USD/UAH", "condition": "above", "threshold": 42.5, "triggered": true
+	}]}`, rr.Body.String())
+	alertRepo.AssertExpectations(t)
+}
+
+func TestDeleteAlert(t *testing.T) {
+	testCases := []struct {
+		name         string
+		id           string
+		deleted      bool
+		expectedCode int
+	}{
+		{name: "deleted", id: "3", deleted: true, expectedCode: http.StatusOK},
+		{name: "not-found", id: "3", expectedCode: http.StatusNotFound},
+		{name: "invalid-id", id: "first", expectedCode: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			user := confirmedUser()
+			userRepo := new(mockUserRepository)
+			userRepo.On("FindByEmail", user.Email).Return(user, nil)
+			alertRepo := new(mockAlertRepository)
+			alertRepo.On("Delete", user.ID, uint(3)).Return(tc.deleted, nil)
+			engine := server.NewEngine(server.Client{
+				Config:      serverCfg.Config{Port: "8080"},
+				UserRepo:    userRepo,
+				AlertRepo:   alertRepo,
+				AlertTokens: alertsSigner(),
+			})
+			path := server.AlertsPath + "/" + alertsSigner().Sign(user.Email) +
+				"/" + tc.id + "/delete"
+			// Act
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestDeleteAlertPage(t *testing.T) {
+	// Arrange
+	alertRepo := new(mockAlertRepository)
+	engine := server.NewEngine(server.Client{
+		Config:      serverCfg.Config{Port: "8080"},
+		AlertRepo:   alertRepo,
+		AlertTokens: alertsSigner(),
+	})
+	path := server.AlertsPath + "/" + alertsSigner().Sign("example@gmail.com") + "/3/delete"
+	// Act
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<form method="post">`)
+	alertRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestAlertLinks(t *testing.T) {
+	signer := alertsSigner()
+	links := server.AlertLinks{BaseURL: "https://example.com/", Signer: signer}
+	alert := models.Alert{ID: 3, User: models.User{Email: "example@gmail.com"}}
+	alertsURL := "https://example.com/alerts/" + signer.Sign("example@gmail.com")
+	assert.Equal(t, alertsURL, links.AlertsURL(alert.User))
+	assert.Equal(t, alertsURL+"/3/delete", links.DeleteAlertURL(alert))
+}
+
+func TestGetRateHistory(t *testing.T) {
+	// Arrange
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(48 * time.Hour)
+	query := service.HistoryQuery{
+		CurrencyFrom: "EUR",
+		CurrencyTo:   "UAH",
+		Since:        since,
+		Until:        until,
+		Interval:     time.Hour,
+		Limit:        2,
+		Offset:       4,
+	}
+	mockService := new(mockHistoryService)
+	mockService.On("History", query).Return(&service.HistoryPage{
+		Candles: []service.Candle{{
+			Time:  since,
+			Open:  decimal.RequireFromString("44"),
+			High:  decimal.RequireFromString("45.12345678"),
+			Low:   decimal.RequireFromString("43.5"),
+			Close: decimal.RequireFromString("44.5"),
+		}},
+		Total: 5,
+	}, nil)
+	engine := server.NewEngine(server.Client{
+		Config:         serverCfg.Config{Port: "8080"},
+		HistoryService: mockService,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.RateHistoryPath+
+		"?from=eur&to=UAH&since=2024-07-01T00:00:00Z&until=2024-07-03T00:00:00Z"+
+		"&interval=hour&limit=2&offset=4", nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{
+		"from": "EUR", "to": "UAH", "interval": "hour", "total": 5, "limit": 2, "offset": 4,
+		"items": [{
+			"time": "2024-07-01T00:00:00Z",
+			"open": 44, "high": 45.12345678, "low": 43.5, "close": 44.5
+		}]
+	}`, rr.Body.String())
+}
+
+func TestGetRateHistoryInvalidQuery(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{name: "currency", query: "from=US"},
+		{name: "since", query: "since=yesterday"},
+		{name: "until", query: "until=2024-07-01"},
+		{name: "range", query: "since=2024-07-02T00:00:00Z&until=2024-07-01T00:00:00Z"},
+		{name: "interval", query: "interval=week"},
+		{name: "limit", query: "limit=1001"},
+		{name: "zero-limit", query: "limit=0"},
+		{name: "offset", query: "offset=-1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(mockHistoryService)
+			engine := server.NewEngine(server.Client{
+				Config:         serverCfg.Config{Port: "8080"},
+				HistoryService: mockService,
+			})
+			// Act
+			req := httptest.NewRequest(http.MethodGet, server.RateHistoryPath+"?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			mockService.AssertNotCalled(t, "History", mock.Anything)
+		})
+	}
+}
+
+func TestGetOutbox(t *testing.T) {
+	// Arrange
+	mockRepo := new(mockOutboxRepository)
+	mockRepo.On("CountPending").Return(int64(3), nil)
+	engine := server.NewEngine(server.Client{
+		Config:     serverCfg.Config{Port: "8080"},
+		OutboxRepo: mockRepo,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.OutboxPath, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"backlog": 3}`, rr.Body.String())
+}
+
+func TestGetOutboxError(t *testing.T) {
+	// Arrange
+	mockRepo := new(mockOutboxRepository)
+	mockRepo.On("CountPending").Return(int64(0), assert.AnError)
+	engine := server.NewEngine(server.Client{
+		Config:     serverCfg.Config{Port: "8080"},
+		OutboxRepo: mockRepo,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.OutboxPath, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestGetProviders(t *testing.T) {
+	// Arrange
+	lastSuccess := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mockMonitor := new(mockProviderMonitor)
+	mockMonitor.On("Health").Return([]breaker.Health{
+		{
+			Provider:  "currencybeacon",
+			State:     breaker.StateOpen,
+			LastError: errors.New("fetching url: 503 Service Unavailable"),
+			Requests:  4,
+			Failures:  3,
+			ErrorRate: 0.75,
+		},
+		{
+			Provider:    "nbu",
+			State:       breaker.StateClosed,
+			LastSuccess: lastSuccess,
+			Requests:    2,
+		},
+	})
+	engine := server.NewEngine(server.Client{
+		Config:          serverCfg.Config{Port: "8080"},
+		ProviderMonitor: mockMonitor,
+	})
+	// Act
+	req := httptest.NewRequest(http.MethodGet, server.ProvidersPath, nil)
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"providers": [
+		{
+			"provider": "currencybeacon",
+			"state": "open",
+			"lastSuccess": null,
+			"lastError": "fetching url: 503 Service Unavailable",
+			"requests": 4,
+			"failures": 3,
+			"errorRate": 0.75
+		},
+		{
+			"provider": "nbu",
+			"state": "closed",
+			"lastSuccess": "2024-06-01T12:00:00Z",
+			"requests": 2,
+			"failures": 0,
+			"errorRate": 0
+		}
+	]}`, rr.Body.String())
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.ReleaseMode)
 	_ = settings.InitSettings()
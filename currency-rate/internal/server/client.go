@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate/breaker"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
 )
 
 const RateTimeout = 3 * time.Second
@@ -14,8 +16,34 @@ type RateService interface {
 	FetchRate(ctx context.Context, from, to string) (*models.Rate, error)
 }
 
+type HistoryService interface {
+	History(query service.HistoryQuery) (*service.HistoryPage, error)
+}
+
+// ProviderMonitor reports the health of the rate providers.
+type ProviderMonitor interface {
+	Health() []breaker.Health
+}
+
+type TokenVerifier interface {
+	Verify(token string) (string, error)
+}
+
+// SubscriptionConfirmer sends the user a request to confirm the subscription.
+type SubscriptionConfirmer interface {
+	SendConfirmation(ctx context.Context, user models.User) error
+}
+
 type Client struct {
-	Config      config.Config
-	RateService RateService
-	UserRepo    UserRepository
+	Config             config.Config
+	RateService        RateService
+	HistoryService     HistoryService
+	UserRepo           UserRepository
+	AlertRepo          AlertRepository
+	OutboxRepo         OutboxRepository
+	UnsubscribeTokens  TokenVerifier
+	ConfirmationTokens TokenVerifier
+	AlertTokens        TokenVerifier
+	Confirmer          SubscriptionConfirmer
+	ProviderMonitor    ProviderMonitor
 }
@@ -1,20 +1,88 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"time"
 )
 
+const (
+	defaultConfirmationTTL      = 24 * time.Hour
+	defaultSchedulerInterval    = time.Minute
+	defaultConfirmationCooldown = 5 * time.Minute
+	// MinSecretKeyLength is the least number of bytes of the key tokens are signed with.
+	MinSecretKeyLength = 32
+)
+
+var ErrSecretKeyNotSet = errors.New("SECRET_KEY is not set")
+
 type Config struct {
 	Port string
+	// BaseURL is a public URL of the API used to build links in emails.
+	BaseURL string
+	// SecretKey is used to sign tokens, e.g. for unsubscribe links.
+	SecretKey string
+	// ConfirmationTTL is a lifetime of subscription confirmation links.
+	ConfirmationTTL time.Duration
+	// ConfirmationCooldown is the least time between confirmation emails to the same address.
+	ConfirmationCooldown time.Duration
+	// SchedulerInterval is how often subscribers due for notifications are looked up.
+	SchedulerInterval time.Duration
 }
 
-func NewFromEnv() Config {
-	port := os.Getenv("PORT")
-	if port == "" {
-		slog.Error("PORT is not set")
+// Validate checks that tokens can't be forged because of an empty or short secret key.
+func (c Config) Validate() error {
+	if c.SecretKey == "" {
+		return ErrSecretKeyNotSet
+	}
+	if len(c.SecretKey) < MinSecretKeyLength {
+		return fmt.Errorf(
+			"SECRET_KEY is %d bytes long, at least %d required",
+			len(c.SecretKey), MinSecretKeyLength,
+		)
+	}
+	return nil
+}
+
+func getOrError(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		slog.Error("value is not set", slog.Any("key", key))
 	}
+	return value
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func NewFromEnv() Config {
 	return Config{
-		Port: port,
+		Port:            getOrError("PORT"),
+		BaseURL:         getOrError("BASE_URL"),
+		SecretKey:       getOrError("SECRET_KEY"),
+		ConfirmationTTL: durationOrDefault("CONFIRMATION_TTL", defaultConfirmationTTL),
+		ConfirmationCooldown: durationOrDefault(
+			"CONFIRMATION_COOLDOWN", defaultConfirmationCooldown,
+		),
+		SchedulerInterval: durationOrDefault(
+			"SCHEDULER_INTERVAL", defaultSchedulerInterval,
+		),
 	}
 }
@@ -0,0 +1,35 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		secretKey     string
+		expectedError bool
+	}{
+		{name: "empty", secretKey: "", expectedError: true},
+		{name: "short", secretKey: "secret", expectedError: true},
+		{name: "long-enough", secretKey: strings.Repeat("k", config.MinSecretKeyLength)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			cfg := config.Config{SecretKey: tc.secretKey}
+			// Act
+			err := cfg.Validate()
+			// Assert
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+)
+
+type TokenSigner interface {
+	Sign(subject string) string
+}
+
+type ExpiringTokenSigner interface {
+	SignWithExpiry(subject string, expiresAt time.Time) string
+}
+
+// UnsubscribeLinks builds one-click unsubscribe URLs for users.
+type UnsubscribeLinks struct {
+	BaseURL string
+	Signer  TokenSigner
+}
+
+func (l UnsubscribeLinks) UnsubscribeURL(user models.User) string {
+	return strings.TrimSuffix(l.BaseURL, "/") + UnsubscribePath + "/" + l.Signer.Sign(user.Email)
+}
+
+// ConfirmationLinks builds subscription confirmation URLs for users,
+// which are valid for TTL since creation.
+type ConfirmationLinks struct {
+	BaseURL string
+	Signer  ExpiringTokenSigner
+	TTL     time.Duration
+}
+
+func (l ConfirmationLinks) ConfirmationURL(user models.User) string {
+	token := l.Signer.SignWithExpiry(user.Email, time.Now().Add(l.TTL))
+	return strings.TrimSuffix(l.BaseURL, "/") + ConfirmSubscriptionPath + "/" + token
+}
+
+// AlertLinks builds URLs to manage rate alerts of users.
+type AlertLinks struct {
+	BaseURL string
+	Signer  TokenSigner
+}
+
+// AlertsURL returns the URL listing and creating alerts of the user.
+func (l AlertLinks) AlertsURL(user models.User) string {
+	return strings.TrimSuffix(l.BaseURL, "/") + AlertsPath + "/" + l.Signer.Sign(user.Email)
+}
+
+// DeleteAlertURL returns the URL deleting the alert, which has to carry its user.
+func (l AlertLinks) DeleteAlertURL(alert models.Alert) string {
+	return l.AlertsURL(alert.User) + "/" + strconv.FormatUint(uint64(alert.ID), 10) + "/delete"
+}
+
+// UserLinks builds every URL included in notifications of users.
+type UserLinks struct {
+	UnsubscribeLinks
+	AlertLinks
+}
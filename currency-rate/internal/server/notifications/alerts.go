@@ -0,0 +1,173 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	changePeriod = 24 * time.Hour
+	percents     = 100
+)
+
+type AlertRepository interface {
+	FindByPair(ccFrom, ccTo string) ([]models.Alert, error)
+	Fire(alert *models.Alert, payload []byte) (bool, error)
+	Rearm(alert *models.Alert) (bool, error)
+}
+
+// EmailEncoder encodes emails as outbox payloads.
+type EmailEncoder interface {
+	Encode(email mail.Email) ([]byte, error)
+}
+
+// AlertLinker builds links deleting alerts, included in their emails.
+type AlertLinker interface {
+	DeleteAlertURL(alert models.Alert) string
+}
+
+type RateHistory interface {
+	FindFirstSince(ccFrom, ccTo string, since time.Time) (*models.Rate, error)
+}
+
+// AlertEvaluator checks user alerts against every fetched rate and emails
+// users whose alert condition started to hold. An alert fires once per crossing
+// and is re-armed when its condition stops holding. The alert is marked triggered
+// in the same transaction its email is stored in the outbox, so that
+// concurrent evaluations of the same crossing send it once.
+type AlertEvaluator struct {
+	encoder   EmailEncoder
+	alertRepo AlertRepository
+	history   RateHistory
+	links     AlertLinker
+}
+
+func NewAlertEvaluator(
+	encoder EmailEncoder,
+	alertRepo AlertRepository,
+	history RateHistory,
+	links AlertLinker,
+) *AlertEvaluator {
+	return &AlertEvaluator{
+		encoder:   encoder,
+		alertRepo: alertRepo,
+		history:   history,
+		links:     links,
+	}
+}
+
+// dailyChange returns the rate change in percents relative to
+// the first rate of the pair within the last day.
+func (e *AlertEvaluator) dailyChange(rate *models.Rate) (decimal.Decimal, error) {
+	reference, err := e.history.FindFirstSince(
+		rate.CurrencyFrom, rate.CurrencyTo, time.Now().Add(-changePeriod),
+	)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if reference == nil || reference.Rate.IsZero() {
+		return decimal.Zero, nil
+	}
+	change := rate.Rate.Sub(reference.Rate).Div(reference.Rate)
+	return change.Mul(decimal.NewFromInt(percents)), nil
+}
+
+// holds reports whether the alert condition holds for the rate,
+// along with the message describing it.
+func (e *AlertEvaluator) holds(alert models.Alert, rate *models.Rate) (bool, string, error) {
+	switch alert.Condition {
+	case models.AlertAbove:
+		return rate.Rate.GreaterThan(alert.Threshold), fmt.Sprintf("above %s", alert.Threshold), nil
+	case models.AlertBelow:
+		return rate.Rate.LessThan(alert.Threshold), fmt.Sprintf("below %s", alert.Threshold), nil
+	case models.AlertChange:
+		change, err := e.dailyChange(rate)
+		if err != nil {
+			return false, "", err
+		}
+		holds := change.Abs().GreaterThan(alert.Threshold)
+		return holds, fmt.Sprintf("changed by %s%% within a day", change.StringFixed(2)), nil
+	}
+	return false, "", fmt.Errorf("unknown alert condition: %s", alert.Condition)
+}
+
+// alertEmail creates the email of the fired alert, carrying a link deleting the alert
+// both in the body and in List-Unsubscribe header.
+func (e *AlertEvaluator) alertEmail(
+	alert models.Alert, rate *models.Rate, reason string,
+) mail.Email {
+	deleteURL := e.links.DeleteAlertURL(alert)
+	text := fmt.Sprintf(
+		"1 %s = %s %s, which is %s.", rate.CurrencyFrom, rate.Rate, rate.CurrencyTo, reason,
+	)
+	return mail.Email{
+		Recipients: []string{alert.User.Email},
+		Subject:    fmt.Sprintf("%s-%s rate alert", rate.CurrencyFrom, rate.CurrencyTo),
+		Body: fmt.Sprintf(
+			"<p>%s</p><p>To stop this alert, follow <a href=\"%s\">this link</a>.</p>",
+			html.EscapeString(text), html.EscapeString(deleteURL),
+		),
+		TextBody: fmt.Sprintf("%s\nTo stop this alert, follow the link: %s", text, deleteURL),
+		Headers: map[string]string{
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", deleteURL),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+}
+
+func (e *AlertEvaluator) fire(alert models.Alert, rate *models.Rate, reason string) error {
+	payload, err := e.encoder.Encode(e.alertEmail(alert, rate, reason))
+	if err != nil {
+		return fmt.Errorf("encoding alert email: %w", err)
+	}
+	fired, err := e.alertRepo.Fire(&alert, payload)
+	if err != nil {
+		return fmt.Errorf("firing alert: %w", err)
+	}
+	if fired {
+		slog.Info("alert fired", slog.Any("alert", alert), slog.Any("rate", rate))
+	}
+	return nil
+}
+
+func (e *AlertEvaluator) evaluate(alert models.Alert, rate *models.Rate) error {
+	holds, reason, err := e.holds(alert, rate)
+	if err != nil {
+		return fmt.Errorf("checking alert condition: %w", err)
+	}
+	if holds == alert.Triggered {
+		return nil
+	}
+	if holds {
+		return e.fire(alert, rate, reason)
+	}
+	if _, err := e.alertRepo.Rearm(&alert); err != nil {
+		return fmt.Errorf("re-arming alert: %w", err)
+	}
+	return nil
+}
+
+// OnRate evaluates alerts of the rate currency pair.
+func (e *AlertEvaluator) OnRate(_ context.Context, rate *models.Rate) {
+	alerts, err := e.alertRepo.FindByPair(rate.CurrencyFrom, rate.CurrencyTo)
+	if err != nil {
+		slog.Error("failed to fetch alerts", slog.Any("error", err))
+		return
+	}
+	for _, alert := range alerts {
+		if err := e.evaluate(alert, rate); err != nil {
+			slog.Error(
+				"failed to evaluate alert",
+				slog.Any("alert", alert),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
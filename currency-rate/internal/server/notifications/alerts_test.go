@@ -0,0 +1,202 @@
+package notifications_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockAlertRepository struct {
+	mock.Mock
+}
+
+func (m *mockAlertRepository) FindByPair(ccFrom, ccTo string) ([]models.Alert, error) {
+	args := m.Called(ccFrom, ccTo)
+	return args.Get(0).([]models.Alert), args.Error(1)
+}
+
+func (m *mockAlertRepository) Fire(alert *models.Alert, payload []byte) (bool, error) {
+	args := m.Called(alert.ID, payload)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockAlertRepository) Rearm(alert *models.Alert) (bool, error) {
+	args := m.Called(alert.ID)
+	return args.Bool(0), args.Error(1)
+}
+
+type mockEmailEncoder struct {
+	mock.Mock
+}
+
+func (m *mockEmailEncoder) Encode(email mail.Email) ([]byte, error) {
+	args := m.Called(email)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+type mockAlertLinker struct{}
+
+func (m *mockAlertLinker) DeleteAlertURL(alert models.Alert) string {
+	return fmt.Sprintf("http://localhost/alerts/%s/%d/delete", alert.User.Email, alert.ID)
+}
+
+type mockRateHistory struct {
+	mock.Mock
+}
+
+func (m *mockRateHistory) FindFirstSince(ccFrom, ccTo string, since time.Time) (*models.Rate, error) {
+	args := m.Called(ccFrom, ccTo, since)
+	return args.Get(0).(*models.Rate), args.Error(1)
+}
+
+func TestAlertEvaluator(t *testing.T) {
+	testCases := []struct {
+		name        string
+		condition   models.AlertCondition
+		threshold   float32
+		triggered   bool
+		rate        float32
+		reference   float32
+		expectFire  bool
+		expectRearm bool
+	}{
+		{
+			name:       "above-crossed",
+			condition:  models.AlertAbove,
+			threshold:  42,
+			rate:       42.1,
+			expectFire: true,
+		},
+		{
+			name:      "above-already-fired",
+			condition: models.AlertAbove,
+			threshold: 42,
+			triggered: true,
+			rate:      42.5,
+		},
+		{
+			name:        "above-re-armed",
+			condition:   models.AlertAbove,
+			threshold:   42,
+			triggered:   true,
+			rate:        41.9,
+			expectRearm: true,
+		},
+		{
+			name:      "below-not-crossed",
+			condition: models.AlertBelow,
+			threshold: 40,
+			rate:      41,
+		},
+		{
+			name:       "below-crossed",
+			condition:  models.AlertBelow,
+			threshold:  40,
+			rate:       39.9,
+			expectFire: true,
+		},
+		{
+			name:       "change-crossed",
+			condition:  models.AlertChange,
+			threshold:  1,
+			rate:       39.5,
+			reference:  40,
+			expectFire: true,
+		},
+		{
+			name:      "change-not-crossed",
+			condition: models.AlertChange,
+			threshold: 1,
+			rate:      40.2,
+			reference: 40,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			rate := &models.Rate{
+				CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.NewFromFloat32(tc.rate),
+			}
+			alertRepo := new(mockAlertRepository)
+			alertRepo.On("FindByPair", "USD", "UAH").Return([]models.Alert{{
+				ID:           1,
+				User:         models.User{Email: "example@gmail.com"},
+				CurrencyFrom: "USD",
+				CurrencyTo:   "UAH",
+				Condition:    tc.condition,
+				Threshold:    decimal.NewFromFloat32(tc.threshold),
+				Triggered:    tc.triggered,
+			}}, nil)
+			alertRepo.On("Fire", uint(1), []byte("alert")).Return(true, nil)
+			alertRepo.On("Rearm", uint(1)).Return(true, nil)
+			history := new(mockRateHistory)
+			history.On("FindFirstSince", "USD", "UAH", mock.Anything).Return(
+				&models.Rate{
+					CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.NewFromFloat32(tc.reference),
+				}, nil,
+			)
+			encoder := new(mockEmailEncoder)
+			encoder.On("Encode", emailTo("example@gmail.com")).Return([]byte("alert"), nil)
+			evaluator := notifications.NewAlertEvaluator(
+				encoder, alertRepo, history, &mockAlertLinker{},
+			)
+			// Act
+			evaluator.OnRate(ctx, rate)
+			// Assert
+			if tc.expectFire {
+				alertRepo.AssertNumberOfCalls(t, "Fire", 1)
+			} else {
+				alertRepo.AssertNotCalled(t, "Fire", mock.Anything, mock.Anything)
+			}
+			if tc.expectRearm {
+				alertRepo.AssertNumberOfCalls(t, "Rearm", 1)
+			} else {
+				alertRepo.AssertNotCalled(t, "Rearm", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAlertEmailDeleteLink(t *testing.T) {
+	// Arrange
+	rate := &models.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("42.5"),
+	}
+	alertRepo := new(mockAlertRepository)
+	alertRepo.On("FindByPair", "USD", "UAH").Return([]models.Alert{{
+		ID:           3,
+		User:         models.User{Email: "example@gmail.com"},
+		CurrencyFrom: "USD",
+		CurrencyTo:   "UAH",
+		Condition:    models.AlertAbove,
+		Threshold:    decimal.RequireFromString("42"),
+	}}, nil)
+	alertRepo.On("Fire", uint(3), []byte("alert")).Return(true, nil).Once()
+	var sent mail.Email
+	encoder := new(mockEmailEncoder)
+	encoder.On("Encode", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mail.Email)
+	}).Return([]byte("alert"), nil).Once()
+	evaluator := notifications.NewAlertEvaluator(
+		encoder, alertRepo, new(mockRateHistory), &mockAlertLinker{},
+	)
+	link := "http://localhost/alerts/example@gmail.com/3/delete"
+	// Act
+	evaluator.OnRate(context.Background(), rate)
+	// Assert
+	alertRepo.AssertExpectations(t)
+	assert.Equal(t, "USD-UAH rate alert", sent.Subject)
+	assert.Contains(t, sent.Body, `href="`+link+`"`)
+	assert.Contains(t, sent.TextBody, link)
+	assert.Equal(t, "<"+link+">", sent.Headers["List-Unsubscribe"])
+	assert.Equal(t, "List-Unsubscribe=One-Click", sent.Headers["List-Unsubscribe-Post"])
+}
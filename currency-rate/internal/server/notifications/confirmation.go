@@ -0,0 +1,49 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+)
+
+const confirmationSubject = "Confirm your subscription to exchange rate notifications"
+
+type ConfirmationLinker interface {
+	ConfirmationURL(user models.User) string
+}
+
+// ConfirmationNotifier sends users emails with a link
+// to confirm their subscription.
+type ConfirmationNotifier struct {
+	mailClient        EmailClient
+	confirmationLinks ConfirmationLinker
+}
+
+func NewConfirmationNotifier(
+	mailClient EmailClient,
+	confirmationLinks ConfirmationLinker,
+) *ConfirmationNotifier {
+	return &ConfirmationNotifier{
+		mailClient:        mailClient,
+		confirmationLinks: confirmationLinks,
+	}
+}
+
+func (n *ConfirmationNotifier) SendConfirmation(ctx context.Context, user models.User) error {
+	err := n.mailClient.SendEmail(ctx, mail.Email{
+		Recipients: []string{user.Email},
+		Subject:    confirmationSubject,
+		Body: fmt.Sprintf(
+			"<p>To start receiving exchange rate notifications, "+
+				"confirm your subscription by following <a href=\"%s\">this link</a>.</p>"+
+				"<p>If you didn't subscribe, just ignore this email.</p>",
+			n.confirmationLinks.ConfirmationURL(user),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("sending confirmation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package notifications_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockConfirmationLinker struct{}
+
+func (m *mockConfirmationLinker) ConfirmationURL(user models.User) string {
+	return "http://localhost/subscribe/confirm/" + user.Email
+}
+
+func TestSendConfirmation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expectError bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:        "error",
+			err:         errors.New("broker is down"),
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			var sent mail.Email
+			emailClient := new(mockEmailClient)
+			emailClient.On("SendEmail", ctx, emailTo("example@gmail.com")).Run(
+				func(args mock.Arguments) {
+					sent = args.Get(1).(mail.Email)
+				},
+			).Return(tc.err).Once()
+			notifier := notifications.NewConfirmationNotifier(
+				emailClient, &mockConfirmationLinker{},
+			)
+			// Act
+			err := notifier.SendConfirmation(ctx, models.User{Email: "example@gmail.com"})
+			// Assert
+			emailClient.AssertExpectations(t)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, sent.Body, "http://localhost/subscribe/confirm/example@gmail.com")
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+const groupSize = 3
+
+// Catalog holds translations of the message phrases and number and date formats of a locale.
+type Catalog struct {
+	// Messages are fmt format strings by their keys.
+	Messages         map[string]string
+	DecimalSeparator string
+	GroupSeparator   string
+	DateLayout       string
+}
+
+// catalogs are translations of the supported locales.
+var catalogs = map[models.Locale]Catalog{
+	models.LocaleEnglish: {
+		Messages: map[string]string{
+			"rate.subject":         "Exchange rates for %s",
+			"rate.unsubscribe":     "To unsubscribe from notifications, follow the link: %s",
+			"rate.unsubscribeHint": "To unsubscribe from notifications, follow",
+			"rate.unsubscribeLink": "this link",
+			"rate.alerts":          "To manage rate alerts, follow the link: %s",
+			"rate.alertsHint":      "To manage rate alerts, follow",
+			"rate.alertsLink":      "this link",
+		},
+		DecimalSeparator: ".",
+		GroupSeparator:   ",",
+		DateLayout:       "January 2, 2006",
+	},
+	models.LocaleUkrainian: {
+		Messages: map[string]string{
+			"rate.subject":         "Курси валют на %s",
+			"rate.unsubscribe":     "Щоб відписатися від сповіщень, перейдіть за посиланням: %s",
+			"rate.unsubscribeHint": "Щоб відписатися від сповіщень, перейдіть за",
+			"rate.unsubscribeLink": "цим посиланням",
+			"rate.alerts":          "Керувати сповіщеннями про курс можна за посиланням: %s",
+			"rate.alertsHint":      "Керувати сповіщеннями про курс можна за",
+			"rate.alertsLink":      "цим посиланням",
+		},
+		DecimalSeparator: ",",
+		GroupSeparator:   "\u00a0", // No-break space
+		DateLayout:       "02.01.2006",
+	},
+}
+
+// CatalogFor returns the catalog of the locale, or of the default one if it isn't supported.
+func CatalogFor(locale models.Locale) Catalog {
+	if catalog, ok := catalogs[locale]; ok {
+		return catalog
+	}
+	return catalogs[models.DefaultLocale]
+}
+
+// Translate formats the message of the key with the arguments,
+// falling back to the key itself if the catalog has no such message.
+func (c Catalog) Translate(key string, args ...any) string {
+	format, ok := c.Messages[key]
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Number formats the decimal with the locale separators,
+// keeping its exact digits, e.g. 1,234.5 or 1 234,5.
+func (c Catalog) Number(d decimal.Decimal) string {
+	integer, fraction, _ := strings.Cut(d.Abs().String(), ".")
+	var b strings.Builder
+	if d.IsNegative() {
+		b.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%groupSize == 0 {
+			b.WriteString(c.GroupSeparator)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(c.DecimalSeparator)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Date formats the date with the locale layout.
+func (c Catalog) Date(t time.Time) string {
+	return t.Format(c.DateLayout)
+}
@@ -0,0 +1,53 @@
+package message_test
+
+import (
+	"testing"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications/message"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogNumber(t *testing.T) {
+	testCases := []struct {
+		name     string
+		locale   models.Locale
+		value    string
+		expected string
+	}{
+		{name: "en fraction", locale: models.LocaleEnglish, value: "41.5", expected: "41.5"},
+		{name: "en zeros", locale: models.LocaleEnglish, value: "41.230000", expected: "41.23"},
+		{name: "en groups", locale: models.LocaleEnglish, value: "1234567.8", expected: "1,234,567.8"},
+		{name: "en negative", locale: models.LocaleEnglish, value: "-1234", expected: "-1,234"},
+		{name: "uk fraction", locale: models.LocaleUkrainian, value: "41.5", expected: "41,5"},
+		{name: "uk groups", locale: models.LocaleUkrainian, value: "1234.5", expected: "1 234,5"},
+		{name: "integer", locale: models.LocaleUkrainian, value: "100", expected: "100"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			formatted := message.CatalogFor(tc.locale).Number(decimal.RequireFromString(tc.value))
+			// Assert
+			assert.Equal(t, tc.expected, formatted)
+		})
+	}
+}
+
+func TestCatalogTranslate(t *testing.T) {
+	// Arrange
+	catalog := message.CatalogFor(models.LocaleUkrainian)
+	// Act & Assert
+	assert.Equal(t, "Курси валют на 05.06.2024", catalog.Translate("rate.subject", "05.06.2024"))
+	assert.Equal(t, "missing.key", catalog.Translate("missing.key"))
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	// Every locale translates the phrases of the default one
+	for _, locale := range []models.Locale{models.LocaleEnglish, models.LocaleUkrainian} {
+		catalog := message.CatalogFor(locale)
+		for key := range message.CatalogFor(models.DefaultLocale).Messages {
+			assert.Contains(t, catalog.Messages, key, locale)
+		}
+	}
+}
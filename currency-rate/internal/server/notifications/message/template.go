@@ -0,0 +1,188 @@
+package message
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+)
+
+const (
+	subjectName = "rate.subject.tmpl"
+	textName    = "rate.txt.tmpl"
+	htmlName    = "rate.html.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// RateData is passed to the rate message templates.
+// Templates are rendered in the locale, with its translations available
+// as the T function, and numbers and dates formatted by number and date ones.
+type RateData struct {
+	Rates          []*models.Rate
+	UnsubscribeURL string
+	// AlertsURL lists and creates rate alerts of the recipient.
+	AlertsURL string
+	Locale    models.Locale
+	// Date is the date of the rates, defaults to the current one.
+	Date time.Time
+}
+
+// Message is an email rendered both as HTML and as plain text.
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// rateTemplates are the subject, plain-text and HTML templates of a locale.
+type rateTemplates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *template.Template
+}
+
+// TemplateRate formats rate messages with the templates of the recipient locale.
+type TemplateRate struct {
+	locales map[models.Locale]rateTemplates
+}
+
+func (m *TemplateRate) Format(data RateData) (*Message, error) {
+	if !data.Locale.IsValid() {
+		data.Locale = models.DefaultLocale
+	}
+	if data.Date.IsZero() {
+		data.Date = time.Now()
+	}
+	templates := m.locales[data.Locale]
+	var subject, text, html bytes.Buffer
+	if err := templates.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("rendering subject: %w", err)
+	}
+	if err := templates.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("rendering text: %w", err)
+	}
+	if err := templates.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("rendering HTML: %w", err)
+	}
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+// readTemplate reads the template from the directory, falling back
+// to the embedded default if the directory is empty or has no such file.
+func readTemplate(dir, name string) (string, error) {
+	if dir != "" {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("reading template %s: %w", name, err)
+		}
+	}
+	content, err := defaultTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("reading default template %s: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// catalogFuncs are template functions of the catalog.
+func catalogFuncs(catalog Catalog) map[string]any {
+	return map[string]any{
+		"T":      catalog.Translate,
+		"number": catalog.Number,
+		"date":   catalog.Date,
+	}
+}
+
+func parseText(dir, name string) (*texttemplate.Template, error) {
+	content, err := readTemplate(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	funcs := catalogFuncs(CatalogFor(models.DefaultLocale))
+	tmpl, err := texttemplate.New(name).Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func parseHTML(dir, name string) (*template.Template, error) {
+	content, err := readTemplate(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	funcs := catalogFuncs(CatalogFor(models.DefaultLocale))
+	tmpl, err := template.New(name).Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// localize clones the templates with the functions of the locale catalog.
+func localize(base rateTemplates, catalog Catalog) (rateTemplates, error) {
+	funcs := catalogFuncs(catalog)
+	subject, err := base.subject.Clone()
+	if err != nil {
+		return rateTemplates{}, err
+	}
+	text, err := base.text.Clone()
+	if err != nil {
+		return rateTemplates{}, err
+	}
+	html, err := base.html.Clone()
+	if err != nil {
+		return rateTemplates{}, err
+	}
+	return rateTemplates{
+		subject: subject.Funcs(funcs),
+		text:    text.Funcs(funcs),
+		html:    html.Funcs(funcs),
+	}, nil
+}
+
+// NewTemplateRate parses the templates of the directory, which override
+// the embedded default ones with the same names: rate.subject.tmpl,
+// rate.txt.tmpl and rate.html.tmpl. Empty directory uses the defaults only.
+// The templates are shared by the locales and translated by their catalogs.
+func NewTemplateRate(dir string) (*TemplateRate, error) {
+	subject, err := parseText(dir, subjectName)
+	if err != nil {
+		return nil, err
+	}
+	text, err := parseText(dir, textName)
+	if err != nil {
+		return nil, err
+	}
+	html, err := parseHTML(dir, htmlName)
+	if err != nil {
+		return nil, err
+	}
+	base := rateTemplates{subject: subject, text: text, html: html}
+	locales := make(map[models.Locale]rateTemplates, len(catalogs))
+	for locale, catalog := range catalogs {
+		templates, err := localize(base, catalog)
+		if err != nil {
+			return nil, fmt.Errorf("localizing templates to %s: %w", locale, err)
+		}
+		locales[locale] = templates
+	}
+	return &TemplateRate{locales: locales}, nil
+}
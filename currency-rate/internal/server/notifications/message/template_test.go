@@ -0,0 +1,139 @@
+package message_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications/message"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	unsubscribeURL = "http://localhost/unsubscribe/token?a=1&b=2"
+	alertsURL      = "http://localhost/alerts/token"
+)
+
+var date = time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+
+func TestTemplateRate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		locale          models.Locale
+		rates           []*models.Rate
+		expectedSubject string
+		expectedText    string
+		expectedHTML    []string
+	}{
+		{
+			name:   "single",
+			locale: models.LocaleEnglish,
+			rates: []*models.Rate{
+				{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("41.5")},
+			},
+			expectedSubject: "Exchange rates for June 5, 2024",
+			expectedText:    "1 USD = 41.5 UAH\n",
+			expectedHTML:    []string{"<td>1 USD</td><td>=</td><td>41.5 UAH</td>"},
+		},
+		{
+			name:   "multiple",
+			locale: models.LocaleEnglish,
+			rates: []*models.Rate{
+				{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("41.5")},
+				{CurrencyFrom: "EUR", CurrencyTo: "UAH", Rate: decimal.RequireFromString("44.25")},
+			},
+			expectedSubject: "Exchange rates for June 5, 2024",
+			expectedText:    "1 USD = 41.5 UAH\n1 EUR = 44.25 UAH\n",
+			expectedHTML: []string{
+				"<td>1 USD</td><td>=</td><td>41.5 UAH</td>",
+				"<td>1 EUR</td><td>=</td><td>44.25 UAH</td>",
+			},
+		},
+		{
+			name:   "ukrainian",
+			locale: models.LocaleUkrainian,
+			rates: []*models.Rate{
+				{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("41.230000")},
+			},
+			expectedSubject: "Курси валют на 05.06.2024",
+			expectedText:    "1 USD = 41,23 UAH\n",
+			expectedHTML:    []string{"<td>1 USD</td><td>=</td><td>41,23 UAH</td>"},
+		},
+		{
+			name:   "unsupported locale",
+			locale: models.Locale("de"),
+			rates: []*models.Rate{
+				{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("41.5")},
+			},
+			expectedSubject: "Exchange rates for June 5, 2024",
+			expectedText:    "1 USD = 41.5 UAH\n",
+			expectedHTML:    []string{"<td>1 USD</td><td>=</td><td>41.5 UAH</td>"},
+		},
+	}
+	formatter, err := message.NewTemplateRate("")
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			msg, err := formatter.Format(message.RateData{
+				Rates:          tc.rates,
+				UnsubscribeURL: unsubscribeURL,
+				AlertsURL:      alertsURL,
+				Locale:         tc.locale,
+				Date:           date,
+			})
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSubject, msg.Subject)
+			assert.Contains(t, msg.Text, tc.expectedText)
+			assert.Contains(t, msg.Text, unsubscribeURL)
+			assert.Contains(t, msg.Text, alertsURL)
+			assert.Contains(t, msg.HTML, `href="`+alertsURL+`"`)
+			for _, row := range tc.expectedHTML {
+				assert.Contains(t, msg.HTML, row)
+			}
+			// HTML attributes are escaped, unlike the plain text
+			assert.Contains(t, msg.HTML, `href="http://localhost/unsubscribe/token?a=1&amp;b=2"`)
+		})
+	}
+}
+
+func TestTemplateRateOverride(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	err := os.WriteFile(
+		filepath.Join(dir, "rate.subject.tmpl"),
+		[]byte("Daily rates: {{len .Rates}}, {{date .Date}}"),
+		0o600,
+	)
+	require.NoError(t, err)
+	formatter, err := message.NewTemplateRate(dir)
+	require.NoError(t, err)
+	// Act
+	msg, err := formatter.Format(message.RateData{
+		Rates: []*models.Rate{
+			{CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("41.5")},
+		},
+		Locale: models.LocaleUkrainian,
+		Date:   date,
+	})
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Daily rates: 1, 05.06.2024", msg.Subject)
+	assert.Contains(t, msg.Text, "1 USD = 41,5 UAH")
+}
+
+func TestTemplateRateInvalidOverride(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "rate.html.tmpl"), []byte("{{range}"), 0o600)
+	require.NoError(t, err)
+	// Act
+	_, err = message.NewTemplateRate(dir)
+	// Assert
+	assert.Error(t, err)
+}
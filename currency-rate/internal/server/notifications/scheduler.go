@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+)
+
+type ScheduleRepository interface {
+	FindDue(now time.Time) ([]models.User, error)
+}
+
+// Notifier notifies the users as of the time and records them notified.
+type Notifier interface {
+	Notify(ctx context.Context, users []models.User, at time.Time) []models.User
+}
+
+// Scheduler periodically notifies the users whose delivery time has come.
+type Scheduler struct {
+	repo     ScheduleRepository
+	notifier Notifier
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// NewScheduler creates a scheduler waking up every interval,
+// with notifying of the due users limited by the timeout.
+func NewScheduler(
+	repo ScheduleRepository, notifier Notifier, interval, timeout time.Duration,
+) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		notifier: notifier,
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+// Run notifies due users every interval until the context is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Tick(ctx, now)
+		}
+	}
+}
+
+// Tick notifies the users due at the time. The notifier records them notified,
+// so that they aren't mailed twice for the same delivery.
+// Users failed to be notified are retried on the next tick.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	users, err := s.repo.FindDue(now)
+	if err != nil {
+		slog.Error("failed to find due users", slog.Any("error", err))
+		return
+	}
+	if len(users) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	notified := s.notifier.Notify(ctx, users, now)
+	slog.Info(
+		"notified due users",
+		slog.Any("notified", len(notified)),
+		slog.Any("due", len(users)),
+	)
+}
@@ -0,0 +1,88 @@
+package notifications_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *mockScheduleRepository) FindDue(now time.Time) ([]models.User, error) {
+	args := m.Called(now)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+type mockNotifier struct {
+	mock.Mock
+}
+
+func (m *mockNotifier) Notify(
+	ctx context.Context, users []models.User, at time.Time,
+) []models.User {
+	args := m.Called(ctx, users, at)
+	return args.Get(0).([]models.User)
+}
+
+func TestSchedulerTick(t *testing.T) {
+	// Arrange
+	now := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	due := []models.User{{Email: "sent@gmail.com"}, {Email: "failed@gmail.com"}}
+	repo := new(mockScheduleRepository)
+	repo.On("FindDue", now).Return(due, nil).Once()
+	notifier := new(mockNotifier)
+	notifier.On("Notify", mock.Anything, due, now).Return(due[:1]).Once()
+	scheduler := notifications.NewScheduler(repo, notifier, time.Minute, time.Minute)
+	// Act
+	scheduler.Tick(context.Background(), now)
+	// Assert
+	repo.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestSchedulerTickNoDueUsers(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{name: "none due"},
+		{name: "repository error", err: errors.New("database error")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			now := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+			repo := new(mockScheduleRepository)
+			repo.On("FindDue", now).Return([]models.User{}, tc.err).Once()
+			notifier := new(mockNotifier)
+			scheduler := notifications.NewScheduler(repo, notifier, time.Minute, time.Minute)
+			// Act
+			scheduler.Tick(context.Background(), now)
+			// Assert
+			repo.AssertExpectations(t)
+			notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	// Arrange
+	repo := new(mockScheduleRepository)
+	repo.On("FindDue", mock.Anything).Return([]models.User{}, nil)
+	scheduler := notifications.NewScheduler(
+		repo, new(mockNotifier), 10*time.Millisecond, time.Minute,
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// Act
+	scheduler.Run(ctx)
+	// Assert
+	repo.AssertCalled(t, "FindDue", mock.Anything)
+}
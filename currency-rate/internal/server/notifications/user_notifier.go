@@ -2,81 +2,214 @@ package notifications
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications/message"
 )
 
 type EmailClient interface {
-	SendEmail(ctx context.Context, emails []string, subject, message string) error
+	SendEmail(ctx context.Context, email mail.Email) error
 }
 
-type Repository interface {
-	FindAll() ([]models.User, error)
+// RateMessageFormatter renders the rates as a message with HTML and plain-text bodies.
+type RateMessageFormatter interface {
+	Format(data message.RateData) (*message.Message, error)
 }
 
-type RateMessageFormatter interface {
-	SetRate(rate *models.Rate)
-	Subject() string
-	String() string
+// NotificationStore records users notified along with storing their notifications.
+type NotificationStore interface {
+	MarkNotified(user *models.User, at time.Time, payload []byte) error
+}
+
+// UserLinker builds personal links of the user included in notifications.
+type UserLinker interface {
+	UnsubscribeURL(user models.User) string
+	AlertsURL(user models.User) string
 }
 
+var defaultPair = models.CurrencyPair{CurrencyFrom: "USD", CurrencyTo: "UAH"}
+
+// UsersNotifier emails users the rates they follow. Every email is stored
+// in the outbox in the same transaction the user is recorded notified in.
 type UsersNotifier struct {
-	mailClient       EmailClient
+	encoder          EmailEncoder
+	store            NotificationStore
 	rateService      server.RateService
-	userRepository   Repository
 	messageFormatter RateMessageFormatter
+	links            UserLinker
 }
 
 func NewUsersNotifier(
-	mailClient EmailClient,
+	encoder EmailEncoder,
+	store NotificationStore,
 	rateService server.RateService,
-	userRepository Repository,
 	msgFormatter RateMessageFormatter,
+	links UserLinker,
 ) *UsersNotifier {
 	return &UsersNotifier{
-		mailClient:       mailClient,
+		encoder:          encoder,
+		store:            store,
 		rateService:      rateService,
-		userRepository:   userRepository,
 		messageFormatter: msgFormatter,
+		links:            links,
 	}
 }
 
-func (n *UsersNotifier) Notify(ctx context.Context) {
-	rate, err := n.rateService.FetchRate(ctx, "USD", "UAH")
+// userEmail creates an email of the rates for the single user, as every email
+// carries a personal unsubscribe link both in the body and in List-Unsubscribe header,
+// and a personal link managing rate alerts.
+func (n *UsersNotifier) userEmail(user models.User, data message.RateData) (mail.Email, error) {
+	unsubscribeURL := n.links.UnsubscribeURL(user)
+	data.UnsubscribeURL = unsubscribeURL
+	data.AlertsURL = n.links.AlertsURL(user)
+	msg, err := n.messageFormatter.Format(data)
 	if err != nil {
-		slog.Warn("failed to fetch rate", slog.Any("error", err))
-		return
+		return mail.Email{}, fmt.Errorf("formatting message: %w", err)
+	}
+	return mail.Email{
+		Recipients: []string{user.Email},
+		Subject:    msg.Subject,
+		Body:       msg.HTML,
+		TextBody:   msg.Text,
+		Headers: map[string]string{
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeURL),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+// userPairs returns pairs the user follows, without IDs to be usable as map keys.
+// Users subscribed before pairs were introduced follow the default pair.
+func userPairs(user models.User) []models.CurrencyPair {
+	if len(user.Subscriptions) == 0 {
+		return []models.CurrencyPair{defaultPair}
+	}
+	pairs := make([]models.CurrencyPair, 0, len(user.Subscriptions))
+	for _, pair := range user.Pairs() {
+		pairs = append(pairs, models.CurrencyPair{
+			CurrencyFrom: pair.CurrencyFrom,
+			CurrencyTo:   pair.CurrencyTo,
+		})
+	}
+	return pairs
+}
+
+// fetchRates fetches the rate of every distinct pair the users follow once.
+// Pairs that failed to be fetched are missing from the result.
+func (n *UsersNotifier) fetchRates(
+	ctx context.Context, users []models.User,
+) map[models.CurrencyPair]*models.Rate {
+	rates := make(map[models.CurrencyPair]*models.Rate)
+	failed := make(map[models.CurrencyPair]bool)
+	for _, user := range users {
+		for _, pair := range userPairs(user) {
+			if _, ok := rates[pair]; ok || failed[pair] {
+				continue
+			}
+			rate, err := n.rateService.FetchRate(ctx, pair.CurrencyFrom, pair.CurrencyTo)
+			if err != nil {
+				slog.Warn("failed to fetch rate", slog.Any("pair", pair), slog.Any("error", err))
+				failed[pair] = true
+				continue
+			}
+			rates[pair] = rate
+		}
+	}
+	return rates
+}
+
+// groupByLocale groups the users by the language of their notifications.
+func groupByLocale(users []models.User) map[models.Locale][]models.User {
+	groups := make(map[models.Locale][]models.User)
+	for _, user := range users {
+		locale := user.NotificationLocale()
+		groups[locale] = append(groups[locale], user)
+	}
+	return groups
+}
+
+// userRates returns the fetched rates of the pairs the user follows.
+func userRates(
+	user models.User, rates map[models.CurrencyPair]*models.Rate,
+) []*models.Rate {
+	result := make([]*models.Rate, 0, len(user.Subscriptions))
+	for _, pair := range userPairs(user) {
+		if rate, ok := rates[pair]; ok {
+			result = append(result, rate)
+		}
 	}
+	return result
+}
 
-	users, err := n.userRepository.FindAll()
+// notify stores the email of the user, recording the user notified at the time.
+func (n *UsersNotifier) notify(user *models.User, email mail.Email, at time.Time) error {
+	payload, err := n.encoder.Encode(email)
 	if err != nil {
-		slog.Error("failed to fetch users", slog.Any("error", err))
-		return
+		return fmt.Errorf("encoding email: %w", err)
 	}
+	return n.store.MarkNotified(user, at, payload)
+}
 
+// notifyGroup sends every user of the group the rates they follow rendered in the locale,
+// dated in the timezone of the user. Returns the users sent the email.
+func (n *UsersNotifier) notifyGroup(
+	locale models.Locale,
+	users []models.User,
+	rates map[models.CurrencyPair]*models.Rate,
+	at time.Time,
+) []models.User {
 	slog.Info(
-		"notifying users by email",
+		"notifying locale group by email",
+		slog.Any("locale", locale),
 		slog.Any("userCount", len(users)),
 	)
-
-	userEmails := make([]string, 0, len(users))
+	notified := make([]models.User, 0, len(users))
 	for _, user := range users {
-		userEmails = append(userEmails, user.Email)
+		followed := userRates(user, rates)
+		if len(followed) == 0 {
+			slog.Warn("no rates to notify user about", slog.Any("user", user))
+			continue
+		}
+		email, err := n.userEmail(user, message.RateData{
+			Rates: followed, Locale: locale, Date: at.In(user.Schedule.Location()),
+		})
+		if err != nil {
+			slog.Error("failed creating email", slog.Any("user", user), slog.Any("error", err))
+			continue
+		}
+		if err := n.notify(&user, email, at); err != nil {
+			slog.Error(
+				"failed sending email",
+				slog.Any("user", user),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		notified = append(notified, user)
 	}
+	return notified
+}
 
-	n.messageFormatter.SetRate(rate)
-	err = n.mailClient.SendEmail(
-		ctx,
-		userEmails,
-		n.messageFormatter.Subject(),
-		n.messageFormatter.String(),
+// Notify sends the users the rates they follow as of the time, grouped by locale
+// so that every group gets messages in its language, and records them notified.
+// Returns the users sent the email, the rest may be notified again later.
+func (n *UsersNotifier) Notify(
+	ctx context.Context, users []models.User, at time.Time,
+) []models.User {
+	slog.Info(
+		"notifying users by email",
+		slog.Any("userCount", len(users)),
 	)
-	if err != nil {
-		slog.Error(
-			"failed sending email",
-			slog.Any("error", err),
-		)
+
+	rates := n.fetchRates(ctx, users)
+	notified := make([]models.User, 0, len(users))
+	for locale, group := range groupByLocale(users) {
+		notified = append(notified, n.notifyGroup(locale, group, rates, at)...)
 	}
+	return notified
 }
@@ -2,11 +2,17 @@ package notifications_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/notifications/message"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -19,40 +25,66 @@ func (m *mockRateFetcher) FetchRate(ctx context.Context, from, to string) (*mode
 	return args.Get(0).(*models.Rate), args.Error(1)
 }
 
-type mockUserRepository struct {
+type mockMessageFormatter struct {
 	mock.Mock
 }
 
-func (m *mockUserRepository) FindAll() ([]models.User, error) {
-	args := m.Called()
-	return args.Get(0).([]models.User), args.Error(1)
+func (m *mockMessageFormatter) Format(data message.RateData) (*message.Message, error) {
+	args := m.Called(data)
+	msg, _ := args.Get(0).(*message.Message)
+	return msg, args.Error(1)
 }
 
-type mockMessageFormatter struct {
+type mockEmailClient struct {
 	mock.Mock
 }
 
-func (m *mockMessageFormatter) SetRate(rate *models.Rate) {
-	m.Called(rate)
+func (m *mockEmailClient) SendEmail(ctx context.Context, email mail.Email) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
 }
 
-func (m *mockMessageFormatter) Subject() string {
-	args := m.Called()
-	return args.String(0)
+type mockNotificationStore struct {
+	mock.Mock
 }
 
-func (m *mockMessageFormatter) String() string {
-	args := m.Called()
-	return args.String(0)
+func (m *mockNotificationStore) MarkNotified(
+	user *models.User, at time.Time, payload []byte,
+) error {
+	args := m.Called(user.Email, at, payload)
+	return args.Error(0)
 }
 
-type mockEmailClient struct {
-	mock.Mock
+type mockUserLinker struct{}
+
+func (m *mockUserLinker) UnsubscribeURL(user models.User) string {
+	return "http://localhost/unsubscribe/" + user.Email
 }
 
-func (m *mockEmailClient) SendEmail(ctx context.Context, emails []string, subject, message string) error {
-	args := m.Called(ctx, emails, subject, message)
-	return args.Error(0)
+func (m *mockUserLinker) AlertsURL(user models.User) string {
+	return "http://localhost/alerts/" + user.Email
+}
+
+func emailTo(recipient string) any {
+	return mock.MatchedBy(func(email mail.Email) bool {
+		return len(email.Recipients) == 1 && email.Recipients[0] == recipient
+	})
+}
+
+// expectNotified expects the email to the recipient to be encoded
+// and stored along with the recipient notified at the time.
+func expectNotified(
+	encoder *mockEmailEncoder, store *mockNotificationStore, recipient string, at time.Time,
+) {
+	payload := []byte("email to " + recipient)
+	encoder.On("Encode", emailTo(recipient)).Return(payload, nil).Once()
+	store.On("MarkNotified", recipient, at, payload).Return(nil).Once()
+}
+
+func withRates(rates ...*models.Rate) any {
+	return mock.MatchedBy(func(data message.RateData) bool {
+		return assert.ObjectsAreEqual(rates, data.Rates)
+	})
 }
 
 func TestUserNotify(t *testing.T) {
@@ -63,41 +95,328 @@ func TestUserNotify(t *testing.T) {
 
 	rateService := new(mockRateFetcher)
 	rateService.On("FetchRate", mock.Anything, ccFrom, ccTo).Return(&models.Rate{
-		Rate:         27.5,
+		Rate:         decimal.RequireFromString("27.5"),
 		CurrencyFrom: ccFrom,
 		CurrencyTo:   ccTo,
 	}, nil)
 
-	userRepository := new(mockUserRepository)
-	userRepository.On("FindAll").Return([]models.User{
+	users := []models.User{
 		{Email: "example@gmail.com"},
 		{Email: "example2@gmail.com"},
-	}, nil)
+	}
 
-	emailClient := new(mockEmailClient)
-	emailClient.On(
-		"SendEmail", ctx, []string{"example@gmail.com", "example2@gmail.com"}, mock.Anything, mock.Anything,
-	).Return(nil).Once()
+	now := time.Now()
+	encoder := new(mockEmailEncoder)
+	store := new(mockNotificationStore)
+	expectNotified(encoder, store, "example@gmail.com", now)
+	expectNotified(encoder, store, "example2@gmail.com", now)
 
 	messageFormatter := new(mockMessageFormatter)
-	messageFormatter.On("SetRate", &models.Rate{
-		Rate:         27.5,
+	messageFormatter.On("Format", withRates(&models.Rate{
+		Rate:         decimal.RequireFromString("27.5"),
 		CurrencyFrom: ccFrom,
 		CurrencyTo:   ccTo,
-	}).Return()
-	messageFormatter.On("Subject").Return(fmt.Sprintf("%s-%s exchange rate", ccFrom, ccTo))
-	messageFormatter.On("String").Return(fmt.Sprintf("1 %s = 27.5 %s", ccFrom, ccTo))
+	})).Return(&message.Message{
+		Subject: fmt.Sprintf("%s-%s exchange rate", ccFrom, ccTo),
+		HTML:    fmt.Sprintf("<p>1 %s = 27.5 %s</p>", ccFrom, ccTo),
+		Text:    fmt.Sprintf("1 %s = 27.5 %s", ccFrom, ccTo),
+	}, nil)
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notifier.Notify(ctx, users, now)
+	// Assert
+	rateService.AssertExpectations(t)
+	rateService.AssertNumberOfCalls(t, "FetchRate", 1)
+	encoder.AssertExpectations(t)
+	store.AssertExpectations(t)
+}
+
+func subscribed(email string, pairs ...string) models.User {
+	subscriptions := make([]models.Subscription, 0, len(pairs))
+	for i, pair := range pairs {
+		subscriptions = append(subscriptions, models.Subscription{Pair: models.CurrencyPair{
+			ID:           uint(i + 1),
+			CurrencyFrom: pair[:3],
+			CurrencyTo:   pair[4:],
+		}})
+	}
+	return models.User{Email: email, Subscriptions: subscriptions}
+}
+
+func TestUserNotifyPairs(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	usdRate := &models.Rate{
+		Rate: decimal.RequireFromString("41.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	eurRate := &models.Rate{
+		Rate: decimal.RequireFromString("44.5"), CurrencyFrom: "EUR", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(usdRate, nil).Once()
+	rateService.On("FetchRate", mock.Anything, "EUR", "UAH").Return(eurRate, nil).Once()
+	rateService.On("FetchRate", mock.Anything, "PLN", "UAH").Return(
+		(*models.Rate)(nil), errors.New("unsupported"),
+	).Once()
+
+	users := []models.User{
+		subscribed("usd@gmail.com", "USD/UAH"),
+		subscribed("both@gmail.com", "USD/UAH", "EUR/UAH"),
+		subscribed("pln@gmail.com", "PLN/UAH"),
+		subscribed("legacy@gmail.com"),
+	}
+
+	now := time.Now()
+	encoder := new(mockEmailEncoder)
+	store := new(mockNotificationStore)
+	expectNotified(encoder, store, "usd@gmail.com", now)
+	expectNotified(encoder, store, "both@gmail.com", now)
+	expectNotified(encoder, store, "legacy@gmail.com", now)
+
+	messageFormatter := new(mockMessageFormatter)
+	msg := &message.Message{Subject: "subject", HTML: "body", Text: "body"}
+	messageFormatter.On("Format", withRates(usdRate)).Return(msg, nil).Twice()
+	messageFormatter.On("Format", withRates(usdRate, eurRate)).Return(msg, nil).Once()
 
 	notifier := notifications.NewUsersNotifier(
-		emailClient,
+		encoder,
+		store,
 		rateService,
-		userRepository,
 		messageFormatter,
+		&mockUserLinker{},
 	)
 	// Act
-	notifier.Notify(ctx)
+	notified := notifier.Notify(ctx, users, now)
 	// Assert
 	rateService.AssertExpectations(t)
-	userRepository.AssertExpectations(t)
-	emailClient.AssertExpectations(t)
+	encoder.AssertExpectations(t)
+	store.AssertExpectations(t)
+	messageFormatter.AssertExpectations(t)
+	encoder.AssertNotCalled(t, "Encode", emailTo("pln@gmail.com"))
+	emails := make([]string, 0, len(notified))
+	for _, user := range notified {
+		emails = append(emails, user.Email)
+	}
+	assert.ElementsMatch(t, []string{"usd@gmail.com", "both@gmail.com", "legacy@gmail.com"}, emails)
+}
+
+func TestUserNotifyUnsubscribeLink(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rate := &models.Rate{
+		Rate: decimal.RequireFromString("27.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate, nil)
+
+	users := []models.User{{Email: "example@gmail.com"}}
+
+	now := time.Now()
+	var sent mail.Email
+	encoder := new(mockEmailEncoder)
+	encoder.On("Encode", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(mail.Email)
+	}).Return([]byte("email"), nil).Once()
+	store := new(mockNotificationStore)
+	store.On("MarkNotified", "example@gmail.com", now, []byte("email")).Return(nil).Once()
+
+	messageFormatter := new(mockMessageFormatter)
+	link := "http://localhost/unsubscribe/example@gmail.com"
+	messageFormatter.On("Format", mock.MatchedBy(func(data message.RateData) bool {
+		return data.UnsubscribeURL == link &&
+			data.AlertsURL == "http://localhost/alerts/example@gmail.com"
+	})).Return(&message.Message{
+		Subject: "USD-UAH exchange rate",
+		HTML:    "<p>1 USD = 27.5 UAH</p><a href=\"" + link + "\">Unsubscribe</a>",
+		Text:    "1 USD = 27.5 UAH\nUnsubscribe: " + link,
+	}, nil)
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notifier.Notify(ctx, users, now)
+	// Assert
+	encoder.AssertExpectations(t)
+	store.AssertExpectations(t)
+	messageFormatter.AssertExpectations(t)
+	assert.Equal(t, "USD-UAH exchange rate", sent.Subject)
+	assert.Contains(t, sent.Body, link)
+	assert.Contains(t, sent.TextBody, link)
+	assert.Equal(t, "<"+link+">", sent.Headers["List-Unsubscribe"])
+	assert.Equal(t, "List-Unsubscribe=One-Click", sent.Headers["List-Unsubscribe-Post"])
+}
+
+func TestUserNotifyFormatError(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rate := &models.Rate{
+		Rate: decimal.RequireFromString("27.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate, nil)
+
+	users := []models.User{{Email: "example@gmail.com"}}
+
+	now := time.Now()
+	encoder := new(mockEmailEncoder)
+	store := new(mockNotificationStore)
+
+	messageFormatter := new(mockMessageFormatter)
+	messageFormatter.On("Format", withRates(rate)).Return(nil, errors.New("template error"))
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notified := notifier.Notify(ctx, users, now)
+	// Assert
+	messageFormatter.AssertExpectations(t)
+	encoder.AssertNotCalled(t, "Encode", mock.Anything)
+	store.AssertNotCalled(t, "MarkNotified", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, notified)
+}
+
+func TestUserNotifyLocales(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rate := &models.Rate{
+		Rate: decimal.RequireFromString("27.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate, nil).Once()
+
+	users := []models.User{
+		{Email: "en@gmail.com", Locale: models.LocaleEnglish},
+		{Email: "uk@gmail.com", Locale: models.LocaleUkrainian},
+		{Email: "uk2@gmail.com", Locale: models.LocaleUkrainian},
+		{Email: "unknown@gmail.com", Locale: models.Locale("de")},
+	}
+
+	now := time.Now()
+	encoder := new(mockEmailEncoder)
+	encoder.On("Encode", mock.Anything).Return([]byte("email"), nil).Times(4)
+	store := new(mockNotificationStore)
+	store.On("MarkNotified", mock.Anything, now, []byte("email")).Return(nil).Times(4)
+
+	locales := make(map[models.Locale]int)
+	messageFormatter := new(mockMessageFormatter)
+	messageFormatter.On("Format", withRates(rate)).Run(func(args mock.Arguments) {
+		data := args.Get(0).(message.RateData)
+		locales[data.Locale]++
+		assert.True(t, now.Equal(data.Date))
+	}).Return(&message.Message{Subject: "subject", HTML: "body", Text: "body"}, nil)
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notifier.Notify(ctx, users, now)
+	// Assert
+	store.AssertExpectations(t)
+	assert.Equal(t, map[models.Locale]int{
+		models.LocaleEnglish:   2,
+		models.LocaleUkrainian: 2,
+	}, locales)
+}
+
+func TestUserNotifyStoreError(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rate := &models.Rate{
+		Rate: decimal.RequireFromString("27.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate, nil)
+
+	users := []models.User{{Email: "stored@gmail.com"}, {Email: "failed@gmail.com"}}
+
+	now := time.Now()
+	encoder := new(mockEmailEncoder)
+	store := new(mockNotificationStore)
+	expectNotified(encoder, store, "stored@gmail.com", now)
+	failed := []byte("email to failed@gmail.com")
+	encoder.On("Encode", emailTo("failed@gmail.com")).Return(failed, nil).Once()
+	store.On("MarkNotified", "failed@gmail.com", now, failed).Return(
+		errors.New("database error"),
+	).Once()
+
+	messageFormatter := new(mockMessageFormatter)
+	messageFormatter.On("Format", withRates(rate)).Return(
+		&message.Message{Subject: "subject", HTML: "body", Text: "body"}, nil,
+	)
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notified := notifier.Notify(ctx, users, now)
+	// Assert
+	store.AssertExpectations(t)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, "stored@gmail.com", notified[0].Email)
+}
+
+func TestUserNotifyTimezoneDate(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rate := &models.Rate{
+		Rate: decimal.RequireFromString("27.5"), CurrencyFrom: "USD", CurrencyTo: "UAH",
+	}
+	rateService := new(mockRateFetcher)
+	rateService.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate, nil)
+
+	users := []models.User{
+		{Email: "kyiv@gmail.com", Schedule: models.Schedule{Timezone: "Europe/Kyiv"}},
+		{Email: "tokyo@gmail.com", Schedule: models.Schedule{Timezone: "Asia/Tokyo"}},
+	}
+
+	at := time.Date(2024, time.June, 5, 20, 0, 0, 0, time.UTC)
+	encoder := new(mockEmailEncoder)
+	store := new(mockNotificationStore)
+	expectNotified(encoder, store, "kyiv@gmail.com", at)
+	expectNotified(encoder, store, "tokyo@gmail.com", at)
+
+	days := make(map[string]int)
+	messageFormatter := new(mockMessageFormatter)
+	messageFormatter.On("Format", withRates(rate)).Run(func(args mock.Arguments) {
+		data := args.Get(0).(message.RateData)
+		days[data.Date.Location().String()] = data.Date.Day()
+	}).Return(&message.Message{Subject: "subject", HTML: "body", Text: "body"}, nil)
+
+	notifier := notifications.NewUsersNotifier(
+		encoder,
+		store,
+		rateService,
+		messageFormatter,
+		&mockUserLinker{},
+	)
+	// Act
+	notifier.Notify(ctx, users, at)
+	// Assert
+	store.AssertExpectations(t)
+	assert.Equal(t, map[string]int{"Europe/Kyiv": 5, "Asia/Tokyo": 6}, days)
 }
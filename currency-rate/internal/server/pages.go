@@ -0,0 +1,40 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmationPage asks to confirm the action by submitting a POST form to the page URL.
+var confirmationPage = template.Must(template.New("confirmation").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<form method="post">
+<p>{{.}}</p>
+<button type="submit">Confirm</button>
+</form>
+</body>
+</html>
+`))
+
+// renderConfirmation responds with a page asking to confirm the action of the link,
+// so that mail scanners and link previews fetching the link don't perform it.
+func renderConfirmation(c *gin.Context, question string) {
+	var page bytes.Buffer
+	if err := confirmationPage.Execute(&page, question); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// NewConfirmationPageHandler is a handler that renders a page asking the question,
+// whose form POSTs to the same URL once confirmed.
+func NewConfirmationPageHandler(question string) func(*gin.Context) {
+	return func(c *gin.Context) {
+		renderConfirmation(c, question)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type HistoryRepo interface {
+	CountBuckets(
+		ccFrom, ccTo string, since, until time.Time, interval time.Duration,
+	) (int64, error)
+	FindBuckets(
+		ccFrom, ccTo string, since, until time.Time, interval time.Duration, limit, offset int,
+	) ([]int64, error)
+	FindRange(ccFrom, ccTo string, since, until time.Time) ([]models.Rate, error)
+}
+
+// HistoryQuery selects a page of interval-long buckets of the currency pair
+// rates created within [Since, Until).
+type HistoryQuery struct {
+	CurrencyFrom string
+	CurrencyTo   string
+	Since        time.Time
+	Until        time.Time
+	Interval     time.Duration
+	Limit        int
+	Offset       int
+}
+
+// Candle holds open, high, low and close rates of a time bucket.
+type Candle struct {
+	Time  time.Time
+	Open  decimal.Decimal
+	High  decimal.Decimal
+	Low   decimal.Decimal
+	Close decimal.Decimal
+}
+
+type HistoryPage struct {
+	Candles []Candle
+	// Total is a number of buckets in the whole range.
+	Total int64
+}
+
+// HistoryService computes OHLC values of stored rates.
+type HistoryService struct {
+	repo HistoryRepo
+}
+
+// aggregate folds rates ordered by creation time into candles of the buckets.
+func aggregate(rates []models.Rate, buckets []int64, interval time.Duration) []Candle {
+	seconds := int64(interval.Seconds())
+	candles := make([]Candle, 0, len(buckets))
+	for _, r := range rates {
+		bucket := r.Created - r.Created%seconds
+		last := len(candles) - 1
+		if last < 0 || candles[last].Time.Unix() != bucket {
+			candles = append(candles, Candle{
+				Time: time.Unix(bucket, 0).UTC(),
+				Open: r.Rate, High: r.Rate, Low: r.Rate, Close: r.Rate,
+			})
+			continue
+		}
+		candles[last].High = decimal.Max(candles[last].High, r.Rate)
+		candles[last].Low = decimal.Min(candles[last].Low, r.Rate)
+		candles[last].Close = r.Rate
+	}
+	return candles
+}
+
+func (s *HistoryService) History(q HistoryQuery) (*HistoryPage, error) {
+	total, err := s.repo.CountBuckets(q.CurrencyFrom, q.CurrencyTo, q.Since, q.Until, q.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("service history counting: %w", err)
+	}
+	buckets, err := s.repo.FindBuckets(
+		q.CurrencyFrom, q.CurrencyTo, q.Since, q.Until, q.Interval, q.Limit, q.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("service history buckets: %w", err)
+	}
+	page := &HistoryPage{Candles: []Candle{}, Total: total}
+	if len(buckets) == 0 {
+		return page, nil
+	}
+	// Load only rates of the page buckets, clamped to the queried range
+	since := time.Unix(buckets[0], 0).UTC()
+	if since.Before(q.Since) {
+		since = q.Since
+	}
+	until := time.Unix(buckets[len(buckets)-1], 0).UTC().Add(q.Interval)
+	if until.After(q.Until) {
+		until = q.Until
+	}
+	rates, err := s.repo.FindRange(q.CurrencyFrom, q.CurrencyTo, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("service history rates: %w", err)
+	}
+	page.Candles = aggregate(rates, buckets, q.Interval)
+	return page, nil
+}
+
+func NewHistoryService(repo HistoryRepo) *HistoryService {
+	return &HistoryService{repo: repo}
+}
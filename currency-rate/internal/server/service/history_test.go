@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *mockHistoryRepository) CountBuckets(
+	ccFrom, ccTo string, since, until time.Time, interval time.Duration,
+) (int64, error) {
+	args := m.Called(ccFrom, ccTo, since, until, interval)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockHistoryRepository) FindBuckets(
+	ccFrom, ccTo string, since, until time.Time, interval time.Duration, limit, offset int,
+) ([]int64, error) {
+	args := m.Called(ccFrom, ccTo, since, until, interval, limit, offset)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *mockHistoryRepository) FindRange(
+	ccFrom, ccTo string, since, until time.Time,
+) ([]models.Rate, error) {
+	args := m.Called(ccFrom, ccTo, since, until)
+	return args.Get(0).([]models.Rate), args.Error(1)
+}
+
+func dec(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func TestHistory(t *testing.T) {
+	// Arrange
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration, rate float32) models.Rate {
+		return models.Rate{
+			CurrencyFrom: "USD", CurrencyTo: "UAH",
+			Rate: decimal.NewFromFloat32(rate), Created: day.Add(d).Unix(),
+		}
+	}
+	query := service.HistoryQuery{
+		CurrencyFrom: "USD",
+		CurrencyTo:   "UAH",
+		Since:        day,
+		Until:        day.Add(24 * time.Hour),
+		Interval:     time.Hour,
+		Limit:        2,
+		Offset:       0,
+	}
+	repo := new(mockHistoryRepository)
+	repo.On("CountBuckets", "USD", "UAH", query.Since, query.Until, time.Hour).Return(int64(3), nil)
+	repo.On("FindBuckets", "USD", "UAH", query.Since, query.Until, time.Hour, 2, 0).Return(
+		[]int64{day.Unix(), day.Add(2 * time.Hour).Unix()}, nil,
+	)
+	repo.On("FindRange", "USD", "UAH", day, day.Add(3*time.Hour)).Return([]models.Rate{
+		at(time.Minute, 41.0),
+		at(10*time.Minute, 41.5),
+		at(20*time.Minute, 40.5),
+		at(30*time.Minute, 41.2),
+		at(2*time.Hour, 42.0),
+	}, nil)
+	s := service.NewHistoryService(repo)
+	// Act
+	page, err := s.History(query)
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []service.Candle{
+		{Time: day, Open: dec("41"), High: dec("41.5"), Low: dec("40.5"), Close: dec("41.2")},
+		{
+			Time: day.Add(2 * time.Hour),
+			Open: dec("42"), High: dec("42"), Low: dec("42"), Close: dec("42"),
+		},
+	}, page.Candles)
+}
+
+func TestHistoryEmpty(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	repo := new(mockHistoryRepository)
+	repo.On("CountBuckets", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(int64(0), nil)
+	repo.On(
+		"FindBuckets", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+	).Return([]int64{}, nil)
+	s := service.NewHistoryService(repo)
+	// Act
+	page, err := s.History(service.HistoryQuery{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Since: now.Add(-time.Hour), Until: now,
+		Interval: time.Hour, Limit: 10,
+	})
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, page.Candles)
+	repo.AssertNotCalled(t, "FindRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
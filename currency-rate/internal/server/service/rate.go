@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
@@ -16,9 +17,54 @@ type RateFetcher interface {
 	FetchRate(ctx context.Context, ccFrom, ccTo string) (rate.Rate, error)
 }
 
+// RateObserver is notified about every rate successfully fetched by the service.
+type RateObserver interface {
+	OnRate(ctx context.Context, rate *models.Rate)
+}
+
+// ObserverQueueSize is the number of fetched rates waiting for the observers,
+// beyond which new rates are not observed.
+const ObserverQueueSize = 64
+
 type RateService struct {
-	repo    RateRepo
-	fetcher RateFetcher
+	repo      RateRepo
+	fetcher   RateFetcher
+	observers []RateObserver
+	observed  chan *models.Rate
+}
+
+// AddObserver registers the observer to be called after every successful FetchRate.
+// Observers are called in the background by Run, so it must be started.
+func (s *RateService) AddObserver(observer RateObserver) {
+	s.observers = append(s.observers, observer)
+}
+
+// Run notifies the observers about fetched rates one by one until the context is done,
+// so that slow observers don't hold up fetching.
+func (s *RateService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case row := <-s.observed:
+			for _, observer := range s.observers {
+				observer.OnRate(ctx, row)
+			}
+		}
+	}
+}
+
+// observe queues the rate for the observers. The rate is dropped if the queue is full,
+// as the observers are going to see the next one.
+func (s *RateService) observe(row *models.Rate) {
+	if len(s.observers) == 0 {
+		return
+	}
+	select {
+	case s.observed <- row:
+	default:
+		slog.Warn("rate observer queue is full, dropping rate", slog.Any("rate", row))
+	}
 }
 
 func (s *RateService) createRate(r rate.Rate) (*models.Rate, error) {
@@ -26,6 +72,9 @@ func (s *RateService) createRate(r rate.Rate) (*models.Rate, error) {
 		CurrencyFrom: r.CurrencyFrom,
 		CurrencyTo:   r.CurrencyTo,
 		Rate:         r.Rate,
+		Cache:        r.Cache,
+		Provider:     r.Provider,
+		Legs:         r.Legs,
 	}
 	err := s.repo.Create(row)
 	if err != nil {
@@ -38,17 +87,37 @@ func (s *RateService) fetchRate(ctx context.Context, from, to string) (rate.Rate
 	return s.fetcher.FetchRate(ctx, from, to)
 }
 
+// FetchRate fetches and stores the rate, then queues it for the observers.
+// Rates served from a cache were already stored and observed, so they are
+// returned as is, timestamped with the time they were fetched at.
 func (s *RateService) FetchRate(ctx context.Context, from, to string) (*models.Rate, error) {
 	r, err := s.fetchRate(ctx, from, to)
 	if err != nil {
 		return nil, fmt.Errorf("service rate fetching: %w", err)
 	}
-	return s.createRate(r)
+	if r.Cache == rate.CacheHit || r.Cache == rate.CacheStale {
+		return &models.Rate{
+			CurrencyFrom: r.CurrencyFrom,
+			CurrencyTo:   r.CurrencyTo,
+			Rate:         r.Rate,
+			Created:      r.Time.Unix(),
+			Cache:        r.Cache,
+			Provider:     r.Provider,
+			Legs:         r.Legs,
+		}, nil
+	}
+	row, err := s.createRate(r)
+	if err != nil {
+		return nil, err
+	}
+	s.observe(row)
+	return row, nil
 }
 
 func NewRateService(repo RateRepo, fetcher RateFetcher) *RateService {
 	return &RateService{
-		repo:    repo,
-		fetcher: fetcher,
+		repo:     repo,
+		fetcher:  fetcher,
+		observed: make(chan *models.Rate, ObserverQueueSize),
 	}
 }
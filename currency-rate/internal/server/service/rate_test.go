@@ -2,11 +2,14 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/models"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/rate"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/service"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -32,6 +35,14 @@ func (m *mockRateRepository) Create(rate *models.Rate) error {
 	return args.Error(0)
 }
 
+type mockRateObserver struct {
+	mock.Mock
+}
+
+func (m *mockRateObserver) OnRate(ctx context.Context, rate *models.Rate) {
+	m.Called(ctx, rate)
+}
+
 func TestFetchRate(t *testing.T) {
 	// Arrange
 	ccFrom := "USD"
@@ -39,7 +50,7 @@ func TestFetchRate(t *testing.T) {
 	expected := &models.Rate{
 		CurrencyFrom: ccFrom,
 		CurrencyTo:   ccTo,
-		Rate:         27.5,
+		Rate:         decimal.RequireFromString("27.5"),
 	}
 	mockFetcher := new(mockRateFetcher)
 	mockFetcher.On("FetchRate", mock.Anything, ccFrom, ccTo).Return(rate.Rate{
@@ -61,3 +72,106 @@ func TestFetchRate(t *testing.T) {
 	assert.Equal(t, expected, result)
 	mockFetcher.AssertExpectations(t)
 }
+
+func TestFetchRateCached(t *testing.T) {
+	// Arrange
+	fetched := time.Now().Add(-time.Minute)
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+		Time: fetched, Cache: rate.CacheHit,
+	}, nil)
+	mockRepo := new(mockRateRepository)
+	observer := new(mockRateObserver)
+	s := service.NewRateService(mockRepo, mockFetcher)
+	s.AddObserver(observer)
+	// Act
+	result, err := s.FetchRate(context.Background(), "USD", "UAH")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &models.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+		Created: fetched.Unix(), Cache: rate.CacheHit,
+	}, result)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	observer.AssertNotCalled(t, "OnRate", mock.Anything, mock.Anything)
+}
+
+func TestFetchRateSlowObserver(t *testing.T) {
+	// Arrange
+	mockFetcher := new(mockRateFetcher)
+	mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{
+		CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+	}, nil)
+	mockRepo := new(mockRateRepository)
+	mockRepo.On("Create", mock.Anything).Return(nil)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	observer := new(mockRateObserver)
+	observer.On("OnRate", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		<-release
+	}).Return()
+	s := service.NewRateService(mockRepo, mockFetcher)
+	s.AddObserver(observer)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Run(ctx)
+	// Act
+	for range service.ObserverQueueSize + 2 {
+		_, err := s.FetchRate(context.Background(), "USD", "UAH")
+		// Assert
+		require.NoError(t, err)
+	}
+}
+
+func TestFetchRateObservers(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fetchErr      error
+		expectObserve bool
+	}{
+		{
+			name:          "success",
+			expectObserve: true,
+		},
+		{
+			name:          "fetch-error",
+			fetchErr:      errors.New("no rate"),
+			expectObserve: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockFetcher := new(mockRateFetcher)
+			mockFetcher.On("FetchRate", mock.Anything, "USD", "UAH").Return(rate.Rate{
+				CurrencyFrom: "USD", CurrencyTo: "UAH", Rate: decimal.RequireFromString("27.5"),
+			}, tc.fetchErr)
+			mockRepo := new(mockRateRepository)
+			mockRepo.On("Create", mock.Anything).Return(nil)
+			observed := make(chan *models.Rate, 1)
+			observer := new(mockRateObserver)
+			observer.On("OnRate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				observed <- args.Get(1).(*models.Rate)
+			}).Return()
+			s := service.NewRateService(mockRepo, mockFetcher)
+			s.AddObserver(observer)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			go s.Run(ctx)
+			// Act
+			result, _ := s.FetchRate(context.Background(), "USD", "UAH")
+			// Assert
+			if tc.expectObserve {
+				select {
+				case row := <-observed:
+					assert.Same(t, result, row)
+				case <-time.After(time.Second):
+					t.Fatal("rate was not observed")
+				}
+				return
+			}
+			observer.AssertNotCalled(t, "OnRate", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+const (
+	separator  = "."
+	expiryBase = 36
+)
+
+// Signer issues and verifies HMAC-signed tokens carrying a subject,
+// e.g. user email. Purpose is mixed into the signature, so tokens issued
+// for one purpose can't be used for another one with the same secret.
+//
+// Token format is "<subject>.<signature>" or "<subject>.<expiry>.<signature>"
+// for tokens with expiry, where subject and signature are base64url-encoded.
+type Signer struct {
+	secret  []byte
+	purpose string
+}
+
+func (s *Signer) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(s.purpose + separator + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) sign(payload string) string {
+	return payload + separator + s.signature(payload)
+}
+
+// Sign returns a URL-safe token for the subject, which never expires.
+func (s *Signer) Sign(subject string) string {
+	return s.sign(base64.RawURLEncoding.EncodeToString([]byte(subject)))
+}
+
+// SignWithExpiry returns a URL-safe token for the subject,
+// which is valid until expiresAt.
+func (s *Signer) SignWithExpiry(subject string, expiresAt time.Time) string {
+	return s.sign(
+		base64.RawURLEncoding.EncodeToString([]byte(subject)) +
+			separator + strconv.FormatInt(expiresAt.Unix(), expiryBase),
+	)
+}
+
+// Verify checks the token signature and expiry, if any,
+// and returns the subject it was issued for.
+func (s *Signer) Verify(token string) (string, error) {
+	index := strings.LastIndex(token, separator)
+	if index == -1 {
+		return "", ErrInvalidToken
+	}
+	payload, signature := token[:index], token[index+len(separator):]
+	if !hmac.Equal([]byte(signature), []byte(s.signature(payload))) {
+		return "", ErrInvalidToken
+	}
+	encodedSubject, expiry, hasExpiry := strings.Cut(payload, separator)
+	subject, err := base64.RawURLEncoding.DecodeString(encodedSubject)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if !hasExpiry {
+		return string(subject), nil
+	}
+	expiresAt, err := strconv.ParseInt(expiry, expiryBase, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", ErrTokenExpired
+	}
+	return string(subject), nil
+}
+
+func NewSigner(secret, purpose string) *Signer {
+	return &Signer{secret: []byte(secret), purpose: purpose}
+}
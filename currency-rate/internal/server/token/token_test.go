@@ -0,0 +1,100 @@
+package token_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/server/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	// Arrange
+	signer := token.NewSigner("secret", "unsubscribe")
+	// Act
+	subject, err := signer.Verify(signer.Sign("example@gmail.com"))
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "example@gmail.com", subject)
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	signer := token.NewSigner("secret", "unsubscribe")
+	valid := signer.Sign("example@gmail.com")
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "empty",
+			token: "",
+		},
+		{
+			name:  "no-signature",
+			token: "ZXhhbXBsZUBnbWFpbC5jb20",
+		},
+		{
+			name:  "tampered-payload",
+			token: "ZXhhbXBsZTJAZ21haWwuY29t" + valid[len("ZXhhbXBsZUBnbWFpbC5jb20"):],
+		},
+		{
+			name:  "other-secret",
+			token: token.NewSigner("other", "unsubscribe").Sign("example@gmail.com"),
+		},
+		{
+			name:  "other-purpose",
+			token: token.NewSigner("secret", "confirm").Sign("example@gmail.com"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := signer.Verify(tc.token)
+			assert.ErrorIs(t, err, token.ErrInvalidToken)
+		})
+	}
+}
+
+func TestSignWithExpiry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expiresAt   time.Time
+		expectedErr error
+	}{
+		{
+			name:      "valid",
+			expiresAt: time.Now().Add(time.Hour),
+		},
+		{
+			name:        "expired",
+			expiresAt:   time.Now().Add(-time.Hour),
+			expectedErr: token.ErrTokenExpired,
+		},
+	}
+	signer := token.NewSigner("secret", "confirm")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			subject, err := signer.Verify(signer.SignWithExpiry("example@gmail.com", tc.expiresAt))
+			// Assert
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "example@gmail.com", subject)
+		})
+	}
+}
+
+func TestVerifyTamperedExpiry(t *testing.T) {
+	signer := token.NewSigner("secret", "confirm")
+	signed := signer.SignWithExpiry("example@gmail.com", time.Now().Add(-time.Hour))
+	parts := strings.Split(signed, ".")
+	require.Len(t, parts, 3)
+	parts[1] = strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 36)
+	_, err := signer.Verify(strings.Join(parts, "."))
+	assert.ErrorIs(t, err, token.ErrInvalidToken)
+}
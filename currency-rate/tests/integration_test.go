@@ -25,9 +25,52 @@ const (
 	ccTo   = "UAH"
 )
 
+type stubConfirmer struct {
+	sent []models.User
+}
+
+func (s *stubConfirmer) SendConfirmation(_ context.Context, user models.User) error {
+	s.sent = append(s.sent, user)
+	return nil
+}
+
+// nbuStandIn serves a catalogue of rates in the format of the NBU API.
+func nbuStandIn(t *testing.T) fetchers.Option {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"r030":840,"txt":"Долар США","rate":41.2345,"cc":"USD"},
+			{"r030":978,"txt":"Євро","rate":44.1234,"cc":"EUR"}
+		]`))
+	}))
+	t.Cleanup(server.Close)
+	return fetchers.WithBaseURL(server.URL)
+}
+
+// currencyBeaconStandIn lists supported currencies, but rejects rate requests
+// as unauthorized like the CurrencyBeacon API without an API key.
+func currencyBeaconStandIn(t *testing.T) []fetchers.Option {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/supported-currencies", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(
+			"<table><tbody><tr><td><a>USD</a></td></tr><tr><td><a>UAH</a></td></tr></tbody></table>",
+		))
+	})
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return []fetchers.Option{
+		fetchers.WithBaseURL(server.URL),
+		fetchers.WithCurrenciesURL(server.URL + "/supported-currencies"),
+	}
+}
+
 func TestCurrencyBeaconFetchRate_NoAuthorization(t *testing.T) {
 	// Arrange
-	fetcher := fetchers.NewCurrencyBeaconFetcher("")
+	fetcher := fetchers.NewCurrencyBeaconFetcher("", currencyBeaconStandIn(t)...)
 	// Act
 	_, err := fetcher.FetchRate(context.Background(), ccFrom, ccTo)
 	// Assert
@@ -36,32 +79,32 @@ func TestCurrencyBeaconFetchRate_NoAuthorization(t *testing.T) {
 
 func TestNBUFetchRate(t *testing.T) {
 	// Arrange
-	fetcher := fetchers.NewNBURateFetcher()
+	fetcher := fetchers.NewNBURateFetcher(nbuStandIn(t))
 	// Act
 	result, err := fetcher.FetchRate(context.Background(), ccFrom, ccTo)
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, ccFrom, result.CurrencyFrom)
 	assert.Equal(t, ccTo, result.CurrencyTo)
-	assert.NotZero(t, result.Rate)
+	assert.True(t, result.Rate.IsPositive())
 }
 
 func TestChainFetchRate_FailFirst(t *testing.T) {
 	// Arrange
-	nbuFetcher := fetchers.NewNBURateFetcher()
-	curBeaconFetcher := fetchers.NewCurrencyBeaconFetcher("")
+	nbuFetcher := fetchers.NewNBURateFetcher(nbuStandIn(t))
+	curBeaconFetcher := fetchers.NewCurrencyBeaconFetcher("", currencyBeaconStandIn(t)...)
 	curBeaconFetcher.SetNext(nbuFetcher)
 	// Act
 	result, err := curBeaconFetcher.FetchRate(context.Background(), ccFrom, ccTo)
 	// Assert
 	require.NoError(t, err)
-	assert.NotZero(t, result.Rate)
+	assert.True(t, result.Rate.IsPositive())
 }
 
 func TestRateServiceFetchRate_Success(t *testing.T) {
 	// Arrange
 	rateRepo := models.NewRateRepository(database.SetUpTest(t, &models.Rate{}))
-	nbuFetcher := fetchers.NewNBURateFetcher()
+	nbuFetcher := fetchers.NewNBURateFetcher(nbuStandIn(t))
 	rateFetcher := service.NewRateService(rateRepo, nbuFetcher)
 	// Act
 	result, err := rateFetcher.FetchRate(context.Background(), ccFrom, ccTo)
@@ -69,17 +112,21 @@ func TestRateServiceFetchRate_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, ccFrom, result.CurrencyFrom)
 	assert.Equal(t, ccTo, result.CurrencyTo)
-	assert.NotZero(t, result.Rate)
+	assert.True(t, result.Rate.IsPositive())
 }
 
 func TestSubscribeUser_Success(t *testing.T) {
 	// Arrange
-	db := database.SetUpTest(t, &models.User{})
+	db := database.SetUpTest(
+		t, &models.User{}, &models.CurrencyPair{}, &models.Subscription{},
+	)
 	user := &models.User{Email: "example@gmail.com"}
 	repo := models.NewUserRepository(db)
+	confirmer := &stubConfirmer{}
 	engine := server.NewEngine(server.Client{
-		Config:   serverCfg.Config{Port: "8080"},
-		UserRepo: repo,
+		Config:    serverCfg.Config{Port: "8080"},
+		UserRepo:  repo,
+		Confirmer: confirmer,
 	})
 	// Act
 	rr := httptest.NewRecorder()
@@ -90,15 +137,20 @@ func TestSubscribeUser_Success(t *testing.T) {
 	engine.ServeHTTP(rr, req)
 	// Assert
 	assert.Equal(t, http.StatusOK, rr.Code)
-	exists, err := repo.Exists(user)
+	created, err := repo.FindByEmail(user.Email)
 	require.NoError(t, err)
-	assert.True(t, exists)
+	require.NotNil(t, created)
+	assert.Equal(t, models.StatusPending, created.Status)
+	require.Len(t, confirmer.sent, 1)
+	assert.Equal(t, user.Email, confirmer.sent[0].Email)
 }
 
 func TestSubscribeUser_Conflict(t *testing.T) {
 	user := &models.User{Email: "example@gmail.com"}
 	// Arrange
-	repo := models.NewUserRepository(database.SetUpTest(t, &models.User{}))
+	repo := models.NewUserRepository(database.SetUpTest(
+		t, &models.User{}, &models.CurrencyPair{}, &models.Subscription{},
+	))
 	err := repo.Create(user)
 	require.NoError(t, err)
 	engine := server.NewEngine(server.Client{
@@ -0,0 +1,49 @@
+//go:build integration
+
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/internal/mail/transport/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProducerPersistentConfirmedMessage(t *testing.T) {
+	// Arrange
+	brokerURI := os.Getenv("BROKER_URI")
+	if brokerURI == "" {
+		t.Skip("BROKER_URI is not set")
+	}
+	cfg := config.Config{
+		BrokerURI:      brokerURI,
+		QueueName:      "test-producer-" + time.Now().Format("150405.000"),
+		ConfirmTimeout: 5 * time.Second,
+	}
+	producer := transport.NewProducer(cfg)
+	t.Cleanup(func() { producer.Close() })
+	conn, err := amqp.Dial(brokerURI)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = ch.QueueDelete(cfg.QueueName, false, false, false) })
+	// Act
+	require.Eventually(t, func() bool {
+		err = producer.Produce(context.Background(), []byte(`{"commandType":"SendEmail"}`))
+		return !errors.Is(err, transport.ErrDisconnected)
+	}, 5*time.Second, 50*time.Millisecond)
+	// Assert
+	require.NoError(t, err)
+	msg, ok, err := ch.Get(cfg.QueueName, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, amqp.Persistent, msg.DeliveryMode)
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/currency-rate/pkg/settings"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup"
+	dedupCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup/config"
 	transportCfg "github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/backends"
@@ -26,19 +28,23 @@ func main() {
 	if debug {
 		mailer = backends.NewConsoleMailer(mailConfig)
 	} else {
-		mailer = backends.NewGomailMailer(mailConfig)
+		gomailMailer := backends.NewGomailMailer(mailConfig)
+		defer gomailMailer.Close()
+		mailer = gomailMailer
 	}
 	mailClient := mail.NewClient(mailer)
 
-	transportConfig := transportCfg.NewFromEnv()
-	client, err := broker.NewClient(transportConfig)
+	store, err := dedup.New(dedupCfg.NewFromEnv())
 	if err != nil {
-		slog.Error("creating broker client", slog.Any("error", err))
+		slog.Error("creating deduplication store", slog.Any("error", err))
 		return
 	}
+
+	transportConfig := transportCfg.NewFromEnv()
+	client := broker.NewClient(transportConfig, store)
 	defer client.Close()
 
-	if err = client.Subscribe(mailClient.SendEmail); err != nil {
+	if err = client.Subscribe(mailClient); err != nil {
 		slog.Error("subscribing to broker", slog.Any("error", err))
 		return
 	}
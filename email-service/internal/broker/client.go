@@ -3,21 +3,26 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
-	"time"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
 )
 
-var mailTimeout = 5 * time.Second
-
 const eventType = "SendEmail"
 
 type MailData struct {
 	Emails  []string `json:"emails"`
 	Subject string   `json:"subject"`
 	Body    string   `json:"body"`
+	// TextBody is a plain-text alternative of the HTML body.
+	TextBody string            `json:"textBody,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	// PerRecipient requests a separate message rendered for every recipient.
+	PerRecipient bool `json:"perRecipient,omitempty"`
 }
 
 type Command struct {
@@ -27,30 +32,124 @@ type Command struct {
 	Data      MailData `json:"data"`
 }
 
-type MailSender func(ctx context.Context, emails []string, subject, body string) error
+type MailSender interface {
+	SendEmail(ctx context.Context, email mail.Email) error
+	SendEach(ctx context.Context, email mail.Email) []mail.Result
+}
 
 type Client struct {
-	consumer   *transport.Consumer
-	stopSignal chan struct{}
+	consumer *transport.Consumer
+	store    dedup.Store
+}
+
+// Subscribe adds the sender of commands and starts consuming them.
+func (c *Client) Subscribe(sender MailSender) error {
+	c.consumer.Subscribe(NewCommandListener(c.store, sender))
+	c.consumer.Start()
+	return nil
 }
 
-func (c *Client) Subscribe(f MailSender) error {
-	c.consumer.Subscribe(func(b []byte) error {
-		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
-		defer cancel()
-		command, err := c.unmarshal(b)
+// NewCommandListener returns a listener sending emails of the commands.
+// Commands with IDs claimed by the store are skipped, so that redelivered
+// commands are never mailed twice. Per-recipient commands are claimed
+// per recipient, so that a retry sends only the failed messages.
+func NewCommandListener(store dedup.Store, sender MailSender) transport.Listener {
+	return func(ctx context.Context, b []byte) error {
+		command, err := unmarshal(b)
 		if err != nil {
-			return err
+			// Malformed commands can't be fixed by retrying
+			return fmt.Errorf("%w: %w", transport.ErrPermanent, err)
 		}
 		if command.Type != eventType {
 			return nil
 		}
-		return f(ctx, command.Data.Emails, command.Data.Subject, command.Data.Body)
-	})
-	return nil
+		if command.Data.PerRecipient {
+			return sendEach(ctx, store, sender, command)
+		}
+		return send(ctx, store, sender, command)
+	}
+}
+
+func send(ctx context.Context, store dedup.Store, sender MailSender, command *Command) error {
+	claimed, err := store.Claim(ctx, command.ID)
+	if err != nil {
+		return fmt.Errorf("claiming command: %w", err)
+	}
+	if !claimed {
+		slog.Info("skipping duplicate command", slog.Any("commandID", command.ID))
+		return nil
+	}
+	err = sender.SendEmail(ctx, command.Data.email())
+	settle(ctx, store, command.ID, err)
+	return err
 }
 
-func (c *Client) unmarshal(data []byte) (*Command, error) {
+func sendEach(ctx context.Context, store dedup.Store, sender MailSender, command *Command) error {
+	email := command.Data.email()
+	email.Recipients = nil
+	for _, recipient := range command.Data.Emails {
+		claimed, err := store.Claim(ctx, recipientKey(command.ID, recipient))
+		if err != nil {
+			// Recipients claimed so far would be skipped as duplicates on retry otherwise
+			for _, pending := range email.Recipients {
+				settle(ctx, store, recipientKey(command.ID, pending), err)
+			}
+			return fmt.Errorf("claiming recipient: %w", err)
+		}
+		if claimed {
+			email.Recipients = append(email.Recipients, recipient)
+		}
+	}
+	if len(email.Recipients) == 0 {
+		slog.Info("skipping duplicate command", slog.Any("commandID", command.ID))
+		return nil
+	}
+	results := sender.SendEach(ctx, email)
+	for _, result := range results {
+		if result.Err != nil {
+			slog.Error(
+				"sending email to recipient",
+				slog.Any("commandID", command.ID),
+				slog.Any("error", result.Err),
+			)
+		}
+		settle(ctx, store, recipientKey(command.ID, result.Recipient), result.Err)
+	}
+	return mail.ResultsError(results)
+}
+
+func recipientKey(commandID, recipient string) string {
+	return commandID + "/" + recipient
+}
+
+// settle completes the claim of the sent email, or releases it to be retried.
+func settle(ctx context.Context, store dedup.Store, id string, sendErr error) {
+	// Store updates must not be skipped once the message context times out
+	ctx = context.WithoutCancel(ctx)
+	if sendErr != nil {
+		if err := store.Release(ctx, id); err != nil {
+			slog.Error("releasing command", slog.Any("error", err))
+		}
+		return
+	}
+	// The email is already sent, so failing to complete must not retry it
+	if err := store.Complete(ctx, id); err != nil {
+		slog.Error("completing command", slog.Any("error", err))
+	}
+}
+
+func (d MailData) email() mail.Email {
+	return mail.Email{
+		Recipients:   d.Emails,
+		Subject:      d.Subject,
+		Body:         d.Body,
+		TextBody:     d.TextBody,
+		Headers:      d.Headers,
+		PerRecipient: d.PerRecipient,
+	}
+}
+
+func unmarshal(data []byte) (*Command, error) {
 	command := &Command{}
 	if err := json.Unmarshal(data, command); err != nil {
 		return nil, err
@@ -58,18 +157,12 @@ func (c *Client) unmarshal(data []byte) (*Command, error) {
 	return command, nil
 }
 
+// Close stops consuming commands and waits for the commands in process
+// to be sent before closing the connection.
 func (c *Client) Close() error {
-	close(c.stopSignal)
 	return c.consumer.Close()
 }
 
-func NewClient(config config.Config) (*Client, error) {
-	consumer, err := transport.NewConsumer(config)
-	if err != nil {
-		slog.Error("creating consumer", slog.Any("error", err))
-		return nil, err
-	}
-	stopSignal := make(chan struct{})
-	go consumer.Listen(stopSignal)
-	return &Client{consumer: consumer, stopSignal: stopSignal}, nil
+func NewClient(config config.Config, store dedup.Store) *Client {
+	return &Client{consumer: transport.NewConsumer(config), store: store}
 }
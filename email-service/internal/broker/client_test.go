@@ -0,0 +1,207 @@
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// senderFunc sends every email of SendEach separately with the function.
+type senderFunc func(ctx context.Context, email mail.Email) error
+
+func (f senderFunc) SendEmail(ctx context.Context, email mail.Email) error {
+	return f(ctx, email)
+}
+
+func (f senderFunc) SendEach(ctx context.Context, email mail.Email) []mail.Result {
+	results := make([]mail.Result, 0, len(email.Recipients))
+	for _, recipient := range email.Recipients {
+		personal, err := email.For(recipient)
+		if err == nil {
+			err = f(ctx, personal)
+		}
+		results = append(results, mail.Result{Recipient: recipient, Err: err})
+	}
+	return results
+}
+
+func commandBytes(t *testing.T, id string) []byte {
+	t.Helper()
+	data, err := json.Marshal(broker.Command{
+		ID:   id,
+		Type: "SendEmail",
+		Data: broker.MailData{Emails: []string{"example@gmail.com"}, Subject: "Subject"},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestCommandListenerSkipsDuplicates(t *testing.T) {
+	// Arrange
+	sent := 0
+	listener := broker.NewCommandListener(
+		dedup.NewMemoryStore(time.Hour, time.Minute),
+		senderFunc(func(_ context.Context, email mail.Email) error {
+			sent++
+			assert.Equal(t, []string{"example@gmail.com"}, email.Recipients)
+			return nil
+		}),
+	)
+	// Act
+	require.NoError(t, listener(context.Background(), commandBytes(t, "first")))
+	require.NoError(t, listener(context.Background(), commandBytes(t, "first")))
+	require.NoError(t, listener(context.Background(), commandBytes(t, "second")))
+	// Assert
+	assert.Equal(t, 2, sent)
+}
+
+func TestCommandListenerRetriesFailed(t *testing.T) {
+	// Arrange
+	sent := 0
+	fail := true
+	listener := broker.NewCommandListener(
+		dedup.NewMemoryStore(time.Hour, time.Minute),
+		senderFunc(func(context.Context, mail.Email) error {
+			if fail {
+				fail = false
+				return assert.AnError
+			}
+			sent++
+			return nil
+		}),
+	)
+	// Act
+	err := listener(context.Background(), commandBytes(t, "id"))
+	retryErr := listener(context.Background(), commandBytes(t, "id"))
+	// Assert
+	require.ErrorIs(t, err, assert.AnError)
+	require.NoError(t, retryErr)
+	assert.Equal(t, 1, sent)
+}
+
+func TestCommandListenerMalformed(t *testing.T) {
+	// Arrange
+	listener := broker.NewCommandListener(
+		dedup.NewMemoryStore(time.Hour, time.Minute),
+		senderFunc(func(context.Context, mail.Email) error {
+			t.Fatal("malformed command must not be sent")
+			return nil
+		}),
+	)
+	// Act
+	err := listener(context.Background(), []byte("{"))
+	// Assert
+	assert.ErrorIs(t, err, transport.ErrPermanent)
+}
+
+func TestCommandListenerPassesContext(t *testing.T) {
+	// Arrange
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "message")
+	listener := broker.NewCommandListener(
+		dedup.NewMemoryStore(time.Hour, time.Minute),
+		senderFunc(func(ctx context.Context, _ mail.Email) error {
+			// Assert
+			assert.Equal(t, "message", ctx.Value(key{}))
+			return nil
+		}),
+	)
+	// Act
+	err := listener(ctx, commandBytes(t, "id"))
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestCommandListenerPerRecipient(t *testing.T) {
+	// Arrange
+	var sent []mail.Email
+	failing := "failing@gmail.com"
+	listener := broker.NewCommandListener(
+		dedup.NewMemoryStore(time.Hour, time.Minute),
+		senderFunc(func(_ context.Context, email mail.Email) error {
+			if email.Recipients[0] == failing {
+				failing = ""
+				return assert.AnError
+			}
+			sent = append(sent, email)
+			return nil
+		}),
+	)
+	command, err := json.Marshal(broker.Command{
+		ID:   "id",
+		Type: "SendEmail",
+		Data: broker.MailData{
+			Emails:       []string{"first@gmail.com", "failing@gmail.com"},
+			Subject:      "Hello, {{.Recipient}}",
+			PerRecipient: true,
+		},
+	})
+	require.NoError(t, err)
+	// Act
+	err = listener(context.Background(), command)
+	retryErr := listener(context.Background(), command)
+	// Assert
+	var deliveryErr *mail.DeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	require.Len(t, deliveryErr.Failed, 1)
+	assert.Equal(t, "failing@gmail.com", deliveryErr.Failed[0].Recipient)
+	require.NoError(t, retryErr)
+	require.Len(t, sent, 2)
+	assert.Equal(t, "Hello, first@gmail.com", sent[0].Subject)
+	assert.Equal(t, []string{"failing@gmail.com"}, sent[1].Recipients)
+	assert.Equal(t, "Hello, failing@gmail.com", sent[1].Subject)
+}
+
+// flakyStore fails to claim the ID once.
+type flakyStore struct {
+	dedup.Store
+	failing string
+}
+
+func (s *flakyStore) Claim(ctx context.Context, id string) (bool, error) {
+	if id == s.failing {
+		s.failing = ""
+		return false, assert.AnError
+	}
+	return s.Store.Claim(ctx, id)
+}
+
+func TestCommandListenerPerRecipientClaimFailed(t *testing.T) {
+	// Arrange
+	var sent []string
+	listener := broker.NewCommandListener(
+		&flakyStore{
+			Store:   dedup.NewMemoryStore(time.Hour, time.Minute),
+			failing: "id/second@gmail.com",
+		},
+		senderFunc(func(_ context.Context, email mail.Email) error {
+			sent = append(sent, email.Recipients...)
+			return nil
+		}),
+	)
+	command, err := json.Marshal(broker.Command{
+		ID:   "id",
+		Type: "SendEmail",
+		Data: broker.MailData{
+			Emails:       []string{"first@gmail.com", "second@gmail.com"},
+			Subject:      "Hello, {{.Recipient}}",
+			PerRecipient: true,
+		},
+	})
+	require.NoError(t, err)
+	// Act
+	err = listener(context.Background(), command)
+	retryErr := listener(context.Background(), command)
+	// Assert
+	require.ErrorIs(t, err, assert.AnError)
+	require.NoError(t, retryErr)
+	assert.Equal(t, []string{"first@gmail.com", "second@gmail.com"}, sent)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	defaultTTL   = 24 * time.Hour
+	defaultLease = time.Minute
+)
+
+type Config struct {
+	// TTL is how long processed command IDs are remembered. Redelivered
+	// commands are dropped only within it.
+	TTL time.Duration
+	// Lease is how long a command being processed is reserved, so that
+	// a crashed consumer doesn't block the command until TTL expires.
+	Lease time.Duration
+	// DatabaseService is "sqlite" or "postgres" for keeping command IDs
+	// in the database, IDs are kept in memory if it is empty.
+	DatabaseService string
+	DatabaseDSN     string
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+			slog.Any("error", err),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
+func NewFromEnv() Config {
+	return Config{
+		TTL:             durationOrDefault("DEDUP_TTL", defaultTTL),
+		Lease:           durationOrDefault("DEDUP_LEASE", defaultLease),
+		DatabaseService: os.Getenv("DEDUP_DATABASE_SERVICE"),
+		DatabaseDSN:     os.Getenv("DEDUP_DATABASE_DSN"),
+	}
+}
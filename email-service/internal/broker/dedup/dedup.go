@@ -0,0 +1,42 @@
+// Package dedup remembers processed command IDs, so that commands redelivered
+// by the broker are not processed twice.
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Store claims command IDs. A claim is held for the lease while the command
+// is processed, kept for the TTL once completed and dropped once released.
+type Store interface {
+	// Claim reserves the command ID, returning false if it is already claimed.
+	Claim(ctx context.Context, id string) (bool, error)
+	Complete(ctx context.Context, id string) error
+	Release(ctx context.Context, id string) error
+}
+
+// New creates a SQL store if the database is configured, or a memory store otherwise.
+func New(config config.Config) (Store, error) {
+	var open func(string) gorm.Dialector
+	switch config.DatabaseService {
+	case "":
+		return NewMemoryStore(config.TTL, config.Lease), nil
+	case "sqlite":
+		open = sqlite.Open
+	case "postgres":
+		open = postgres.Open
+	default:
+		return nil, fmt.Errorf("unsupported database service: %s", config.DatabaseService)
+	}
+	db, err := gorm.Open(open(config.DatabaseDSN), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return NewSQLStore(db, config.TTL, config.Lease)
+}
@@ -0,0 +1,59 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps command IDs in memory, so they are forgotten on restart.
+type MemoryStore struct {
+	ttl       time.Duration
+	lease     time.Duration
+	mu        sync.Mutex
+	expires   map[string]time.Time
+	nextSweep time.Time
+}
+
+// sweep forgets expired IDs at most once per lease to keep the memory bounded.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for id, expires := range s.expires {
+		if !now.Before(expires) {
+			delete(s.expires, id)
+		}
+	}
+	s.nextSweep = now.Add(s.lease)
+}
+
+func (s *MemoryStore) Claim(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	if expires, ok := s.expires[id]; ok && now.Before(expires) {
+		return false, nil
+	}
+	s.expires[id] = now.Add(s.lease)
+	return true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires[id] = time.Now().Add(s.ttl)
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, id)
+	return nil
+}
+
+func NewMemoryStore(ttl, lease time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, lease: lease, expires: make(map[string]time.Time)}
+}
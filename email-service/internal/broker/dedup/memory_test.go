@@ -0,0 +1,58 @@
+package dedup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store dedup.Store) {
+	t.Helper()
+	ctx := context.Background()
+	t.Run("claim", func(t *testing.T) {
+		claimed, err := store.Claim(ctx, "claim")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = store.Claim(ctx, "claim")
+		require.NoError(t, err)
+		assert.False(t, claimed)
+	})
+	t.Run("complete", func(t *testing.T) {
+		_, err := store.Claim(ctx, "complete")
+		require.NoError(t, err)
+		require.NoError(t, store.Complete(ctx, "complete"))
+		claimed, err := store.Claim(ctx, "complete")
+		require.NoError(t, err)
+		assert.False(t, claimed)
+	})
+	t.Run("release", func(t *testing.T) {
+		_, err := store.Claim(ctx, "release")
+		require.NoError(t, err)
+		require.NoError(t, store.Release(ctx, "release"))
+		claimed, err := store.Claim(ctx, "release")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, dedup.NewMemoryStore(time.Hour, time.Minute))
+}
+
+func TestMemoryStoreExpiredClaim(t *testing.T) {
+	// Arrange
+	store := dedup.NewMemoryStore(time.Hour, time.Millisecond)
+	ctx := context.Background()
+	_, err := store.Claim(ctx, "id")
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	// Act
+	claimed, err := store.Claim(ctx, "id")
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, claimed, "lease of a crashed consumer must expire")
+}
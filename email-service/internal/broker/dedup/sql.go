@@ -0,0 +1,66 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProcessedCommand is a command ID claimed until the Expires unix time.
+type ProcessedCommand struct {
+	ID      string `gorm:"primaryKey;type:varchar(64)"`
+	Expires int64  `gorm:"index"`
+}
+
+// SQLStore keeps command IDs in the database, so they are shared
+// between consumers and survive restarts.
+type SQLStore struct {
+	db    *gorm.DB
+	ttl   time.Duration
+	lease time.Duration
+}
+
+func (s *SQLStore) Claim(ctx context.Context, id string) (bool, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	// Expired IDs of every command are deleted, so that the table stays bounded
+	err := db.Where("expires <= ?", now.Unix()).Delete(&ProcessedCommand{}).Error
+	if err != nil {
+		return false, fmt.Errorf("deleting expired commands: %w", err)
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedCommand{
+		ID:      id,
+		Expires: now.Add(s.lease).Unix(),
+	})
+	if result.Error != nil {
+		return false, fmt.Errorf("claiming command: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *SQLStore) Complete(ctx context.Context, id string) error {
+	err := s.db.WithContext(ctx).Model(&ProcessedCommand{ID: id}).
+		Update("expires", time.Now().Add(s.ttl).Unix()).Error
+	if err != nil {
+		return fmt.Errorf("completing command: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Release(ctx context.Context, id string) error {
+	if err := s.db.WithContext(ctx).Delete(&ProcessedCommand{ID: id}).Error; err != nil {
+		return fmt.Errorf("releasing command: %w", err)
+	}
+	return nil
+}
+
+// NewSQLStore creates the table of command IDs if it doesn't exist.
+func NewSQLStore(db *gorm.DB, ttl, lease time.Duration) (*SQLStore, error) {
+	if err := db.AutoMigrate(&ProcessedCommand{}); err != nil {
+		return nil, fmt.Errorf("migrating processed commands: %w", err)
+	}
+	return &SQLStore{db: db, ttl: ttl, lease: lease}, nil
+}
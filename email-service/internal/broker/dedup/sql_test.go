@@ -0,0 +1,52 @@
+package dedup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/dedup/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLStore(t *testing.T, lease time.Duration) dedup.Store {
+	t.Helper()
+	store, err := dedup.New(config.Config{
+		TTL:             time.Hour,
+		Lease:           lease,
+		DatabaseService: "sqlite",
+		DatabaseDSN:     "file:" + t.Name() + "?mode=memory&cache=shared",
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestSQLStore(t *testing.T) {
+	testStore(t, newSQLStore(t, time.Minute))
+}
+
+func TestSQLStoreExpiredClaim(t *testing.T) {
+	// Arrange
+	store := newSQLStore(t, -time.Second)
+	ctx := context.Background()
+	_, err := store.Claim(ctx, "id")
+	require.NoError(t, err)
+	// Act
+	claimed, err := store.Claim(ctx, "id")
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, claimed, "lease of a crashed consumer must expire")
+}
+
+func TestNewUnsupportedService(t *testing.T) {
+	_, err := dedup.New(config.Config{DatabaseService: "mysql"})
+	assert.Error(t, err)
+}
+
+func TestNewMemoryStore(t *testing.T) {
+	store, err := dedup.New(config.Config{TTL: time.Hour, Lease: time.Minute})
+	require.NoError(t, err)
+	assert.IsType(t, &dedup.MemoryStore{}, store)
+}
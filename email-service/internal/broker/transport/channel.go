@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"context"
+	"errors"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Channel is the part of the broker channel used by the consumer,
+// so that delivery handling can be tested without a broker.
+type Channel interface {
+	// Consume starts delivering messages of the queue, unacknowledged until
+	// the consumer acknowledges them.
+	Consume(queue, consumerTag string) (<-chan amqp.Delivery, error)
+	// Publish publishes the message and waits for the broker to confirm it.
+	Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error
+	// Cancel stops deliveries, which are closed once the buffered ones are passed.
+	Cancel(consumerTag string) error
+	// NotifyClose registers the receiver of the channel or its connection closing.
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	// Close closes the channel along with its connection.
+	Close() error
+}
+
+// Dialer connects to the broker and opens a channel with the topology declared.
+type Dialer func(config config.Config) (Channel, error)
+
+// amqpChannel is a channel of its own AMQP connection.
+type amqpChannel struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	config  config.Config
+}
+
+func (c *amqpChannel) Consume(queue, consumerTag string) (<-chan amqp.Delivery, error) {
+	return c.channel.Consume(
+		queue,       // queue
+		consumerTag, // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+}
+
+func (c *amqpChannel) Publish(
+	ctx context.Context, exchange, key string, msg amqp.Publishing,
+) error {
+	confirmation, err := c.channel.PublishWithDeferredConfirmWithContext(
+		ctx, exchange, key, false, false, msg,
+	)
+	if err != nil {
+		return err
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("message is not confirmed by the broker")
+	}
+	return nil
+}
+
+func (c *amqpChannel) Cancel(consumerTag string) error {
+	return c.channel.Cancel(consumerTag, false)
+}
+
+// NotifyClose registers the receiver on the channel, which is notified about
+// connection exceptions as well, as they close all channels of the connection.
+func (c *amqpChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return c.channel.NotifyClose(receiver)
+}
+
+func (c *amqpChannel) Close() error {
+	if err := c.channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return logAndWrap("closing channel", err)
+	}
+	if err := c.conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return logAndWrap("closing connection", err)
+	}
+	return nil
+}
+
+// open opens the channel with publisher confirms, declares the topology
+// and limits unacknowledged messages.
+func (c *amqpChannel) open() error {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return logAndWrap("getting channel", err)
+	}
+	c.channel = ch
+	if err := ch.Confirm(false); err != nil {
+		return logAndWrap("enabling publisher confirms", err)
+	}
+	if err := declareTopology(ch, c.config); err != nil {
+		return err
+	}
+	if err := ch.Qos(c.config.PrefetchCount, 0, false); err != nil {
+		return logAndWrap("setting prefetch count", err)
+	}
+	return nil
+}
+
+// DialAMQP connects to the broker of the config and opens a channel
+// with the topology declared.
+func DialAMQP(config config.Config) (Channel, error) {
+	conn, err := amqp.Dial(config.BrokerURI)
+	if err != nil {
+		return nil, logAndWrap("dialing amqp", err)
+	}
+	ch := &amqpChannel{conn: conn, config: config}
+	if err := ch.open(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// declareTopology declares the durable queue, delay queues dead-lettering
+// expired messages back to it, and the dead-letter exchange with its queue.
+func declareTopology(ch *amqp.Channel, config config.Config) error {
+	_, err := ch.QueueDeclare(
+		config.QueueName, // name
+		true,             // durable
+		false,            // delete when unused
+		false,            // exclusive
+		false,            // no-wait
+		nil,              // arguments
+	)
+	if err != nil {
+		return logAndWrap("declaring queue", err)
+	}
+	for attempt := 1; attempt <= config.MaxRetries; attempt++ {
+		delay := config.RetryDelayFor(attempt)
+		_, err := ch.QueueDeclare(
+			retryQueueName(config.QueueName, delay), true, false, false, false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": config.QueueName,
+			},
+		)
+		if err != nil {
+			return logAndWrap("declaring retry queue", err)
+		}
+	}
+	err = ch.ExchangeDeclare(
+		config.DeadLetterExchange, amqp.ExchangeFanout, true, false, false, false, nil,
+	)
+	if err != nil {
+		return logAndWrap("declaring dead-letter exchange", err)
+	}
+	_, err = ch.QueueDeclare(config.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return logAndWrap("declaring dead-letter queue", err)
+	}
+	err = ch.QueueBind(config.DeadLetterQueue, "", config.DeadLetterExchange, false, nil)
+	if err != nil {
+		return logAndWrap("binding dead-letter queue", err)
+	}
+	return nil
+}
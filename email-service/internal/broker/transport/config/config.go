@@ -3,11 +3,70 @@ package config
 import (
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries    = 5
+	defaultRetryDelay    = 5 * time.Second
+	defaultMaxRetryDelay = 5 * time.Minute
+	deadLetterSuffix     = ".dead"
+	defaultWorkers       = 4
+	defaultHandleTimeout = 30 * time.Second
+	defaultDrainTimeout  = 30 * time.Second
+	// Reconnection starts fast and backs off to avoid hammering a restarting broker
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
+	// prefetchPerWorker keeps a message buffered for every busy worker.
+	prefetchPerWorker = 2
 )
 
 type Config struct {
 	BrokerURI string
 	QueueName string
+	// MaxRetries is how many times a failed message is redelivered
+	// before it is dead-lettered.
+	MaxRetries int
+	// RetryDelay is a delay of the first retry, doubled for every next one.
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the exponential retry delay.
+	MaxRetryDelay time.Duration
+	// DeadLetterExchange receives messages which exhausted retries,
+	// and routes them to the DeadLetterQueue.
+	DeadLetterExchange string
+	DeadLetterQueue    string
+	// Workers is the number of messages processed concurrently.
+	Workers int
+	// PrefetchCount limits unacknowledged messages delivered to the consumer.
+	PrefetchCount int
+	// HandleTimeout limits processing of a single message.
+	HandleTimeout time.Duration
+	// DrainTimeout limits waiting for messages in process on closing,
+	// after which their processing is cancelled.
+	DrainTimeout time.Duration
+	// ReconnectDelay is a delay of the first reconnection attempt once the connection
+	// is lost or fails to be established, doubled for every next one up to MaxReconnectDelay.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+}
+
+// RetryDelayFor returns the delay before the retry attempt, starting from 1.
+func (c Config) RetryDelayFor(attempt int) time.Duration {
+	delay := c.RetryDelay
+	for i := 1; i < attempt && delay < c.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxRetryDelay)
+}
+
+// ReconnectDelayFor returns the delay before the reconnection attempt, starting from 1.
+func (c Config) ReconnectDelayFor(attempt int) time.Duration {
+	delay := c.ReconnectDelay
+	for i := 1; i < attempt && delay < c.MaxReconnectDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxReconnectDelay)
 }
 
 func getOrError(key string) string {
@@ -18,9 +77,65 @@ func getOrError(key string) string {
 	return value
 }
 
+func getOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func intOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
 func NewFromEnv() Config {
+	queueName := getOrError("QUEUE_NAME")
+	workers := max(intOrDefault("BROKER_WORKERS", defaultWorkers), 1)
 	return Config{
-		BrokerURI: getOrError("BROKER_URI"),
-		QueueName: getOrError("QUEUE_NAME"),
+		BrokerURI:          getOrError("BROKER_URI"),
+		QueueName:          queueName,
+		MaxRetries:         intOrDefault("BROKER_MAX_RETRIES", defaultMaxRetries),
+		RetryDelay:         durationOrDefault("BROKER_RETRY_DELAY", defaultRetryDelay),
+		MaxRetryDelay:      durationOrDefault("BROKER_MAX_RETRY_DELAY", defaultMaxRetryDelay),
+		DeadLetterExchange: getOrDefault("BROKER_DEAD_LETTER_EXCHANGE", queueName+deadLetterSuffix),
+		DeadLetterQueue:    getOrDefault("BROKER_DEAD_LETTER_QUEUE", queueName+deadLetterSuffix),
+		Workers:            workers,
+		PrefetchCount:      intOrDefault("BROKER_PREFETCH_COUNT", workers*prefetchPerWorker),
+		HandleTimeout:      durationOrDefault("BROKER_HANDLE_TIMEOUT", defaultHandleTimeout),
+		DrainTimeout:       durationOrDefault("BROKER_DRAIN_TIMEOUT", defaultDrainTimeout),
+		ReconnectDelay:     durationOrDefault("BROKER_RECONNECT_DELAY", defaultReconnectDelay),
+		MaxReconnectDelay: durationOrDefault(
+			"BROKER_MAX_RECONNECT_DELAY", defaultMaxReconnectDelay,
+		),
 	}
 }
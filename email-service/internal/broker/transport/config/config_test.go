@@ -0,0 +1,82 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelayFor(t *testing.T) {
+	cfg := config.Config{RetryDelay: time.Second, MaxRetryDelay: 10 * time.Second}
+	testCases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: time.Second},
+		{attempt: 2, expected: 2 * time.Second},
+		{attempt: 4, expected: 8 * time.Second},
+		{attempt: 5, expected: 10 * time.Second},
+		{attempt: 60, expected: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, cfg.RetryDelayFor(tc.attempt), "attempt %d", tc.attempt)
+	}
+}
+
+func TestReconnectDelayFor(t *testing.T) {
+	cfg := config.Config{ReconnectDelay: time.Second, MaxReconnectDelay: 10 * time.Second}
+	testCases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: time.Second},
+		{attempt: 3, expected: 4 * time.Second},
+		{attempt: 60, expected: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, cfg.ReconnectDelayFor(tc.attempt), "attempt %d", tc.attempt)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	// Arrange
+	t.Setenv("QUEUE_NAME", "emails")
+	t.Setenv("BROKER_MAX_RETRIES", "3")
+	t.Setenv("BROKER_RETRY_DELAY", "2s")
+	t.Setenv("BROKER_MAX_RETRY_DELAY", "")
+	t.Setenv("BROKER_DEAD_LETTER_EXCHANGE", "")
+	t.Setenv("BROKER_DEAD_LETTER_QUEUE", "")
+	t.Setenv("BROKER_WORKERS", "3")
+	t.Setenv("BROKER_PREFETCH_COUNT", "")
+	t.Setenv("BROKER_HANDLE_TIMEOUT", "10s")
+	t.Setenv("BROKER_DRAIN_TIMEOUT", "")
+	t.Setenv("BROKER_RECONNECT_DELAY", "")
+	t.Setenv("BROKER_MAX_RECONNECT_DELAY", "1m")
+	// Act
+	cfg := config.NewFromEnv()
+	// Assert
+	assert.Equal(t, 3, cfg.MaxRetries)
+	assert.Equal(t, 2*time.Second, cfg.RetryDelay)
+	assert.Equal(t, 5*time.Minute, cfg.MaxRetryDelay)
+	assert.Equal(t, "emails.dead", cfg.DeadLetterExchange)
+	assert.Equal(t, "emails.dead", cfg.DeadLetterQueue)
+	assert.Equal(t, 3, cfg.Workers)
+	assert.Equal(t, 6, cfg.PrefetchCount)
+	assert.Equal(t, 10*time.Second, cfg.HandleTimeout)
+	assert.Equal(t, 30*time.Second, cfg.DrainTimeout)
+	assert.Equal(t, time.Second, cfg.ReconnectDelay)
+	assert.Equal(t, time.Minute, cfg.MaxReconnectDelay)
+}
+
+func TestNewFromEnvZeroWorkers(t *testing.T) {
+	// Arrange
+	t.Setenv("BROKER_WORKERS", "0")
+	t.Setenv("BROKER_PREFETCH_COUNT", "")
+	// Act
+	cfg := config.NewFromEnv()
+	// Assert
+	assert.Equal(t, 1, cfg.Workers)
+	assert.Equal(t, 2, cfg.PrefetchCount)
+}
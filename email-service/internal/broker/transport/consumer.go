@@ -1,122 +1,395 @@
 package transport
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	// retryHeader counts redeliveries of a failed message.
+	retryHeader    = "x-retry-count"
+	publishTimeout = 5 * time.Second
+)
+
+// ErrPermanent marks listener errors which retrying can't fix, e.g. malformed
+// messages. Such messages are dead-lettered right away.
+var ErrPermanent = errors.New("permanent failure")
+
 func logAndWrap(msg string, err error) error {
 	slog.Error(msg, slog.Any("error", err))
 	return fmt.Errorf("%s: %w", msg, err)
 }
 
-var listenerAccess = sync.Mutex{}
+// Listener processes the message body. The context is cancelled once
+// the handle timeout passes, or the consumer stops draining messages.
+type Listener func(ctx context.Context, body []byte) error
 
-type Listener func([]byte) error
+var errClosed = errors.New("consumer is closed")
 
+// Consumer handles messages of the queue with a pool of workers. Once the connection
+// is lost, it reconnects with backoff, declares the queues and consumes again.
 type Consumer struct {
-	config    config.Config
-	conn      *amqp.Connection
-	channel   *amqp.Channel
-	messages  <-chan amqp.Delivery
-	listeners []Listener
+	config      config.Config
+	consumerTag string
+	dial        Dialer
+
+	connAccess sync.RWMutex
+	channel    Channel
+
+	// deliveries receive messages of every connection, so that workers outlive reconnections
+	deliveries     chan amqp.Delivery
+	forwarders     sync.WaitGroup
+	stopForwarding chan struct{}
+	stopping       sync.Once
+
+	listenerAccess sync.RWMutex
+	listeners      []Listener
+
+	// ctx is a parent of message contexts, cancelled when draining times out
+	ctx     context.Context
+	cancel  context.CancelFunc
+	start   sync.Once
+	workers sync.WaitGroup
+	done    chan struct{}
+	closing sync.Once
 }
 
 func (c *Consumer) Subscribe(f Listener) {
+	c.listenerAccess.Lock()
+	defer c.listenerAccess.Unlock()
 	slog.Info(
 		"adding subscriber",
 		slog.Any("listener", f),
 		slog.Any("totalListeners", len(c.listeners)+1),
 	)
-	listenerAccess.Lock()
-	defer listenerAccess.Unlock()
 	c.listeners = append(c.listeners, f)
 }
 
-func (c *Consumer) deliverMessage(msg amqp.Delivery) {
-	listenerAccess.Lock()
-	defer listenerAccess.Unlock()
-	for _, listener := range c.listeners {
-		err := listener(msg.Body)
+func (c *Consumer) deliverMessage(ctx context.Context, msg amqp.Delivery) error {
+	c.listenerAccess.RLock()
+	listeners := c.listeners
+	c.listenerAccess.RUnlock()
+	var errs []error
+	for _, listener := range listeners {
+		err := listener(ctx, msg.Body)
 		if err != nil {
 			slog.Error(
 				"error delivering message",
 				slog.Any("error", err),
 				slog.Any("listener", listener),
 			)
+			errs = append(errs, err)
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// retryCount returns the number of times the message was already retried.
+func retryCount(msg amqp.Delivery) int {
+	switch count := msg.Headers[retryHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
 }
 
-func (c *Consumer) Listen(stop <-chan struct{}) {
+// retryQueueName returns the name of the delay queue holding messages for the delay.
+// Queues are named after their delay, as the TTL of a declared queue can't change.
+func retryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queueName, delay)
+}
+
+// republish publishes a copy of the message with the retry count and waits
+// for the broker to confirm it.
+func (c *Consumer) republish(msg amqp.Delivery, exchange, key string, retries int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[retryHeader] = int32(retries)
+	c.connAccess.RLock()
+	channel := c.channel
+	c.connAccess.RUnlock()
+	if channel == nil {
+		return errors.New("consumer is disconnected from the broker")
+	}
+	return channel.Publish(ctx, exchange, key, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	})
+}
+
+// fail schedules a retry of the failed message with an exponential delay,
+// or dead-letters it if retries are exhausted or the failure is permanent.
+func (c *Consumer) fail(msg amqp.Delivery, deliveryErr error) error {
+	retries := retryCount(msg)
+	if errors.Is(deliveryErr, ErrPermanent) || retries >= c.config.MaxRetries {
+		slog.Warn(
+			"dead-lettering message",
+			slog.Any("retries", retries), slog.Any("error", deliveryErr),
+		)
+		return c.republish(msg, c.config.DeadLetterExchange, c.config.QueueName, retries)
+	}
+	delay := c.config.RetryDelayFor(retries + 1)
+	slog.Info("retrying message", slog.Any("attempt", retries+1), slog.Any("delay", delay))
+	return c.republish(msg, "", retryQueueName(c.config.QueueName, delay), retries+1)
+}
+
+// handle delivers the message to listeners and acknowledges it once it is
+// either delivered or safely moved to a retry or dead-letter queue.
+// If moving fails, the message is requeued to be delivered again.
+func (c *Consumer) handle(msg amqp.Delivery) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.config.HandleTimeout)
+	defer cancel()
+	err := c.deliverMessage(ctx, msg)
+	if err != nil {
+		if err := c.fail(msg, err); err != nil {
+			slog.Error("requeueing failed message", slog.Any("error", err))
+			if err := msg.Nack(false, true); err != nil {
+				slog.Error("nacking message", slog.Any("error", err))
+			}
+			return
+		}
+	}
+	if err := msg.Ack(false); err != nil {
+		slog.Error("acking message", slog.Any("error", err))
+	}
+}
+
+// work handles messages until the deliveries are closed.
+func (c *Consumer) work() {
+	defer c.workers.Done()
+	for msg := range c.deliveries {
+		slog.Info("received message")
+		c.handle(msg)
+	}
+}
+
+// forward passes messages of a connection to the workers until the connection
+// is closed or the consumer is cancelled. Messages not passed stay unacknowledged
+// and are requeued by the broker.
+func (c *Consumer) forward(messages <-chan amqp.Delivery) {
+	defer c.forwarders.Done()
 	for {
 		select {
-		case <-stop:
+		case <-c.stopForwarding:
 			return
-		case msg, ok := <-c.messages:
+		case msg, ok := <-messages:
 			if !ok {
 				return
 			}
-			slog.Info("received message")
-			c.deliverMessage(msg)
+			select {
+			case c.deliveries <- msg:
+			case <-c.stopForwarding:
+				return
+			}
 		}
 	}
 }
 
+func (c *Consumer) stopForwardingOnce() {
+	c.stopping.Do(func() { close(c.stopForwarding) })
+}
+
+// Start starts the workers handling messages concurrently. Only the first
+// call starts them, so that listeners may subscribe before.
+func (c *Consumer) Start() {
+	c.start.Do(func() {
+		slog.Info("starting workers", slog.Any("workers", c.config.Workers))
+		c.workers.Add(c.config.Workers)
+		for range c.config.Workers {
+			go c.work()
+		}
+	})
+}
+
+// drain stops consuming and waits for the workers to handle the messages
+// already delivered. Messages in process are cancelled after the drain timeout.
+func (c *Consumer) drain(channel Channel) {
+	// Without workers there is no one to handle delivered messages
+	c.start.Do(c.stopForwardingOnce)
+	// Cancelling the consumer closes the deliveries once buffered messages are passed
+	if channel != nil {
+		if err := channel.Cancel(c.consumerTag); err != nil {
+			slog.Error("cancelling consumer", slog.Any("error", err))
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		c.forwarders.Wait()
+		close(c.deliveries)
+		c.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(c.config.DrainTimeout):
+		slog.Warn("draining timed out, cancelling messages in process")
+		c.stopForwardingOnce()
+		c.cancel()
+		<-done
+	}
+	c.cancel()
+}
+
+// Close stops reconnecting, drains the messages, then closes the channel
+// and the connection.
 func (c *Consumer) Close() error {
 	slog.Info("closing consumer")
-	if err := c.channel.Close(); err != nil {
-		return logAndWrap("closing channel", err)
+	var err error
+	c.closing.Do(func() {
+		close(c.done)
+		// Connecting after the done is closed is aborted, so no forwarders are added
+		c.connAccess.RLock()
+		channel := c.channel
+		c.connAccess.RUnlock()
+		c.drain(channel)
+		c.connAccess.Lock()
+		defer c.connAccess.Unlock()
+		err = c.closeChannel()
+	})
+	return err
+}
+
+func (c *Consumer) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
 	}
-	if err := c.conn.Close(); err != nil {
-		return logAndWrap("closing connection", err)
+}
+
+// closeChannel closes the current channel, it must be called with the lock held.
+func (c *Consumer) closeChannel() error {
+	if c.channel == nil {
+		return nil
 	}
-	return nil
+	ch := c.channel
+	c.channel = nil
+	return ch.Close()
 }
 
-func NewConsumer(config config.Config) (*Consumer, error) {
-	slog.Info("creating consumer", slog.Any("config", config))
-	conn, err := amqp.Dial(config.BrokerURI)
+// connect dials the broker, starts consuming and supervising the channel.
+func (c *Consumer) connect() error {
+	ch, err := c.dial(c.config)
 	if err != nil {
-		return nil, logAndWrap("dialing amqp", err)
+		return err
 	}
-	ch, err := conn.Channel()
+	messages, err := ch.Consume(c.config.QueueName, c.consumerTag)
 	if err != nil {
-		return nil, logAndWrap("getting channel", err)
-	}
-	q, err := ch.QueueDeclare(
-		config.QueueName, // name
-		false,            // durable
-		false,            // delete when unused
-		false,            // exclusive
-		false,            // no-wait
-		nil,              // arguments
-	)
-	if err != nil {
-		return nil, logAndWrap("declaring queue", err)
-	}
-	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		true,   // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
-	)
-	if err != nil {
-		return nil, logAndWrap("delivery creating", err)
+		ch.Close()
+		return logAndWrap("delivery creating", err)
+	}
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	if c.isClosed() {
+		ch.Close()
+		return errClosed
+	}
+	c.channel = ch
+	c.forwarders.Add(1)
+	go c.forward(messages)
+	go c.supervise(closed)
+	return nil
+}
+
+// supervise waits for the channel or its connection to close,
+// and reconnects unless the consumer is closed.
+func (c *Consumer) supervise(closed <-chan *amqp.Error) {
+	var reason *amqp.Error
+	select {
+	case <-c.done:
+		return
+	case reason = <-closed:
 	}
+	if c.isClosed() {
+		return
+	}
+	slog.Error("broker connection lost", slog.Any("reason", reason))
+	c.connAccess.Lock()
+	_ = c.closeChannel()
+	c.connAccess.Unlock()
+	c.connectWithBackoff(1)
+}
 
-	return &Consumer{
-		config:    config,
-		conn:      conn,
-		channel:   ch,
-		messages:  msgs,
-		listeners: make([]Listener, 0),
-	}, nil
+// connectWithBackoff connects until it succeeds or the consumer is closed.
+// Attempts are delayed with exponential backoff, except the initial attempt 0.
+func (c *Consumer) connectWithBackoff(attempt int) {
+	for ; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(c.config.ReconnectDelayFor(attempt)):
+			}
+		}
+		err := c.connect()
+		if err == nil {
+			slog.Info("connected to broker", slog.Any("attempt", attempt))
+			return
+		}
+		if errors.Is(err, errClosed) {
+			return
+		}
+		slog.Error(
+			"connecting to broker",
+			slog.Any("attempt", attempt),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// Option configures the consumer.
+type Option func(*Consumer)
+
+// WithDialer sets the dialer of the broker channel, DialAMQP by default.
+func WithDialer(dial Dialer) Option {
+	return func(c *Consumer) {
+		c.dial = dial
+	}
+}
+
+// NewConsumer creates a consumer connecting to the broker in the background,
+// with the same backoff as reconnections.
+func NewConsumer(config config.Config, opts ...Option) *Consumer {
+	slog.Info("creating consumer", slog.Any("config", config))
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Consumer{
+		config: config,
+		dial:   DialAMQP,
+		consumerTag: fmt.Sprintf(
+			"%s-%d-%d", config.QueueName, os.Getpid(), time.Now().UnixNano(),
+		),
+		deliveries:     make(chan amqp.Delivery),
+		stopForwarding: make(chan struct{}),
+		listeners:      make([]Listener, 0),
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	go c.connectWithBackoff(0)
+	return c
 }
@@ -0,0 +1,448 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/broker/transport/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const awaitTimeout = time.Second
+
+type publishing struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// fakeChannel delivers messages sent to its deliveries and records publishings.
+type fakeChannel struct {
+	mu         sync.Mutex
+	deliveries chan amqp.Delivery
+	published  []publishing
+	publishErr error
+	stopped    bool
+	cancelled  bool
+	closed     bool
+	receivers  []chan *amqp.Error
+}
+
+func newFakeChannel() *fakeChannel {
+	return &fakeChannel{deliveries: make(chan amqp.Delivery, 10)}
+}
+
+func (f *fakeChannel) Consume(string, string) (<-chan amqp.Delivery, error) {
+	return f.deliveries, nil
+}
+
+func (f *fakeChannel) Publish(_ context.Context, exchange, key string, msg amqp.Publishing) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.published = append(f.published, publishing{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+// stopDeliveries closes the deliveries, it must be called with the lock held.
+func (f *fakeChannel) stopDeliveries() {
+	if !f.stopped {
+		f.stopped = true
+		close(f.deliveries)
+	}
+}
+
+func (f *fakeChannel) Cancel(string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancelled = true
+	f.stopDeliveries()
+	return nil
+}
+
+func (f *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.receivers = append(f.receivers, receiver)
+	return receiver
+}
+
+// shutdown notifies the receivers about the reason and closes them,
+// it must be called with the lock held.
+func (f *fakeChannel) shutdown(reason *amqp.Error) {
+	for _, receiver := range f.receivers {
+		if reason != nil {
+			receiver <- reason
+		}
+		close(receiver)
+	}
+	f.receivers = nil
+	f.stopDeliveries()
+}
+
+func (f *fakeChannel) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.shutdown(nil)
+	return nil
+}
+
+// lose simulates the broker closing the channel.
+func (f *fakeChannel) lose() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restart"})
+}
+
+func (f *fakeChannel) publishings() []publishing {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]publishing(nil), f.published...)
+}
+
+func (f *fakeChannel) state() (bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cancelled, f.closed
+}
+
+// fakeDialer returns its channels in order, failing once they run out.
+type fakeDialer struct {
+	mu       sync.Mutex
+	channels []*fakeChannel
+	calls    int
+}
+
+func (d *fakeDialer) dial(config.Config) (transport.Channel, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if len(d.channels) == 0 {
+		return nil, errors.New("broker is down")
+	}
+	ch := d.channels[0]
+	d.channels = d.channels[1:]
+	if ch == nil {
+		return nil, errors.New("broker is starting")
+	}
+	return ch, nil
+}
+
+func (d *fakeDialer) callCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls
+}
+
+// fakeAcknowledger reports every acknowledgement of the deliveries.
+type fakeAcknowledger struct {
+	outcomes chan string
+}
+
+func newFakeAcknowledger() *fakeAcknowledger {
+	return &fakeAcknowledger{outcomes: make(chan string, 10)}
+}
+
+func (a *fakeAcknowledger) Ack(uint64, bool) error {
+	a.outcomes <- "ack"
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(_ uint64, _, requeue bool) error {
+	a.outcomes <- fmt.Sprintf("nack requeue=%t", requeue)
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(_ uint64, requeue bool) error {
+	a.outcomes <- fmt.Sprintf("reject requeue=%t", requeue)
+	return nil
+}
+
+func testConfig() config.Config {
+	return config.Config{
+		QueueName:          "emails",
+		MaxRetries:         2,
+		RetryDelay:         time.Second,
+		MaxRetryDelay:      time.Minute,
+		DeadLetterExchange: "emails.dead",
+		DeadLetterQueue:    "emails.dead",
+		Workers:            2,
+		HandleTimeout:      time.Second,
+		DrainTimeout:       time.Second,
+		ReconnectDelay:     time.Millisecond,
+		MaxReconnectDelay:  10 * time.Millisecond,
+	}
+}
+
+// startConsumer starts the consumer of the listener dialing the channels.
+func startConsumer(
+	t *testing.T, cfg config.Config, listener transport.Listener, dialer *fakeDialer,
+) *transport.Consumer {
+	t.Helper()
+	consumer := transport.NewConsumer(cfg, transport.WithDialer(dialer.dial))
+	t.Cleanup(func() { consumer.Close() })
+	consumer.Subscribe(listener)
+	consumer.Start()
+	return consumer
+}
+
+func delivery(ack amqp.Acknowledger, headers amqp.Table) amqp.Delivery {
+	return amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  1,
+		Headers:      headers,
+		Body:         []byte(`{"commandType":"SendEmail"}`),
+	}
+}
+
+func await[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(awaitTimeout):
+		t.Fatal("timed out waiting")
+	}
+	var zero T
+	return zero
+}
+
+func TestConsumerAcksDelivered(t *testing.T) {
+	// Arrange
+	ch := newFakeChannel()
+	ack := newFakeAcknowledger()
+	bodies := make(chan []byte, 1)
+	startConsumer(t, testConfig(), func(_ context.Context, body []byte) error {
+		bodies <- body
+		return nil
+	}, &fakeDialer{channels: []*fakeChannel{ch}})
+	// Act
+	ch.deliveries <- delivery(ack, nil)
+	// Assert
+	assert.Equal(t, `{"commandType":"SendEmail"}`, string(await(t, bodies)))
+	assert.Equal(t, "ack", await(t, ack.outcomes))
+	assert.Empty(t, ch.publishings())
+}
+
+func TestConsumerRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		headers          amqp.Table
+		err              error
+		expectExchange   string
+		expectKey        string
+		expectRetryCount int32
+	}{
+		{
+			name:             "first-failure",
+			err:              assert.AnError,
+			expectExchange:   "",
+			expectKey:        "emails.retry.1s",
+			expectRetryCount: 1,
+		},
+		{
+			name:             "second-failure",
+			headers:          amqp.Table{"x-retry-count": int32(1)},
+			err:              assert.AnError,
+			expectExchange:   "",
+			expectKey:        "emails.retry.2s",
+			expectRetryCount: 2,
+		},
+		{
+			name:             "int64-retry-count",
+			headers:          amqp.Table{"x-retry-count": int64(1)},
+			err:              assert.AnError,
+			expectExchange:   "",
+			expectKey:        "emails.retry.2s",
+			expectRetryCount: 2,
+		},
+		{
+			name:             "retries-exhausted",
+			headers:          amqp.Table{"x-retry-count": int32(2)},
+			err:              assert.AnError,
+			expectExchange:   "emails.dead",
+			expectKey:        "emails",
+			expectRetryCount: 2,
+		},
+		{
+			name:             "permanent-failure",
+			err:              fmt.Errorf("%w: malformed command", transport.ErrPermanent),
+			expectExchange:   "emails.dead",
+			expectKey:        "emails",
+			expectRetryCount: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			ch := newFakeChannel()
+			ack := newFakeAcknowledger()
+			startConsumer(t, testConfig(), func(context.Context, []byte) error {
+				return tc.err
+			}, &fakeDialer{channels: []*fakeChannel{ch}})
+			// Act
+			ch.deliveries <- delivery(ack, tc.headers)
+			// Assert
+			assert.Equal(t, "ack", await(t, ack.outcomes))
+			published := ch.publishings()
+			require.Len(t, published, 1)
+			assert.Equal(t, tc.expectExchange, published[0].exchange)
+			assert.Equal(t, tc.expectKey, published[0].key)
+			assert.Equal(t, tc.expectRetryCount, published[0].msg.Headers["x-retry-count"])
+			assert.Equal(t, amqp.Persistent, published[0].msg.DeliveryMode)
+			assert.Equal(t, `{"commandType":"SendEmail"}`, string(published[0].msg.Body))
+		})
+	}
+}
+
+func TestConsumerRequeuesOnRepublishFailure(t *testing.T) {
+	// Arrange
+	ch := newFakeChannel()
+	ch.publishErr = errors.New("message is not confirmed by the broker")
+	ack := newFakeAcknowledger()
+	startConsumer(t, testConfig(), func(context.Context, []byte) error {
+		return assert.AnError
+	}, &fakeDialer{channels: []*fakeChannel{ch}})
+	// Act
+	ch.deliveries <- delivery(ack, nil)
+	// Assert
+	assert.Equal(t, "nack requeue=true", await(t, ack.outcomes))
+	assert.Empty(t, ack.outcomes)
+}
+
+func TestConsumerWorkerPool(t *testing.T) {
+	// Arrange
+	const workers = 3
+	cfg := testConfig()
+	cfg.Workers = workers
+	ch := newFakeChannel()
+	ack := newFakeAcknowledger()
+	entered := make(chan struct{}, workers)
+	release := make(chan struct{})
+	startConsumer(t, cfg, func(context.Context, []byte) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}, &fakeDialer{channels: []*fakeChannel{ch}})
+	// Act
+	for range workers {
+		ch.deliveries <- delivery(ack, nil)
+	}
+	// Assert
+	// Every worker holds a message at once, otherwise entering blocks
+	for range workers {
+		await(t, entered)
+	}
+	close(release)
+	for range workers {
+		assert.Equal(t, "ack", await(t, ack.outcomes))
+	}
+}
+
+func TestConsumerDrainsOnClose(t *testing.T) {
+	// Arrange
+	ch := newFakeChannel()
+	ack := newFakeAcknowledger()
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	listenerErr := make(chan error, 1)
+	consumer := startConsumer(t, testConfig(), func(ctx context.Context, _ []byte) error {
+		entered <- struct{}{}
+		<-release
+		listenerErr <- ctx.Err()
+		return nil
+	}, &fakeDialer{channels: []*fakeChannel{ch}})
+	ch.deliveries <- delivery(ack, nil)
+	await(t, entered)
+	// Act
+	closed := make(chan error, 1)
+	go func() { closed <- consumer.Close() }()
+	// Assert
+	select {
+	case <-closed:
+		t.Fatal("closed before the message in process was handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, await(t, closed))
+	require.NoError(t, await(t, listenerErr))
+	assert.Equal(t, "ack", await(t, ack.outcomes))
+	cancelled, channelClosed := ch.state()
+	assert.True(t, cancelled)
+	assert.True(t, channelClosed)
+}
+
+func TestConsumerCancelsAfterDrainTimeout(t *testing.T) {
+	// Arrange
+	cfg := testConfig()
+	cfg.DrainTimeout = 20 * time.Millisecond
+	ch := newFakeChannel()
+	ack := newFakeAcknowledger()
+	entered := make(chan struct{}, 1)
+	listenerErr := make(chan error, 1)
+	consumer := startConsumer(t, cfg, func(ctx context.Context, _ []byte) error {
+		entered <- struct{}{}
+		<-ctx.Done()
+		listenerErr <- ctx.Err()
+		return ctx.Err()
+	}, &fakeDialer{channels: []*fakeChannel{ch}})
+	ch.deliveries <- delivery(ack, nil)
+	await(t, entered)
+	// Act
+	err := consumer.Close()
+	// Assert
+	require.NoError(t, err)
+	require.ErrorIs(t, await(t, listenerErr), context.Canceled)
+	// The cancelled message is moved to a retry queue rather than lost
+	assert.Equal(t, "ack", await(t, ack.outcomes))
+	require.Len(t, ch.publishings(), 1)
+	assert.Equal(t, "emails.retry.1s", ch.publishings()[0].key)
+}
+
+func TestConsumerReconnects(t *testing.T) {
+	// Arrange
+	first, second := newFakeChannel(), newFakeChannel()
+	// The initial connection fails, as the broker is still starting
+	dialer := &fakeDialer{channels: []*fakeChannel{nil, first, second}}
+	ack := newFakeAcknowledger()
+	startConsumer(t, testConfig(), func(context.Context, []byte) error {
+		return nil
+	}, dialer)
+	first.deliveries <- delivery(ack, nil)
+	assert.Equal(t, "ack", await(t, ack.outcomes))
+	// Act
+	first.lose()
+	second.deliveries <- delivery(ack, nil)
+	// Assert
+	assert.Equal(t, "ack", await(t, ack.outcomes))
+	assert.Equal(t, 3, dialer.callCount())
+	_, firstClosed := first.state()
+	assert.True(t, firstClosed)
+}
+
+func TestConsumerCloseStopsConnecting(t *testing.T) {
+	// Arrange
+	dialer := &fakeDialer{}
+	consumer := transport.NewConsumer(testConfig(), transport.WithDialer(dialer.dial))
+	require.Eventually(t, func() bool {
+		return dialer.callCount() > 1
+	}, awaitTimeout, time.Millisecond)
+	// Act
+	err := consumer.Close()
+	// Assert
+	require.NoError(t, err)
+	// An attempt started before closing may still finish
+	calls := dialer.callCount()
+	time.Sleep(50 * time.Millisecond)
+	assert.LessOrEqual(t, dialer.callCount(), calls+1)
+}
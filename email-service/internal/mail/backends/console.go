@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/config"
 )
 
@@ -11,20 +12,31 @@ type ConsoleMailer struct {
 	config config.Config
 }
 
-func (cm *ConsoleMailer) SendEmail(
-	_ context.Context, emails []string,
-	subject, message string,
-) error {
+func (cm *ConsoleMailer) SendEmail(_ context.Context, email mail.Email) error {
 	slog.Info(
 		"sending email",
 		slog.Any("fromEmail", cm.config.FromEmail),
-		slog.Any("toEmails", emails),
-		slog.Any("subject", subject),
-		slog.Any("message", message),
+		slog.Any("toEmails", email.Recipients),
+		slog.Any("subject", email.Subject),
+		slog.Any("message", email.Body),
+		slog.Any("textMessage", email.TextBody),
+		slog.Any("headers", email.Headers),
 	)
 	return nil
 }
 
+func (cm *ConsoleMailer) SendEach(ctx context.Context, email mail.Email) []mail.Result {
+	results := make([]mail.Result, 0, len(email.Recipients))
+	for _, recipient := range email.Recipients {
+		personal, err := email.For(recipient)
+		if err == nil {
+			err = cm.SendEmail(ctx, personal)
+		}
+		results = append(results, mail.Result{Recipient: recipient, Err: err})
+	}
+	return results
+}
+
 func NewConsoleMailer(config config.Config) *ConsoleMailer {
 	return &ConsoleMailer{config: config}
 }
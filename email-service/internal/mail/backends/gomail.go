@@ -4,57 +4,170 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/config"
 	"github.com/go-gomail/gomail"
+	"golang.org/x/time/rate"
 )
 
+// GomailMailer sends emails over pooled SMTP connections, limiting the rate
+// of messages. Senders block while the rate or the connections are exhausted,
+// which holds back the consumer of email commands.
 type GomailMailer struct {
-	config config.Config
+	config  config.Config
+	pool    *smtpPool
+	limiter *rate.Limiter
 }
 
-func (gm *GomailMailer) SendEmail(
-	ctx context.Context, emails []string,
-	subject, message string,
-) error {
-	mail := gomail.NewMessage()
-	mail.SetHeader("From", gm.config.FromEmail)
-	if len(emails) == 0 {
-		return errors.New("no email recipients")
+func (gm *GomailMailer) message(email mail.Email) *gomail.Message {
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", gm.config.FromEmail)
+	msg.SetHeader("To", email.Recipients[0])
+	msg.SetHeader("Bcc", email.Recipients[1:]...)
+	msg.SetHeader("Subject", email.Subject)
+	for header, value := range email.Headers {
+		msg.SetHeader(header, value)
+	}
+	// Clients show the last supported part of multipart/alternative,
+	// so the plain text goes first
+	if email.TextBody != "" {
+		msg.SetBody("text/plain", email.TextBody)
+		msg.AddAlternative("text/html", email.Body)
+	} else {
+		msg.SetBody("text/html", email.Body)
 	}
-	mail.SetHeader("To", emails[0])
-	mail.SetHeader("Bcc", emails[1:]...)
-	mail.SetHeader("Subject", subject)
-	mail.SetBody("text/html", message)
+	return msg
+}
 
+func (gm *GomailMailer) dial() (gomail.SendCloser, error) {
 	port, err := strconv.Atoi(gm.config.SMTPPort)
 	if err != nil {
-		return fmt.Errorf("failed to convert SMTP port to int: %w", err)
+		return nil, fmt.Errorf("failed to convert SMTP port to int: %w", err)
 	}
-	dialer := gomail.NewDialer(
+	return gomail.NewDialer(
 		gm.config.SMTPHost,
 		port,
 		gm.config.SMTPUser,
 		gm.config.SMTPPassword,
-	)
+	).Dial()
+}
 
-	done := make(chan error)
+// send sends the message over the connection unless the context is done first.
+// It returns the connection if it may send further messages, otherwise
+// the connection is released.
+func (gm *GomailMailer) send(
+	ctx context.Context, conn *smtpConn, msg *gomail.Message,
+) (*smtpConn, error) {
+	done := make(chan error, 1)
 	go func() {
-		done <- dialer.DialAndSend(mail)
+		done <- gomail.Send(conn, msg)
 	}()
 
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("email sending cancelled: %w", ctx.Err())
+		// The connection is busy until the message is sent
+		go func() { gm.pool.release(conn, <-done) }()
+		return nil, fmt.Errorf("email sending cancelled: %w", ctx.Err())
 	case err := <-done:
 		if err != nil {
-			return fmt.Errorf("failed to send email: %w", err)
+			gm.pool.release(conn, err)
+			return nil, fmt.Errorf("failed to send email: %w", err)
+		}
+	}
+	conn.sent++
+	conn.reused = false
+	if gm.pool.exhausted(conn) {
+		gm.pool.release(conn, nil)
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// sendOn sends the message within the rate limit over the held connection,
+// acquiring one if none is held. A connection reused from idle ones may have
+// been closed by the server, so the message is retried on a fresh one.
+func (gm *GomailMailer) sendOn(
+	ctx context.Context, conn *smtpConn, msg *gomail.Message,
+) (*smtpConn, error) {
+	if err := gm.limiter.Wait(ctx); err != nil {
+		return conn, fmt.Errorf("waiting for sending rate: %w", err)
+	}
+	for {
+		var err error
+		if conn == nil {
+			if conn, err = gm.pool.acquire(ctx); err != nil {
+				return nil, err
+			}
+		}
+		reused := conn.reused
+		conn, err = gm.send(ctx, conn, msg)
+		if err == nil || !reused || ctx.Err() != nil {
+			return conn, err
+		}
+	}
+}
+
+func (gm *GomailMailer) SendEmail(ctx context.Context, email mail.Email) error {
+	if len(email.Recipients) == 0 {
+		return errors.New("no email recipients")
+	}
+	conn, err := gm.sendOn(ctx, nil, gm.message(email))
+	if conn != nil {
+		gm.pool.release(conn, nil)
+	}
+	return err
+}
+
+// SendEach sends a message rendered for every recipient, holding a pooled
+// connection for consecutive messages. The connection is replaced after
+// a failed message, as the SMTP session may be left in an unknown state.
+func (gm *GomailMailer) SendEach(ctx context.Context, email mail.Email) []mail.Result {
+	results := make([]mail.Result, 0, len(email.Recipients))
+	var conn *smtpConn
+	defer func() {
+		if conn != nil {
+			gm.pool.release(conn, nil)
+		}
+	}()
+	for i, recipient := range email.Recipients {
+		if ctx.Err() != nil {
+			err := fmt.Errorf("email sending cancelled: %w", ctx.Err())
+			return failAll(results, email.Recipients[i:], err)
+		}
+		personal, err := email.For(recipient)
+		if err == nil {
+			conn, err = gm.sendOn(ctx, conn, gm.message(personal))
 		}
+		results = append(results, mail.Result{Recipient: recipient, Err: err})
 	}
-	return nil
+	return results
+}
+
+// failAll appends failed results of the recipients.
+func failAll(results []mail.Result, recipients []string, err error) []mail.Result {
+	for _, recipient := range recipients {
+		results = append(results, mail.Result{Recipient: recipient, Err: err})
+	}
+	return results
+}
+
+// Close closes idle SMTP connections.
+func (gm *GomailMailer) Close() {
+	gm.pool.Close()
 }
 
 func NewGomailMailer(config config.Config) *GomailMailer {
-	return &GomailMailer{config: config}
+	limit := rate.Limit(config.RateLimit)
+	if config.RateLimit <= 0 {
+		limit = rate.Inf
+	}
+	gm := &GomailMailer{
+		config:  config,
+		limiter: rate.NewLimiter(limit, int(math.Max(1, config.RateLimit))),
+	}
+	gm.pool = newSMTPPool(config, gm.dial)
+	return gm
 }
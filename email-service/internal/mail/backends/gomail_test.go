@@ -51,7 +51,11 @@ func TestSendEmail_InvalidPort(t *testing.T) {
 			ctx := context.Background()
 			config := getDefaultConfig(tc.port)
 			gm := backends.NewGomailMailer(config)
-			err := gm.SendEmail(ctx, []string{"example2@gmail.com"}, "subject", "message")
+			err := gm.SendEmail(ctx, mail.Email{
+				Recipients: []string{"example2@gmail.com"},
+				Subject:    "subject",
+				Body:       "message",
+			})
 			if tc.expectError {
 				assert.Error(t, err)
 				return
@@ -85,7 +89,11 @@ func TestSendEmail_Success(t *testing.T) {
 			gm := backends.NewGomailMailer(
 				getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())),
 			)
-			err := gm.SendEmail(ctx, tc.toEmails, "subject", "message")
+			err := gm.SendEmail(ctx, mail.Email{
+				Recipients: tc.toEmails,
+				Subject:    "subject",
+				Body:       "message",
+			})
 			if tc.expectError {
 				assert.Error(t, err)
 				return
@@ -164,7 +172,11 @@ func TestEmailMessageParameters(t *testing.T) {
 			config.FromEmail = tc.fromEmail
 			gm := backends.NewGomailMailer(config)
 			// Act
-			err := gm.SendEmail(ctx, tc.toEmails, tc.subject, tc.message)
+			err := gm.SendEmail(ctx, mail.Email{
+				Recipients: tc.toEmails,
+				Subject:    tc.subject,
+				Body:       tc.message,
+			})
 			// Assert
 			if tc.expectError {
 				assert.Error(t, err)
@@ -202,7 +214,11 @@ func TestSendEmailTimeout(t *testing.T) {
 			gm := backends.NewGomailMailer(
 				getDefaultConfig(portNumber),
 			)
-			err := gm.SendEmail(ctx, []string{"example2@gmail.com"}, "subject", "message")
+			err := gm.SendEmail(ctx, mail.Email{
+				Recipients: []string{"example2@gmail.com"},
+				Subject:    "subject",
+				Body:       "message",
+			})
 			if tc.expectError {
 				assert.Error(t, err)
 				return
@@ -213,3 +229,139 @@ func TestSendEmailTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestSendEmailHeaders(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	gm := backends.NewGomailMailer(
+		getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())),
+	)
+	// Act
+	err := gm.SendEmail(context.Background(), mail.Email{
+		Recipients: []string{"example2@gmail.com"},
+		Subject:    "subject",
+		Body:       "message",
+		Headers: map[string]string{
+			"List-Unsubscribe": "<http://localhost/unsubscribe/token>",
+		},
+	})
+	// Assert
+	require.NoError(t, err)
+	messages := smtpServer.Messages()
+	require.Len(t, messages, 1)
+	assert.Contains(
+		t, messages[0].MsgRequest(), "List-Unsubscribe: <http://localhost/unsubscribe/token>",
+	)
+}
+
+func TestSendEmailAlternative(t *testing.T) {
+	testCases := []struct {
+		name     string
+		textBody string
+		expected []string
+	}{
+		{
+			name:     "html only",
+			expected: []string{"Content-Type: text/html"},
+		},
+		{
+			name:     "html and text",
+			textBody: "plain message",
+			expected: []string{
+				"Content-Type: multipart/alternative",
+				"Content-Type: text/plain",
+				"plain message",
+				"Content-Type: text/html",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			smtpServer := mail.MockSMTPServer(t)
+			gm := backends.NewGomailMailer(getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())))
+			// Act
+			err := gm.SendEmail(context.Background(), mail.Email{
+				Recipients: []string{"example2@gmail.com"},
+				Subject:    "subject",
+				Body:       "<p>html message</p>",
+				TextBody:   tc.textBody,
+			})
+			// Assert
+			require.NoError(t, err)
+			messages := smtpServer.Messages()
+			require.Len(t, messages, 1)
+			request := messages[0].MsgRequest()
+			assert.Contains(t, request, "<p>html message</p>")
+			for _, expected := range tc.expected {
+				assert.Contains(t, request, expected)
+			}
+		})
+	}
+}
+
+func TestSendEachReusesConnection(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	gm := backends.NewGomailMailer(getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())))
+	recipients := []string{"example2@gmail.com", "example3@gmail.com", "example4@gmail.com"}
+	// Act
+	results := gm.SendEach(context.Background(), mail.Email{
+		Recipients:   recipients,
+		Subject:      "Hello, {{.Recipient}}",
+		Body:         "message",
+		PerRecipient: true,
+	})
+	// Assert
+	require.Len(t, results, len(recipients))
+	for i, result := range results {
+		assert.Equal(t, recipients[i], result.Recipient)
+		assert.NoError(t, result.Err)
+	}
+	// The mock server records a message per SMTP session, overwriting earlier
+	// messages of the session, so a single message means the connection was reused
+	messages := smtpServer.Messages()
+	require.Len(t, messages, 1)
+	request := messages[0].MsgRequest()
+	assert.Contains(t, request, "To: example4@gmail.com")
+	assert.Contains(t, request, "Subject: Hello, example4@gmail.com")
+	assert.NotContains(t, request, "Bcc")
+}
+
+func TestSendEachFailedRecipient(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	// The failed message must not break sending to the next recipients
+	gm := backends.NewGomailMailer(getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())))
+	// Act
+	results := gm.SendEach(context.Background(), mail.Email{
+		Recipients:   []string{"example2@gmail.com", "invalid", "example3@gmail.com"},
+		Subject:      "subject",
+		Body:         "message",
+		PerRecipient: true,
+	})
+	// Assert
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+	assert.NoError(t, results[2].Err)
+}
+
+func TestSendEachCancelled(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	gm := backends.NewGomailMailer(getDefaultConfig(strconv.Itoa(smtpServer.PortNumber())))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Act
+	results := gm.SendEach(ctx, mail.Email{
+		Recipients: []string{"example2@gmail.com", "example3@gmail.com"},
+		Subject:    "subject",
+	})
+	// Assert
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	}
+	assert.Empty(t, smtpServer.Messages())
+}
@@ -0,0 +1,142 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/config"
+	"github.com/go-gomail/gomail"
+)
+
+var errPoolClosed = errors.New("SMTP pool is closed")
+
+type smtpConn struct {
+	gomail.SendCloser
+	sent      int
+	idleSince time.Time
+	// reused is set while a connection taken from idle ones sends no message yet,
+	// as the server may have closed it meanwhile
+	reused bool
+}
+
+// smtpPool limits open SMTP connections and keeps idle ones for reuse.
+type smtpPool struct {
+	config config.Config
+	dial   func() (gomail.SendCloser, error)
+	// slots hold a token per connection in use, blocking acquiring once full
+	slots  chan struct{}
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
+}
+
+// acquire returns an idle connection or dials a new one, waiting for a free
+// slot until the context is done.
+func (p *smtpPool) acquire(ctx context.Context) (*smtpConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for SMTP connection: %w", ctx.Err())
+	}
+	if conn := p.takeIdle(); conn != nil {
+		return conn, nil
+	}
+	if p.isClosed() {
+		<-p.slots
+		return nil, errPoolClosed
+	}
+	sender, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, fmt.Errorf("dialing SMTP: %w", err)
+	}
+	return &smtpConn{SendCloser: sender}, nil
+}
+
+func (p *smtpPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// takeIdle returns the most recently used idle connection, closing expired ones.
+func (p *smtpPool) takeIdle() *smtpConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if time.Since(conn.idleSince) < p.config.IdleTimeout {
+			conn.reused = true
+			return conn
+		}
+		closeConn(conn)
+	}
+	return nil
+}
+
+// exhausted reports whether the connection sent its limit of messages.
+func (p *smtpPool) exhausted(conn *smtpConn) bool {
+	limit := p.config.MessagesPerConnection
+	return limit > 0 && conn.sent >= limit
+}
+
+// release returns the connection to idle ones, or closes it if it failed,
+// is exhausted or isn't kept idle.
+func (p *smtpPool) release(conn *smtpConn, err error) {
+	defer func() { <-p.slots }()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed || p.config.IdleTimeout <= 0 || p.exhausted(conn) {
+		closeConn(conn)
+		return
+	}
+	conn.idleSince = time.Now()
+	conn.reused = false
+	p.idle = append(p.idle, conn)
+	time.AfterFunc(p.config.IdleTimeout, p.closeExpired)
+}
+
+// closeExpired closes connections idle for longer than the idle timeout.
+func (p *smtpPool) closeExpired() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	active := p.idle[:0]
+	for _, conn := range p.idle {
+		if time.Since(conn.idleSince) < p.config.IdleTimeout {
+			active = append(active, conn)
+			continue
+		}
+		closeConn(conn)
+	}
+	p.idle = active
+}
+
+// Close closes idle connections, connections in use are closed on release.
+func (p *smtpPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, conn := range p.idle {
+		closeConn(conn)
+	}
+	p.idle = nil
+}
+
+func closeConn(conn *smtpConn) {
+	if err := conn.Close(); err != nil {
+		slog.Warn("closing SMTP connection", slog.Any("error", err))
+	}
+}
+
+func newSMTPPool(config config.Config, dial func() (gomail.SendCloser, error)) *smtpPool {
+	return &smtpPool{
+		config: config,
+		dial:   dial,
+		slots:  make(chan struct{}, max(config.MaxConnections, 1)),
+	}
+}
@@ -0,0 +1,103 @@
+package backends_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/backends"
+	smtpmock "github.com/mocktools/go-smtp-mock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pooledEmail = mail.Email{
+	Recipients: []string{"example2@gmail.com"},
+	Subject:    "subject",
+	Body:       "message",
+}
+
+func sendTwice(t *testing.T, gm *backends.GomailMailer) {
+	t.Helper()
+	require.NoError(t, gm.SendEmail(context.Background(), pooledEmail))
+	require.NoError(t, gm.SendEmail(context.Background(), pooledEmail))
+	gm.Close()
+}
+
+func TestSendEmailReusesIdleConnection(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	config := getDefaultConfig(strconv.Itoa(smtpServer.PortNumber()))
+	config.IdleTimeout = time.Minute
+	gm := backends.NewGomailMailer(config)
+	// Act
+	sendTwice(t, gm)
+	// Assert
+	// The mock server records the last message of every SMTP session
+	assert.Eventually(t, func() bool {
+		return len(smtpServer.Messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSendEmailMessagesPerConnection(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	config := getDefaultConfig(strconv.Itoa(smtpServer.PortNumber()))
+	config.IdleTimeout = time.Minute
+	config.MessagesPerConnection = 1
+	gm := backends.NewGomailMailer(config)
+	// Act
+	sendTwice(t, gm)
+	// Assert
+	assert.Eventually(t, func() bool {
+		return len(smtpServer.Messages()) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSendEachRateLimit(t *testing.T) {
+	// Arrange
+	smtpServer := mail.MockSMTPServer(t)
+	config := getDefaultConfig(strconv.Itoa(smtpServer.PortNumber()))
+	config.RateLimit = 4
+	gm := backends.NewGomailMailer(config)
+	recipients := make([]string, 6)
+	for i := range recipients {
+		recipients[i] = "example" + strconv.Itoa(i) + "@gmail.com"
+	}
+	start := time.Now()
+	// Act
+	results := gm.SendEach(context.Background(), mail.Email{
+		Recipients: recipients, Subject: "subject", PerRecipient: true,
+	})
+	// Assert
+	require.NoError(t, mail.ResultsError(results))
+	// A burst of 4 messages is sent at once, the rest wait a quarter of a second each
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestSendEmailWaitsForConnection(t *testing.T) {
+	// Arrange
+	smtpServer := smtpmock.New(smtpmock.ConfigurationAttr{
+		HostAddress:          mail.Localhost,
+		ResponseDelayMessage: 1,
+	})
+	require.NoError(t, smtpServer.Start())
+	t.Cleanup(func() { _ = smtpServer.Stop() })
+	config := getDefaultConfig(strconv.Itoa(smtpServer.PortNumber()))
+	config.MaxConnections = 1
+	gm := backends.NewGomailMailer(config)
+	busy := make(chan error, 1)
+	go func() { busy <- gm.SendEmail(context.Background(), pooledEmail) }()
+	// Let the first email take the only connection
+	time.Sleep(200 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	// Act
+	err := gm.SendEmail(ctx, pooledEmail)
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "waiting for SMTP connection")
+	require.NoError(t, <-busy)
+}
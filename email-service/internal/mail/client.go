@@ -1,12 +1,136 @@
 package mail
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
 )
 
+// Email is a message to be sent to the recipients.
+// Body is HTML, TextBody is its optional plain-text alternative.
+// Headers are optional and are set on the message as is, e.g. List-Unsubscribe.
+// If PerRecipient is set, a separate message is sent to every recipient
+// with the subject and the bodies rendered as templates of RecipientData.
+// The HTML body is rendered with the data escaped, the rest as plain text.
+type Email struct {
+	Recipients   []string
+	Subject      string
+	Body         string
+	TextBody     string
+	Headers      map[string]string
+	PerRecipient bool
+}
+
+// RecipientData is available in templates of per-recipient emails, e.g. {{.Recipient}}.
+type RecipientData struct {
+	Recipient string
+}
+
+// For renders the email for the single recipient.
+func (e Email) For(recipient string) (Email, error) {
+	data := RecipientData{Recipient: recipient}
+	subject, err := renderText(e.Subject, data)
+	if err != nil {
+		return Email{}, fmt.Errorf("rendering subject: %w", err)
+	}
+	body, err := renderHTML(e.Body, data)
+	if err != nil {
+		return Email{}, fmt.Errorf("rendering body: %w", err)
+	}
+	textBody, err := renderText(e.TextBody, data)
+	if err != nil {
+		return Email{}, fmt.Errorf("rendering text body: %w", err)
+	}
+	return Email{
+		Recipients: []string{recipient},
+		Subject:    subject,
+		Body:       body,
+		TextBody:   textBody,
+		Headers:    e.Headers,
+	}, nil
+}
+
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+func execute(tmpl executor, data RecipientData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func renderText(text string, data RecipientData) (string, error) {
+	tmpl, err := template.New("email").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return execute(tmpl, data)
+}
+
+// renderHTML renders the HTML template, escaping the data for its context.
+func renderHTML(text string, data RecipientData) (string, error) {
+	tmpl, err := htmltemplate.New("email").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return execute(tmpl, data)
+}
+
+// Result is the outcome of sending a message to the recipient.
+type Result struct {
+	Recipient string
+	Err       error
+}
+
+// DeliveryError lists the recipients whose messages failed.
+type DeliveryError struct {
+	Failed []Result
+}
+
+func (e *DeliveryError) Error() string {
+	recipients := make([]string, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		recipients = append(recipients, result.Recipient)
+	}
+	return fmt.Sprintf("sending to %s: %v", strings.Join(recipients, ", "), e.Unwrap())
+}
+
+func (e *DeliveryError) Unwrap() error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		errs = append(errs, result.Err)
+	}
+	return errors.Join(errs...)
+}
+
+// ResultsError returns a DeliveryError of the failed results, or nil if none failed.
+func ResultsError(results []Result) error {
+	var failed []Result
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &DeliveryError{Failed: failed}
+}
+
 type Mailer interface {
-	SendEmail(ctx context.Context, emails []string, subject, message string) error
+	// SendEmail sends a single message to all the recipients.
+	SendEmail(ctx context.Context, email Email) error
+	// SendEach sends a message rendered for every recipient over a reused
+	// connection, returning a result per recipient.
+	SendEach(ctx context.Context, email Email) []Result
 }
 
 type Client struct {
@@ -17,10 +141,21 @@ func NewClient(backend Mailer) *Client {
 	return &Client{backend: backend}
 }
 
-func (mc *Client) SendEmail(ctx context.Context, emails []string, subject, message string) error {
-	err := mc.backend.SendEmail(ctx, emails, subject, message)
+func (mc *Client) SendEmail(ctx context.Context, email Email) error {
+	if email.PerRecipient {
+		if err := ResultsError(mc.SendEach(ctx, email)); err != nil {
+			return fmt.Errorf("email client: %w", err)
+		}
+		return nil
+	}
+	err := mc.backend.SendEmail(ctx, email)
 	if err != nil {
 		return fmt.Errorf("email client: %w", err)
 	}
 	return nil
 }
+
+// SendEach sends a separate message to every recipient of the email.
+func (mc *Client) SendEach(ctx context.Context, email Email) []Result {
+	return mc.backend.SendEach(ctx, email)
+}
@@ -8,17 +8,23 @@ import (
 	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockBackend struct {
 	mock.Mock
 }
 
-func (m *mockBackend) SendEmail(ctx context.Context, to []string, subject, message string) error {
-	args := m.Called(ctx, to, subject, message)
+func (m *mockBackend) SendEmail(ctx context.Context, email mail.Email) error {
+	args := m.Called(ctx, email)
 	return args.Error(0)
 }
 
+func (m *mockBackend) SendEach(ctx context.Context, email mail.Email) []mail.Result {
+	args := m.Called(ctx, email)
+	return args.Get(0).([]mail.Result)
+}
+
 func TestSendEmail(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -39,10 +45,14 @@ func TestSendEmail(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			mb := &mockBackend{}
-			recipients := []string{"example@gmail.com", "example2@gmail.com"}
+			email := mail.Email{
+				Recipients: []string{"example@gmail.com", "example2@gmail.com"},
+				Subject:    "subject",
+				Body:       "message",
+			}
 			client := mail.NewClient(mb)
-			mb.On("SendEmail", mock.Anything, recipients, "subject", "message").Return(tc.err)
-			err := client.SendEmail(context.Background(), recipients, "subject", "message")
+			mb.On("SendEmail", mock.Anything, email).Return(tc.err)
+			err := client.SendEmail(context.Background(), email)
 			if tc.expectError {
 				assert.Error(t, err)
 				return
@@ -51,3 +61,100 @@ func TestSendEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestSendEmailPerRecipient(t *testing.T) {
+	// Arrange
+	mb := &mockBackend{}
+	email := mail.Email{
+		Recipients:   []string{"example@gmail.com", "example2@gmail.com"},
+		Subject:      "subject",
+		PerRecipient: true,
+	}
+	mb.On("SendEach", mock.Anything, email).Return([]mail.Result{
+		{Recipient: "example@gmail.com"},
+		{Recipient: "example2@gmail.com", Err: assert.AnError},
+	})
+	client := mail.NewClient(mb)
+	// Act
+	err := client.SendEmail(context.Background(), email)
+	// Assert
+	var deliveryErr *mail.DeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	require.Len(t, deliveryErr.Failed, 1)
+	assert.Equal(t, "example2@gmail.com", deliveryErr.Failed[0].Recipient)
+	require.ErrorIs(t, err, assert.AnError)
+	mb.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything)
+}
+
+func TestEmailFor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subject     string
+		body        string
+		textBody    string
+		expected    mail.Email
+		expectError bool
+	}{
+		{
+			name:     "rendered",
+			subject:  "Hello, {{.Recipient}}",
+			body:     "<p>{{.Recipient}}</p>",
+			textBody: "{{.Recipient}}",
+			expected: mail.Email{
+				Recipients: []string{"example@gmail.com"},
+				Subject:    "Hello, example@gmail.com",
+				Body:       "<p>example@gmail.com</p>",
+				TextBody:   "example@gmail.com",
+			},
+		},
+		{
+			name:        "unknown field",
+			subject:     "{{.Name}}",
+			expectError: true,
+		},
+		{
+			name:        "malformed",
+			body:        "{{",
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			email := mail.Email{
+				Recipients:   []string{"example@gmail.com", "example2@gmail.com"},
+				Subject:      tc.subject,
+				Body:         tc.body,
+				TextBody:     tc.textBody,
+				PerRecipient: true,
+			}
+			// Act
+			personal, err := email.For("example@gmail.com")
+			// Assert
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, personal)
+		})
+	}
+}
+
+func TestEmailForEscapesHTMLBody(t *testing.T) {
+	// Arrange
+	email := mail.Email{
+		Recipients:   []string{"o'brien&co@gmail.com"},
+		Subject:      "Hello, {{.Recipient}}",
+		Body:         "<p>{{.Recipient}}</p>",
+		TextBody:     "{{.Recipient}}",
+		PerRecipient: true,
+	}
+	// Act
+	personal, err := email.For("o'brien&co@gmail.com")
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "<p>o&#39;brien&amp;co@gmail.com</p>", personal.Body)
+	assert.Equal(t, "Hello, o'brien&co@gmail.com", personal.Subject)
+	assert.Equal(t, "o'brien&co@gmail.com", personal.TextBody)
+}
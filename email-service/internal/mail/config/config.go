@@ -3,6 +3,15 @@ package config
 import (
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxConnections        = 2
+	defaultIdleTimeout           = 30 * time.Second
+	defaultRateLimit             = 10
+	defaultMessagesPerConnection = 100
 )
 
 type Config struct {
@@ -11,6 +20,17 @@ type Config struct {
 	SMTPPort     string
 	SMTPUser     string
 	SMTPPassword string
+	// MaxConnections limits concurrently open SMTP connections, senders wait
+	// for a free connection once the limit is reached.
+	MaxConnections int
+	// IdleTimeout is how long an unused connection is kept open for reuse,
+	// zero closes connections right after use.
+	IdleTimeout time.Duration
+	// RateLimit is the number of messages sent per second, zero disables the limit.
+	RateLimit float64
+	// MessagesPerConnection is the number of messages sent over a connection
+	// before it is closed, zero disables the limit.
+	MessagesPerConnection int
 }
 
 func getOrError(key string) string {
@@ -21,12 +41,69 @@ func getOrError(key string) string {
 	return value
 }
 
+func intOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func floatOrDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		slog.Error(
+			"invalid number, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return number
+}
+
+func durationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		slog.Error(
+			"invalid duration, using default value",
+			slog.Any("key", key),
+			slog.Any("default", defaultValue),
+		)
+		return defaultValue
+	}
+	return duration
+}
+
 func NewFromEnv() Config {
 	return Config{
-		FromEmail:    getOrError("EMAIL_FROM"),
-		SMTPHost:     getOrError("SMTP_HOST"),
-		SMTPPort:     getOrError("SMTP_PORT"),
-		SMTPUser:     getOrError("SMTP_USER"),
-		SMTPPassword: getOrError("SMTP_PASSWORD"),
+		FromEmail:      getOrError("EMAIL_FROM"),
+		SMTPHost:       getOrError("SMTP_HOST"),
+		SMTPPort:       getOrError("SMTP_PORT"),
+		SMTPUser:       getOrError("SMTP_USER"),
+		SMTPPassword:   getOrError("SMTP_PASSWORD"),
+		MaxConnections: intOrDefault("SMTP_MAX_CONNECTIONS", defaultMaxConnections),
+		IdleTimeout:    durationOrDefault("SMTP_IDLE_TIMEOUT", defaultIdleTimeout),
+		RateLimit:      floatOrDefault("SMTP_RATE_LIMIT", defaultRateLimit),
+		MessagesPerConnection: intOrDefault(
+			"SMTP_MESSAGES_PER_CONNECTION", defaultMessagesPerConnection,
+		),
 	}
 }
@@ -0,0 +1,24 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GenesisEducationKyiv/software-engineering-school-4-0-Hukyl/email-service/internal/mail/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	// Arrange
+	t.Setenv("SMTP_MAX_CONNECTIONS", "5")
+	t.Setenv("SMTP_IDLE_TIMEOUT", "")
+	t.Setenv("SMTP_RATE_LIMIT", "0.5")
+	t.Setenv("SMTP_MESSAGES_PER_CONNECTION", "invalid")
+	// Act
+	cfg := config.NewFromEnv()
+	// Assert
+	assert.Equal(t, 5, cfg.MaxConnections)
+	assert.Equal(t, 30*time.Second, cfg.IdleTimeout)
+	assert.InEpsilon(t, 0.5, cfg.RateLimit, 1e-9)
+	assert.Equal(t, 100, cfg.MessagesPerConnection)
+}